@@ -0,0 +1,403 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/mail"
+	"github.com/kamilwrzyszcz/go_example/session"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"gopkg.in/guregu/null.v3"
+)
+
+var errChangeOwnAccount = errors.New("admins can't disable or change the role of their own account")
+
+// Escapes LIKE wildcards, so that the search matches them literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type adminUserResponse struct {
+	Username              string    `json:"username"`
+	FullName              string    `json:"full_name"`
+	Email                 string    `json:"email"`
+	Role                  string    `json:"role"`
+	TOTPEnabled           bool      `json:"totp_enabled"`
+	DisabledAt            null.Time `json:"disabled_at" swaggertype:"string"`
+	PasswordResetRequired bool      `json:"password_reset_required"`
+	PasswordChangedAt     time.Time `json:"password_changed_at"`
+	CreatedAt             time.Time `json:"created_at"`
+}
+
+// func to cover some fields that shouldn't be leaked in response, even to admins
+func newAdminUserResponse(user db.User) adminUserResponse {
+	return adminUserResponse{
+		Username:              user.Username,
+		FullName:              user.FullName,
+		Email:                 user.Email,
+		Role:                  user.Role,
+		TOTPEnabled:           user.TotpEnabled,
+		DisabledAt:            null.NewTime(user.DisabledAt.Time, user.DisabledAt.Valid),
+		PasswordResetRequired: user.PasswordResetRequired,
+		PasswordChangedAt:     user.PasswordChangedAt,
+		CreatedAt:             user.CreatedAt,
+	}
+}
+
+type adminSessionResponse struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserAgent string    `json:"user_agent"`
+	ClientIP  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	// Set when an admin is impersonating the user
+	Impersonator string `json:"impersonator,omitempty"`
+}
+
+// func to cover the refresh token
+func newAdminSessionResponse(session *session.Session) adminSessionResponse {
+	return adminSessionResponse{
+		ID:        session.ID,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+		UserAgent: session.UserAgent,
+		ClientIP:  session.ClientIP,
+		IsBlocked: session.IsBlocked,
+
+		Impersonator: session.Impersonator,
+	}
+}
+
+type adminUserDetailsResponse struct {
+	User         adminUserResponse      `json:"user"`
+	Sessions     []adminSessionResponse `json:"sessions"`
+	ArticleCount int64                  `json:"article_count"`
+}
+
+type listUsersRequest struct {
+	// Substring of the username, full name or email
+	Search   string `form:"search" binding:"max=100"`
+	Role     string `form:"role" binding:"omitempty,oneof=user admin"`
+	Disabled *bool  `form:"disabled"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// ListUsers godoc
+// @Summary      Get the list of users
+// @Description  Search users, newest first. Filters that aren't given are skipped. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   search   query    string   false  "Substring of the username, full name or email"
+// @Param   role   query    string   false  "Role filter"  Enums(user, admin)
+// @Param   disabled   query    bool   false  "Disabled accounts filter"
+// @Param   page_id   query    int32   true  "User PageID query param"
+// @Param   page_size  query    int32   true  "User PageSize query param"
+// @Success      200  {object}  []api.adminUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/users [get]
+func (server *Server) listUsers(ctx *gin.Context) {
+	var req listUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListUsersParams{
+		Search: sql.NullString{String: likeEscaper.Replace(req.Search), Valid: req.Search != ""},
+		Role:   sql.NullString{String: req.Role, Valid: req.Role != ""},
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+	if req.Disabled != nil {
+		arg.Disabled = sql.NullBool{Bool: *req.Disabled, Valid: true}
+	}
+
+	users, err := server.store.ListUsers(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]adminUserResponse, len(users))
+	for i, user := range users {
+		resp[i] = newAdminUserResponse(user)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type adminUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// GetUserDetails godoc
+// @Summary      Get a user
+// @Description  Get a user together with their active sessions and article count. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Username path param"
+// @Success      200  {object}  api.adminUserDetailsResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/users/{username} [get]
+func (server *Server) getUserDetails(ctx *gin.Context) {
+	var req adminUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	author, err := server.store.GetAuthor(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	sessions, err := server.sessionClient.ListByUsername(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := adminUserDetailsResponse{
+		User:         newAdminUserResponse(user),
+		Sessions:     make([]adminSessionResponse, len(sessions)),
+		ArticleCount: author.ArticleCount,
+	}
+	for i, session := range sessions {
+		resp.Sessions[i] = newAdminSessionResponse(session)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// DisableUser godoc
+// @Summary      Disable a user
+// @Description  Disable the account and log out all of its sessions. Disabled users can't log in. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Username path param"
+// @Success      200  {object}  api.adminUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/users/{username}/disable [post]
+func (server *Server) disableUser(ctx *gin.Context) {
+	var req adminUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if req.Username == authPayload.Username {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errChangeOwnAccount))
+		return
+	}
+
+	user, err := server.store.DisableUser(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Without sessions the existing tokens are rejected by authMiddleware and can't be renewed
+	err = server.sessionClient.DelByUsername(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+// EnableUser godoc
+// @Summary      Enable a user
+// @Description  Enable a disabled account. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Username path param"
+// @Success      200  {object}  api.adminUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/users/{username}/enable [post]
+func (server *Server) enableUser(ctx *gin.Context) {
+	var req adminUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.EnableUser(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+// ForcePasswordReset godoc
+// @Summary      Force password reset
+// @Description  Log out all sessions of the user and email them a password reset link. They can't log in until the password is reset. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Username path param"
+// @Success      200  {object}  api.adminUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/users/{username}/password_reset [post]
+func (server *Server) forcePasswordReset(ctx *gin.Context) {
+	var req adminUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.RequirePasswordReset(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.sessionClient.DelByUsername(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resetToken, _, err := server.tokenMaker.CreatePurposeToken(
+		token.PurposePasswordReset,
+		user.Username,
+		server.config.ResetTokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nan administrator has asked you to set a new password. Until then you can't log in.\n"+
+				"Use the link below, it works once and expires in %s.\n\n%s?token=%s\n",
+			user.FullName, server.config.ResetTokenDuration, server.config.PasswordResetURL, url.QueryEscape(resetToken),
+		),
+	}
+
+	// Reset is already required, so the admin has to know the email didn't go out and retry
+	if err := server.mailer.Send(ctx, msg); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAdminUserResponse(user))
+}
+
+type changeUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+// ChangeUserRole godoc
+// @Summary      Change role of a user
+// @Description  Change role of a user. Admins can't change their own role. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Username path param"
+// @Param   payload   body    api.changeUserRoleRequest    true  "Role payload"
+// @Success      200  {object}  api.adminUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/users/{username}/role [put]
+func (server *Server) changeUserRole(ctx *gin.Context) {
+	var uriReq adminUserRequest
+	if err := ctx.ShouldBindUri(&uriReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req changeUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Otherwise the last admin could lock everyone out of the admin routes
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if uriReq.Username == authPayload.Username {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errChangeOwnAccount))
+		return
+	}
+
+	arg := db.SetUserRoleParams{
+		Username: uriReq.Username,
+		Role:     req.Role,
+	}
+	user, err := server.store.SetUserRole(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAdminUserResponse(user))
+}
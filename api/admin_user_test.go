@@ -0,0 +1,398 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/mail"
+	mockMail "github.com/kamilwrzyszcz/go_example/mail/mock"
+	"github.com/kamilwrzyszcz/go_example/session"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminUserAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.RoleAdmin
+	user, _ := randomUser(t)
+	user.Role = util.RoleUser
+	disabledAdmin, _ := randomUser(t)
+	disabledAdmin.Role = util.RoleAdmin
+	disabledAdmin.DisabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		caller        db.User
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "List",
+			method: http.MethodGet,
+			url:    "/admin/users?search=a_b&role=user&disabled=true&page_id=2&page_size=5",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				arg := db.ListUsersParams{
+					Search:   sql.NullString{String: `a\_b`, Valid: true},
+					Role:     sql.NullString{String: util.RoleUser, Valid: true},
+					Disabled: sql.NullBool{Bool: true, Valid: true},
+					Limit:    5,
+					Offset:   5,
+				}
+				store.EXPECT().
+					ListUsers(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.User{user}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "hashed_password")
+
+				var got []adminUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got, 1)
+				require.Equal(t, user.Username, got[0].Username)
+			},
+		},
+		{
+			name:   "ListNoFilters",
+			method: http.MethodGet,
+			url:    "/admin/users?page_id=1&page_size=5",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				arg := db.ListUsersParams{
+					Limit:  5,
+					Offset: 0,
+				}
+				store.EXPECT().
+					ListUsers(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.User{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ListInvalidRole",
+			method: http.MethodGet,
+			url:    "/admin/users?role=owner&page_id=1&page_size=5",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					ListUsers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "ListNotAdmin",
+			method: http.MethodGet,
+			url:    "/admin/users?page_id=1&page_size=5",
+			caller: user,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					ListUsers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "ListDisabledAdmin",
+			method: http.MethodGet,
+			url:    "/admin/users?page_id=1&page_size=5",
+			caller: disabledAdmin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					ListUsers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errAccountDisabled)
+			},
+		},
+		{
+			name:   "Get",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/users/%s", user.Username),
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetAuthor(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.GetAuthorRow{Username: user.Username, ArticleCount: 3}, nil)
+				sessionClient.EXPECT().
+					ListByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return([]*session.Session{{ID: "session", Username: user.Username, RefreshToken: "secret"}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "secret")
+
+				var got adminUserDetailsResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, got.User.Username)
+				require.Equal(t, int64(3), got.ArticleCount)
+				require.Len(t, got.Sessions, 1)
+				require.Equal(t, "session", got.Sessions[0].ID)
+			},
+		},
+		{
+			name:   "GetNotFound",
+			method: http.MethodGet,
+			url:    "/admin/users/notfound",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq("notfound")).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "Disable",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/users/%s/disable", user.Username),
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				disabled := user
+				disabled.DisabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					DisableUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(disabled, nil)
+				sessionClient.EXPECT().
+					DelByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got adminUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.True(t, got.DisabledAt.Valid)
+			},
+		},
+		{
+			name:   "DisableSelf",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/users/%s/disable", admin.Username),
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					DisableUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errChangeOwnAccount)
+			},
+		},
+		{
+			name:   "Enable",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/users/%s/enable", user.Username),
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					EnableUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ForcePasswordReset",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/users/%s/password_reset", user.Username),
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				resetRequired := user
+				resetRequired.PasswordResetRequired = true
+				store.EXPECT().
+					RequirePasswordReset(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(resetRequired, nil)
+				sessionClient.EXPECT().
+					DelByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, msg mail.Message) error {
+						require.Equal(t, user.Email, msg.To)
+						require.Contains(t, msg.Body, "http://localhost:3000/password/reset?token=")
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got adminUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.True(t, got.PasswordResetRequired)
+			},
+		},
+		{
+			name:   "ForcePasswordResetNotFound",
+			method: http.MethodPost,
+			url:    "/admin/users/notfound/password_reset",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					RequirePasswordReset(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "ChangeRole",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/users/%s/role", user.Username),
+			body:   gin.H{"role": util.RoleAdmin},
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				promoted := user
+				promoted.Role = util.RoleAdmin
+				arg := db.SetUserRoleParams{
+					Username: user.Username,
+					Role:     util.RoleAdmin,
+				}
+				store.EXPECT().
+					SetUserRole(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(promoted, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got adminUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, util.RoleAdmin, got.Role)
+			},
+		},
+		{
+			name:   "ChangeRoleInvalid",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/users/%s/role", user.Username),
+			body:   gin.H{"role": "owner"},
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					SetUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "ChangeOwnRole",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/admin/users/%s/role", admin.Username),
+			body:   gin.H{"role": util.RoleUser},
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, mailer *mockMail.MockMailer) {
+				store.EXPECT().
+					SetUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errChangeOwnAccount)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			sessionID, err := uuid.NewRandom()
+			require.NoError(t, err)
+
+			store := mockdb.NewMockStore(ctrl)
+			// Role and status of the caller are checked by authMiddleware
+			store.EXPECT().
+				ListUserAccess(gomock.Any(), gomock.Eq([]string{tc.caller.Username})).
+				Times(1).
+				Return([]db.ListUserAccessRow{{
+					Username:   tc.caller.Username,
+					Role:       tc.caller.Role,
+					DisabledAt: tc.caller.DisabledAt,
+				}}, nil)
+
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			sessionClient.EXPECT().
+				Get(gomock.Any(), gomock.Eq(sessionID.String())).
+				Times(1).
+				Return(&session.Session{ID: sessionID.String(), Username: tc.caller.Username}, nil)
+
+			mailer := mockMail.NewMockMailer(ctrl)
+			tc.buildStubs(store, sessionClient, mailer)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			server.mailer = mailer
+			recorder := httptest.NewRecorder()
+
+			var body []byte
+			if tc.body != nil {
+				body, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, sessionID, authorizationTypeBearer, tc.caller.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
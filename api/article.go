@@ -3,27 +3,152 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
 	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
 	"gopkg.in/guregu/null.v3"
 )
 
+type articleResponse struct {
+	db.Article
+	Tags          []string `json:"tags"`
+	CommentCount  int64    `json:"comment_count"`
+	FavoriteCount int64    `json:"favorite_count"`
+	// Whether the logged in reader favorited the article, always false for anonymous readers
+	Favorited bool `json:"favorited"`
+	// Counts of the reactions the article got, keyed by the reaction name
+	Reactions map[string]int64 `json:"reactions"`
+	// Reactions the logged in reader left
+	MyReactions []string `json:"my_reactions"`
+}
+
+func newArticleResponse(article db.Article, tags []string) articleResponse {
+	if tags == nil {
+		tags = []string{}
+	}
+	return articleResponse{
+		Article:     article,
+		Tags:        tags,
+		Reactions:   map[string]int64{},
+		MyReactions: []string{},
+	}
+}
+
+// newArticleResponses loads the tags and the stats of all articles with a query for each
+func (server *Server) newArticleResponses(ctx *gin.Context, articles []db.Article) ([]articleResponse, error) {
+	resp := make([]articleResponse, len(articles))
+	if len(articles) == 0 {
+		return resp, nil
+	}
+
+	ids := make([]int64, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	rows, err := server.store.ListTagsOfArticles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[int64][]string, len(articles))
+	for _, row := range rows {
+		tags[row.ArticleID] = append(tags[row.ArticleID], row.Name)
+	}
+	for i, article := range articles {
+		resp[i] = newArticleResponse(article, tags[article.ID])
+	}
+
+	err = server.addArticleStats(ctx, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// newSingleArticleResponse loads the tags and the stats of a single article
+func (server *Server) newSingleArticleResponse(ctx *gin.Context, article db.Article) (articleResponse, error) {
+	resp, err := server.newArticleResponses(ctx, []db.Article{article})
+	if err != nil {
+		return articleResponse{}, err
+	}
+	return resp[0], nil
+}
+
+// addArticleStats fills in comment, favorite and reaction counts,
+// and whether the logged in reader favorited or reacted to the articles
+func (server *Server) addArticleStats(ctx *gin.Context, articles []articleResponse) error {
+	ids := make([]int64, len(articles))
+	byID := make(map[int64]*articleResponse, len(articles))
+	for i := range articles {
+		ids[i] = articles[i].ID
+		byID[articles[i].ID] = &articles[i]
+	}
+
+	var reader string
+	if authPayload := optionalAuthPayload(ctx); authPayload != nil {
+		reader = authPayload.Username
+	}
+
+	commentCounts, err := server.commentCounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for id, count := range commentCounts {
+		byID[id].CommentCount = count
+	}
+
+	favorites, err := server.store.CountFavoritesOfArticles(ctx, db.CountFavoritesOfArticlesParams{
+		Username:   reader,
+		ArticleIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range favorites {
+		byID[row.ArticleID].FavoriteCount = row.FavoriteCount
+		byID[row.ArticleID].Favorited = row.Favorited
+	}
+
+	reactions, err := server.store.CountReactionsOfArticles(ctx, db.CountReactionsOfArticlesParams{
+		Username:   reader,
+		ArticleIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range reactions {
+		article := byID[row.ArticleID]
+		article.Reactions[row.Reaction] = row.ReactionCount
+		if row.Reacted {
+			article.MyReactions = append(article.MyReactions, row.Reaction)
+		}
+	}
+	return nil
+}
+
 type createArticleRequest struct {
-	Headline string `json:"headline" binding:"required"`
-	Content  string `json:"content" binding:"required"`
+	Headline string   `json:"headline" binding:"required"`
+	Content  string   `json:"content" binding:"required"`
+	Tags     []string `json:"tags" binding:"max=10"`
+	// Draft by default, scheduling is done afterwards
+	Status string `json:"status" binding:"omitempty,oneof=draft published"`
 }
 
 // CreateArticle godoc
 // @Summary      Create an article
-// @Description  Create a new article
+// @Description  Create a new article, as a draft unless status is published. Tags are normalized to lowercase slugs, "Go Modules" becomes "go-modules"
 // @Tags         articles
 // @Accept       json
 // @Produce      json
 // @Param   payload   body    api.createArticleRequest    true  "Article payload"
-// @Success      201  {object}  db.Article
+// @Success      201  {object}  api.articleResponse
 // @Failure      400  {object} object{error=string}
 // @Failure      500  {object} object{error=string}
 // @Security BearerAuth
@@ -35,23 +160,39 @@ func (server *Server) createArticle(ctx *gin.Context) {
 		return
 	}
 
+	tags, err := util.NormalizeTags(req.Tags)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	// Info about author is taken from token part
 	// Could be handled differently. Wanted to try out getting data that way
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	arg := db.CreateArticleParams{
-		Author:   authPayload.Username,
-		Headline: req.Headline,
-		Content:  req.Content,
+	arg := db.CreateArticleTxParams{
+		CreateArticleParams: db.CreateArticleParams{
+			Author:   authPayload.Username,
+			Headline: req.Headline,
+			Content:  req.Content,
+			Status:   util.ArticleDraft,
+			// Indexed with the configuration searches parse queries with
+			SearchLanguage: server.config.SearchLanguage,
+		},
+		Tags: tags,
+	}
+	if req.Status == util.ArticlePublished {
+		arg.Status = util.ArticlePublished
+		arg.PublishAt = nullTime(time.Now())
 	}
 
-	article, err := server.store.CreateArticle(ctx, arg)
+	result, err := server.store.CreateArticleTx(ctx, arg)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, article)
+	ctx.JSON(http.StatusCreated, newArticleResponse(result.Article, result.Tags))
 }
 
 type getArticleRequest struct {
@@ -60,12 +201,13 @@ type getArticleRequest struct {
 
 // GetArticle godoc
 // @Summary      Get an article
-// @Description  Get a specific article by ID
+// @Description  Get a specific article by ID. Articles that aren't published can only be read by their author
 // @Tags         articles
 // @Accept       json
 // @Produce      json
 // @Param   id   path    int64   true  "Article ID path param"
-// @Success      200  {object}  db.Article
+// @Success      200  {object}  api.articleResponse
+// @Header       200  {string}  ETag  "Version of the article, to be sent in If-Match when changing it"
 // @Failure      400  {object} object{error=string}
 // @Failure      404  {object} object{error=string}
 // @Failure      500  {object} object{error=string}
@@ -78,7 +220,7 @@ func (server *Server) getArticle(ctx *gin.Context) {
 		return
 	}
 
-	article, err := server.store.GetArticle(ctx, req.ID)
+	article, err := server.getReadableArticle(ctx, req.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -89,23 +231,80 @@ func (server *Server) getArticle(ctx *gin.Context) {
 		}
 	}
 
-	ctx.JSON(http.StatusOK, article)
+	resp, err := server.newSingleArticleResponse(ctx, article)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header(etagHeaderKey, articleETag(article))
+	ctx.JSON(http.StatusOK, resp)
 }
 
+const (
+	defaultArticleMaxPageSize = 10
+	defaultArticleLimit       = 10
+	totalCountHeaderKey       = "X-Total-Count"
+)
+
+var errPagingMode = errors.New("page_id can't be combined with cursor or limit")
+
 type listArticlesRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	// Own articles are listed when no author is given
+	Authors       []string  `form:"author" binding:"max=20,dive,alphanum"`
+	CreatedAfter  time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	EditedAfter   time.Time `form:"edited_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	EditedBefore  time.Time `form:"edited_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Edited        *bool     `form:"edited"`
+	Statuses      []string  `form:"status" binding:"max=4,dive,oneof=draft published scheduled archived"`
+	Tags          []string  `form:"tag" binding:"max=10"`
+	TagMode       string    `form:"tag_mode" binding:"omitempty,oneof=any all"`
+	Sort          string    `form:"sort" binding:"omitempty,oneof=created_at edited_at headline"`
+	Order         string    `form:"order" binding:"omitempty,oneof=asc desc"`
+	// Page number paging is kept for older clients, cursor paging is used when page_id isn't given
+	PageID    int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageSize  int32  `form:"page_size" binding:"omitempty,min=1"`
+	Cursor    string `form:"cursor"`
+	Limit     int32  `form:"limit" binding:"omitempty,min=1"`
+	WithTotal bool   `form:"with_total"`
+}
+
+type listArticlesResponse struct {
+	Articles []articleResponse `json:"articles"`
+	// Empty when there are no more articles
+	NextCursor string `json:"next_cursor"`
+	// Empty on the first page
+	PrevCursor string `json:"prev_cursor"`
 }
 
 // ListArticles godoc
 // @Summary      Get the list of articles
-// @Description  Get the list of articles accoring to specified params
+// @Description  Get the list of articles accoring to specified params. Filters that aren't given are skipped, only own articles are listed when no author is given. Articles of others are listed only once they're published. Time params are in RFC 3339 format, ranges include the start and exclude the end.
+// @Description  Pass next_cursor or prev_cursor of a page as cursor to get the neighbouring one, the sort params have to stay the same. Links to neighbouring pages are also sent in the Link header.
+// @Description  With page_id the response is a plain array of articles, as in older versions of the API
 // @Tags         articles
 // @Accept       json
 // @Produce      json
-// @Param   page_id   query    int32   true  "Article PageID query param"
-// @Param   page_size  query    int32   true  "Article PageSize query param"
-// @Success      200  {object}  []db.Article
+// @Param   author   query    []string   false  "Author usernames"  collectionFormat(multi)
+// @Param   created_after   query    string   false  "Created at or after"
+// @Param   created_before   query    string   false  "Created before"
+// @Param   edited_after   query    string   false  "Edited at or after"
+// @Param   edited_before   query    string   false  "Edited before"
+// @Param   edited   query    bool   false  "Edited or never edited articles only"
+// @Param   status   query    []string   false  "Statuses, articles of others are listed only when published"  collectionFormat(multi)
+// @Param   tag   query    []string   false  "Tags"  collectionFormat(multi)
+// @Param   tag_mode   query    string   false  "Whether articles need any of the tags or all of them, any by default"  Enums(any, all)
+// @Param   sort   query    string   false  "Sort column, created_at by default"  Enums(created_at, edited_at, headline)
+// @Param   order   query    string   false  "Sort direction, asc by default"  Enums(asc, desc)
+// @Param   cursor   query    string   false  "Cursor returned with a neighbouring page"
+// @Param   limit   query    int32   false  "Number of articles, limited by the server config"
+// @Param   with_total   query    bool   false  "Send the number of all matching articles in the X-Total-Count header"
+// @Param   page_id   query    int32   false  "Article PageID query param"
+// @Param   page_size  query    int32   false  "Article PageSize query param, required with page_id"
+// @Success      200  {object}  api.listArticlesResponse
+// @Header       200  {string}  Link  "Links to the first and neighbouring pages"
+// @Header       200  {integer}  X-Total-Count  "Number of all matching articles, only with with_total"
 // @Failure      400  {object} object{error=string}
 // @Failure      500  {object} object{error=string}
 // @Security BearerAuth
@@ -117,21 +316,218 @@ func (server *Server) listArticles(ctx *gin.Context) {
 		return
 	}
 
+	pageSize := req.Limit
+	if req.PageID > 0 {
+		if req.Cursor != "" || req.Limit > 0 {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errPagingMode))
+			return
+		}
+		if req.PageSize == 0 {
+			err := errors.New("page_size is required with page_id")
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		pageSize = req.PageSize
+	}
+	if pageSize == 0 {
+		pageSize = defaultArticleLimit
+		if pageSize > server.config.ArticleMaxPageSize {
+			pageSize = server.config.ArticleMaxPageSize
+		}
+	}
+	if pageSize > server.config.ArticleMaxPageSize {
+		err := fmt.Errorf("page size can't be larger than %d", server.config.ArticleMaxPageSize)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var tags []string
+	if len(req.Tags) > 0 {
+		var err error
+		tags, err = util.NormalizeTags(req.Tags)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
+	sort := req.Sort
+	if sort == "" {
+		sort = db.ArticleSortCreatedAt
+	}
 	arg := db.ListArticlesParams{
-		Author: authPayload.Username,
+		Viewer:        authPayload.Username,
+		Authors:       req.Authors,
+		Statuses:      req.Statuses,
+		CreatedAfter:  nullTime(req.CreatedAfter),
+		CreatedBefore: nullTime(req.CreatedBefore),
+		EditedAfter:   nullTime(req.EditedAfter),
+		EditedBefore:  nullTime(req.EditedBefore),
+		Tags:          tags,
+		AllTags:       req.TagMode == "all",
+		SortBy:        sort,
+		SortDesc:      req.Order == "desc",
+		Limit:         pageSize,
+	}
+	if len(arg.Authors) == 0 {
+		arg.Authors = []string{authPayload.Username}
+	}
+	if req.Edited != nil {
+		arg.Edited = sql.NullBool{Bool: *req.Edited, Valid: true}
+	}
+	if req.PageID > 0 {
+		arg.Offset = (req.PageID - 1) * pageSize
+	} else {
+		// One more article than asked for is fetched to know if there is a page after this one
+		arg.Limit = pageSize + 1
+		if req.Cursor != "" {
+			cursor, err := decodeArticleCursor(req.Cursor, arg.SortBy, arg.SortDesc)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, errorResponse(err))
+				return
+			}
+			arg.Cursor = cursor
+		}
+	}
+
+	articles, err := server.store.ListArticles(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.WithTotal {
+		total, err := server.store.CountArticles(ctx, arg)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.Header(totalCountHeaderKey, strconv.FormatInt(total, 10))
+	}
+
+	resp := listArticlesResponse{}
+	resp.Articles, err = server.newArticleResponses(ctx, articles)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.PageID > 0 {
+		links := []string{pageLink(ctx, "first", map[string]string{"page_id": "1"})}
+		if req.PageID > 1 {
+			links = append(links, pageLink(ctx, "prev", map[string]string{"page_id": strconv.Itoa(int(req.PageID - 1))}))
+		}
+		if len(articles) == int(pageSize) {
+			links = append(links, pageLink(ctx, "next", map[string]string{"page_id": strconv.Itoa(int(req.PageID + 1))}))
+		}
+		ctx.Header("Link", strings.Join(links, ", "))
+		ctx.JSON(http.StatusOK, resp.Articles)
+		return
+	}
+
+	backward := arg.Cursor != nil && arg.Cursor.Backward
+	// The extra article is the one farthest from the cursor
+	hasMore := len(articles) > int(pageSize)
+	if hasMore && backward {
+		resp.Articles = resp.Articles[1:]
+	} else if hasMore {
+		resp.Articles = resp.Articles[:pageSize]
+	}
+
+	if len(resp.Articles) > 0 {
+		first, last := resp.Articles[0].Article, resp.Articles[len(resp.Articles)-1].Article
+		// Coming from another page means there is one in that direction
+		if (backward && hasMore) || (arg.Cursor != nil && !backward) {
+			resp.PrevCursor = encodeCursor(newArticleCursor(first, arg.SortBy, arg.SortDesc, true))
+		}
+		if (!backward && hasMore) || backward {
+			resp.NextCursor = encodeCursor(newArticleCursor(last, arg.SortBy, arg.SortDesc, false))
+		}
+	}
+
+	links := []string{pageLink(ctx, "first", map[string]string{"cursor": ""})}
+	if resp.PrevCursor != "" {
+		links = append(links, pageLink(ctx, "prev", map[string]string{"cursor": resp.PrevCursor}))
+	}
+	if resp.NextCursor != "" {
+		links = append(links, pageLink(ctx, "next", map[string]string{"cursor": resp.NextCursor}))
+	}
+	ctx.Header("Link", strings.Join(links, ", "))
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// nullTime treats the zero time of a missing query param as null
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+type listPublicArticlesRequest struct {
+	Author   string `form:"author" binding:"omitempty,alphanum"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// ListPublicArticles godoc
+// @Summary      Get the list of all articles
+// @Description  Get published articles of all authors, newest first. Doesn't require logging in
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param   author   query    string   false  "Author username filter"
+// @Param   page_id   query    int32   true  "Article PageID query param"
+// @Param   page_size  query    int32   true  "Article PageSize query param"
+// @Success      200  {object}  []api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /public/articles [get]
+func (server *Server) listPublicArticles(ctx *gin.Context) {
+	var req listPublicArticlesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListPublicArticlesParams{
+		Author: sql.NullString{String: req.Author, Valid: req.Author != ""},
 		Limit:  req.PageSize,
 		Offset: (req.PageID - 1) * req.PageSize,
 	}
 
-	articles, err := server.store.ListArticles(ctx, arg)
+	articles, err := server.store.ListPublicArticles(ctx, arg)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, articles)
+	resp, err := server.newArticleResponses(ctx, articles)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// GetPublicArticle godoc
+// @Summary      Get an article without logging in
+// @Description  Get a specific article by ID. Doesn't require logging in, articles that aren't published can only be read by their author
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Success      200  {object}  api.articleResponse
+// @Header       200  {string}  ETag  "Version of the article, to be sent in If-Match when changing it"
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /public/articles/{id} [get]
+func (server *Server) getPublicArticle(ctx *gin.Context) {
+	server.getArticle(ctx)
 }
 
 type deleteArticleRequest struct {
@@ -145,10 +541,13 @@ type deleteArticleRequest struct {
 // @Accept       json
 // @Produce      json
 // @Param   id   path    int64   true  "Article ID path param"
+// @Param   If-Match   header    string   false  "ETag of the article, the delete fails when it changed since"
 // @Success      200  {object} object{}
 // @Failure      400  {object} object{error=string}
 // @Failure      401  {object} object{error=string}
 // @Failure      404  {object} object{error=string}
+// @Failure      412  {object} object{error=string}
+// @Failure      428  {object} object{error=string}
 // @Failure      500  {object} object{error=string}
 // @Security BearerAuth
 // @Router       /articles/{id} [delete]
@@ -161,7 +560,7 @@ func (server *Server) deleteArticle(ctx *gin.Context) {
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	article, err := server.store.GetArticle(ctx, req.ID)
+	article, err := server.getReadableArticle(ctx, req.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -178,11 +577,23 @@ func (server *Server) deleteArticle(ctx *gin.Context) {
 		return
 	}
 
-	err = server.store.DeleteArticle(ctx, req.ID)
+	version, ok := server.checkIfMatch(ctx, article)
+	if !ok {
+		return
+	}
+
+	deleted, err := server.store.DeleteArticle(ctx, db.DeleteArticleParams{
+		ID:      req.ID,
+		Version: version,
+	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	if deleted == 0 && version.Valid {
+		ctx.JSON(http.StatusPreconditionFailed, errorResponse(errArticleChanged))
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{})
 }
@@ -192,21 +603,27 @@ type updateArticleRequest struct {
 	Data struct {
 		Headline null.String `json:"headline"`
 		Content  null.String `json:"content"`
+		// Replaces all tags when given, an empty list removes them
+		Tags []string `json:"tags" binding:"omitempty,max=10"`
 	}
 }
 
 // UpdateArticle godoc
 // @Summary      Update an article
-// @Description  Update an article as a article owner
+// @Description  Update an article as a article owner. Tags are replaced only when given. Every update is kept as a new revision
 // @Tags         articles
 // @Accept       json
 // @Produce      json
 // @Param   id   path    int64   true  "Article ID path param"
-// @Param   payload   body    object{headline=string,content=string}   true  "Article update payload"
-// @Success      200  {object}  db.Article
+// @Param   payload   body    object{headline=string,content=string,tags=[]string}   true  "Article update payload"
+// @Param   If-Match   header    string   false  "ETag of the article, the update fails when it changed since"
+// @Success      200  {object}  api.articleResponse
+// @Header       200  {string}  ETag  "ETag of the updated article"
 // @Failure      400  {object} object{error=string}
 // @Failure      401  {object} object{error=string}
 // @Failure      404  {object} object{error=string}
+// @Failure      412  {object} object{error=string}
+// @Failure      428  {object} object{error=string}
 // @Failure      500  {object} object{error=string}
 // @Security BearerAuth
 // @Router       /articles/{id} [patch]
@@ -221,7 +638,7 @@ func (server *Server) updateArticle(ctx *gin.Context) {
 		return
 	}
 
-	article, err := server.store.GetArticle(ctx, req.ID)
+	article, err := server.getReadableArticle(ctx, req.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -239,17 +656,50 @@ func (server *Server) updateArticle(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.UpdateArticleParams{
-		ID:       req.ID,
-		Headline: req.Data.Headline.NullString,
-		Content:  req.Data.Content.NullString,
+	version, ok := server.checkIfMatch(ctx, article)
+	if !ok {
+		return
+	}
+
+	arg := db.UpdateArticleTxParams{
+		UpdateArticleParams: db.UpdateArticleParams{
+			ID:       req.ID,
+			Headline: req.Data.Headline.NullString,
+			Content:  req.Data.Content.NullString,
+			Version:  version,
+		},
+		Editor: authPayload.Username,
+	}
+	// A missing list is nil and keeps the tags, an empty one removes them
+	if req.Data.Tags != nil {
+		arg.Tags, err = util.NormalizeTags(req.Data.Tags)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	result, err := server.store.UpdateArticleTx(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows && version.Valid {
+			ctx.JSON(http.StatusPreconditionFailed, errorResponse(errArticleChanged))
+			return
+		}
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	updatedArticle, err := server.store.UpdateArticle(ctx, arg)
+	resp := []articleResponse{newArticleResponse(result.Article, result.Tags)}
+	err = server.addArticleStats(ctx, resp)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, updatedArticle)
+	ctx.Header(etagHeaderKey, articleETag(result.Article))
+	ctx.JSON(http.StatusOK, resp[0])
 }
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+)
+
+var errStatusChanged = errors.New("article status was changed in the meantime")
+
+// getReadableArticle gets an article if the reader can see it. Articles that aren't published
+// are hidden from everyone but their author, as if they didn't exist
+func (server *Server) getReadableArticle(ctx *gin.Context, id int64) (db.Article, error) {
+	article, err := server.store.GetArticle(ctx, id)
+	if err != nil {
+		return db.Article{}, err
+	}
+
+	if article.Status != util.ArticlePublished {
+		authPayload := optionalAuthPayload(ctx)
+		if authPayload == nil || authPayload.Username != article.Author {
+			return db.Article{}, sql.ErrNoRows
+		}
+	}
+	return article, nil
+}
+
+type articleStatusRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type scheduleArticleRequest struct {
+	PublishAt time.Time `json:"publish_at" binding:"required"`
+}
+
+// PublishArticle godoc
+// @Summary      Publish an article
+// @Description  Publish a draft, scheduled or archived article right away
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Success      200  {object}  api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      409  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/publish [post]
+func (server *Server) publishArticle(ctx *gin.Context) {
+	server.changeArticleStatus(ctx, util.ArticlePublished, func(article db.Article) sql.NullTime {
+		return nullTime(time.Now())
+	})
+}
+
+// ScheduleArticle godoc
+// @Summary      Schedule an article
+// @Description  Schedule a draft to be published at publish_at, or move the publication of a scheduled article
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   payload   body    api.scheduleArticleRequest    true  "Publication time in RFC 3339 format, has to be in the future"
+// @Success      200  {object}  api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      409  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/schedule [post]
+func (server *Server) scheduleArticle(ctx *gin.Context) {
+	var req scheduleArticleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !req.PublishAt.After(time.Now()) {
+		err := errors.New("publish_at has to be in the future")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.changeArticleStatus(ctx, util.ArticleScheduled, func(article db.Article) sql.NullTime {
+		return nullTime(req.PublishAt)
+	})
+}
+
+// UnpublishArticle godoc
+// @Summary      Turn an article back into a draft
+// @Description  Turn a published, scheduled or archived article back into a draft only its author can see
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Success      200  {object}  api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      409  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/unpublish [post]
+func (server *Server) unpublishArticle(ctx *gin.Context) {
+	server.changeArticleStatus(ctx, util.ArticleDraft, func(article db.Article) sql.NullTime {
+		return sql.NullTime{}
+	})
+}
+
+// ArchiveArticle godoc
+// @Summary      Archive an article
+// @Description  Take a published article down. It's kept for its author and can be published again
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Success      200  {object}  api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      409  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/archive [post]
+func (server *Server) archiveArticle(ctx *gin.Context) {
+	// The time of the publication is kept
+	server.changeArticleStatus(ctx, util.ArticleArchived, func(article db.Article) sql.NullTime {
+		return article.PublishAt
+	})
+}
+
+// changeArticleStatus moves an article of the logged in user to another status.
+// publishAt gives the publish_at the article gets based on its current state
+func (server *Server) changeArticleStatus(ctx *gin.Context, status string, publishAt func(article db.Article) sql.NullTime) {
+	var req articleStatusRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	article, err := server.getReadableArticle(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if article.Author != authPayload.Username {
+		err := errors.New("article doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !util.CanChangeArticleStatus(article.Status, status) {
+		err := fmt.Errorf("%s article can't become %s", article.Status, status)
+		ctx.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	// The status the article was read in is checked again by the update,
+	// in case the publisher or another request changed it since
+	article, err = server.store.ChangeArticleStatus(ctx, db.ChangeArticleStatusParams{
+		ID:           article.ID,
+		Status:       status,
+		PublishAt:    publishAt(article),
+		FromStatuses: []string{article.Status},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errStatusChanged))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := server.newSingleArticleResponse(ctx, article)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,437 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleStatusAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+
+	draft := randomArticle(user.Username)
+	draft.Status = util.ArticleDraft
+
+	published := randomArticle(user.Username)
+	published.PublishAt = sql.NullTime{Time: time.Now().Add(-time.Hour).UTC().Truncate(time.Second), Valid: true}
+
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	testCases := []struct {
+		name          string
+		username      string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Publish",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/publish", draft.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(draft.ID)).
+					Times(1).
+					Return(draft, nil)
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ChangeArticleStatusParams) (db.Article, error) {
+						require.Equal(t, draft.ID, arg.ID)
+						require.Equal(t, util.ArticlePublished, arg.Status)
+						require.Equal(t, []string{util.ArticleDraft}, arg.FromStatuses)
+						require.True(t, arg.PublishAt.Valid)
+						require.WithinDuration(t, time.Now(), arg.PublishAt.Time, time.Second)
+
+						article := draft
+						article.Status = arg.Status
+						article.PublishAt = arg.PublishAt
+						return article, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, util.ArticlePublished, resp.Status)
+				require.True(t, resp.PublishAt.Valid)
+			},
+		},
+		{
+			name:     "Schedule",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/schedule", draft.ID),
+			body:     gin.H{"publish_at": publishAt},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(draft.ID)).
+					Times(1).
+					Return(draft, nil)
+				arg := db.ChangeArticleStatusParams{
+					ID:           draft.ID,
+					Status:       util.ArticleScheduled,
+					PublishAt:    sql.NullTime{Time: publishAt, Valid: true},
+					FromStatuses: []string{util.ArticleDraft},
+				}
+				scheduled := draft
+				scheduled.Status = util.ArticleScheduled
+				scheduled.PublishAt = arg.PublishAt
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(scheduled, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, util.ArticleScheduled, resp.Status)
+				require.True(t, publishAt.Equal(resp.PublishAt.Time))
+			},
+		},
+		{
+			name:     "ScheduleInThePast",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/schedule", draft.ID),
+			body:     gin.H{"publish_at": time.Now().Add(-time.Minute)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "ScheduleWithoutTime",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/schedule", draft.ID),
+			body:     gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Unpublish",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/unpublish", published.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(published.ID)).
+					Times(1).
+					Return(published, nil)
+				arg := db.ChangeArticleStatusParams{
+					ID:           published.ID,
+					Status:       util.ArticleDraft,
+					FromStatuses: []string{util.ArticlePublished},
+				}
+				unpublished := published
+				unpublished.Status = util.ArticleDraft
+				unpublished.PublishAt = sql.NullTime{}
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(unpublished, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, util.ArticleDraft, resp.Status)
+				require.False(t, resp.PublishAt.Valid)
+			},
+		},
+		{
+			name:     "Archive",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/archive", published.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(published.ID)).
+					Times(1).
+					Return(published, nil)
+				arg := db.ChangeArticleStatusParams{
+					ID:           published.ID,
+					Status:       util.ArticleArchived,
+					PublishAt:    published.PublishAt,
+					FromStatuses: []string{util.ArticlePublished},
+				}
+				archived := published
+				archived.Status = util.ArticleArchived
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(archived, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, util.ArticleArchived, resp.Status)
+				require.True(t, published.PublishAt.Time.Equal(resp.PublishAt.Time))
+			},
+		},
+		{
+			name:     "InvalidTransition",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/archive", draft.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(draft.ID)).
+					Times(1).
+					Return(draft, nil)
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "ChangedInTheMeantime",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/publish", draft.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(draft.ID)).
+					Times(1).
+					Return(draft, nil)
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Article{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "DraftOfAnotherUser",
+			username: other.Username,
+			url:      fmt.Sprintf("/articles/%d/publish", draft.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(draft.ID)).
+					Times(1).
+					Return(draft, nil)
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "PublishedOfAnotherUser",
+			username: other.Username,
+			url:      fmt.Sprintf("/articles/%d/archive", published.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(published.ID)).
+					Times(1).
+					Return(published, nil)
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			username: user.Username,
+			url:      fmt.Sprintf("/articles/%d/publish", draft.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(draft.ID)).
+					Times(1).
+					Return(draft, nil)
+				store.EXPECT().
+					ChangeArticleStatus(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Article{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubArticleDetails(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, tc.username, http.MethodPost, tc.url)
+			if tc.body != nil {
+				data, err := json.Marshal(tc.body)
+				require.NoError(t, err)
+				request.Body = io.NopCloser(bytes.NewReader(data))
+			}
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDraftVisibility(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+
+	draft := randomArticle(user.Username)
+	draft.Status = util.ArticleDraft
+
+	testCases := []struct {
+		name     string
+		username string
+		code     int
+	}{
+		{
+			name:     "Author",
+			username: user.Username,
+			code:     http.StatusOK,
+		},
+		{
+			name:     "OtherUser",
+			username: other.Username,
+			code:     http.StatusNotFound,
+		},
+		{
+			name: "Anonymous",
+			code: http.StatusNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetArticle(gomock.Any(), gomock.Eq(draft.ID)).
+				Times(1).
+				Return(draft, nil)
+			stubArticleDetails(store)
+
+			var server *Server
+			var request *http.Request
+			if tc.username != "" {
+				server, request = newAuthorizedTestRequest(t, ctrl, store, tc.username, http.MethodGet, fmt.Sprintf("/articles/%d", draft.ID))
+			} else {
+				server = newTestServer(t, store, nil, nil)
+				var err error
+				request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/public/articles/%d", draft.ID), nil)
+				require.NoError(t, err)
+			}
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
+}
+
+func TestArticleOwnership(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+
+	draft := randomArticle(user.Username)
+	draft.Status = util.ArticleDraft
+	published := randomArticle(user.Username)
+	published.Status = util.ArticlePublished
+
+	testCases := []struct {
+		name    string
+		article db.Article
+		method  string
+		code    int
+	}{
+		{
+			name:    "DeleteOthersDraft",
+			article: draft,
+			method:  http.MethodDelete,
+			// Same as reading it, so that drafts of others can't be found
+			code: http.StatusNotFound,
+		},
+		{
+			name:    "UpdateOthersDraft",
+			article: draft,
+			method:  http.MethodPatch,
+			code:    http.StatusNotFound,
+		},
+		{
+			name:    "DeleteOthersPublished",
+			article: published,
+			method:  http.MethodDelete,
+			code:    http.StatusUnauthorized,
+		},
+		{
+			name:    "UpdateOthersPublished",
+			article: published,
+			method:  http.MethodPatch,
+			code:    http.StatusUnauthorized,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetArticle(gomock.Any(), gomock.Eq(tc.article.ID)).
+				Times(1).
+				Return(tc.article, nil)
+			store.EXPECT().
+				DeleteArticle(gomock.Any(), gomock.Any()).
+				Times(0)
+			store.EXPECT().
+				UpdateArticleTx(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, other.Username, tc.method, fmt.Sprintf("/articles/%d", tc.article.ID))
+			request.Body = io.NopCloser(bytes.NewReader([]byte(`{"headline":"changed"}`)))
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.code, recorder.Code)
+		})
+	}
+}
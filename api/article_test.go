@@ -64,6 +64,7 @@ func TestArticleAPI(t *testing.T) {
 					Get(gomock.Any(), gomock.Eq(sessionID.String())).
 					Times(1).
 					Return(arg, nil)
+				expectAccountAccess(store, user.Username, util.RoleUser)
 
 				store.EXPECT().
 					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
@@ -99,6 +100,7 @@ func TestArticleAPI(t *testing.T) {
 					Get(gomock.Any(), gomock.Eq(sessionID.String())).
 					Times(1).
 					Return(arg, nil)
+				expectAccountAccess(store, user.Username, util.RoleUser)
 
 				store.EXPECT().
 					GetArticle(gomock.Any(), gomock.Any()).
@@ -133,11 +135,14 @@ func TestArticleAPI(t *testing.T) {
 					Get(gomock.Any(), gomock.Eq(sessionID.String())).
 					Times(1).
 					Return(arg_session, nil)
+				expectAccountAccess(store, user.Username, util.RoleUser)
 
 				arg_store := db.ListArticlesParams{
-					Author: article.Author,
-					Limit:  5,
-					Offset: (2 - 1) * 5,
+					Viewer:  user.Username,
+					Authors: []string{article.Author},
+					SortBy:  db.ArticleSortCreatedAt,
+					Limit:   5,
+					Offset:  (2 - 1) * 5,
 				}
 				articles := []db.Article{article, article}
 				store.EXPECT().
@@ -209,16 +214,22 @@ func TestArticleAPI(t *testing.T) {
 					Get(gomock.Any(), gomock.Eq(sessionID.String())).
 					Times(1).
 					Return(arg_session, nil)
+				expectAccountAccess(store, user.Username, util.RoleUser)
 
-				arg_store := db.CreateArticleParams{
-					Author:   user.Username,
-					Headline: article.Headline,
-					Content:  article.Content,
+				arg_store := db.CreateArticleTxParams{
+					CreateArticleParams: db.CreateArticleParams{
+						Author:         user.Username,
+						Headline:       article.Headline,
+						Content:        article.Content,
+						Status:         util.ArticleDraft,
+						SearchLanguage: defaultSearchLanguage,
+					},
+					Tags: []string{},
 				}
 				store.EXPECT().
-					CreateArticle(gomock.Any(), gomock.Eq(arg_store)).
+					CreateArticleTx(gomock.Any(), gomock.Eq(arg_store)).
 					Times(1).
-					Return(article, nil)
+					Return(db.ArticleTxResult{Article: article}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusCreated, recorder.Code)
@@ -238,8 +249,10 @@ func TestArticleAPI(t *testing.T) {
 			sessionClient := mockSession.NewMockSessionClient(ctrl)
 			tc.buildStubs(store, sessionClient)
 
+			stubArticleDetails(store)
+
 			// start test server and send request
-			server := newTestServer(t, store, sessionClient)
+			server := newTestServer(t, store, sessionClient, nil)
 			recorder := httptest.NewRecorder()
 
 			// Marshal body data to JSON
@@ -262,14 +275,365 @@ func TestArticleAPI(t *testing.T) {
 	}
 }
 
+func TestListArticlesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	articles := []db.Article{randomArticle(user.Username), randomArticle("other")}
+	createdAfter := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OwnArticlesByDefault",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListArticlesParams{
+					Viewer:  user.Username,
+					Authors: []string{user.Username},
+					SortBy:  db.ArticleSortCreatedAt,
+					Limit:   5,
+					Offset:  0,
+				}
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(articles[:1], nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "Filters",
+			query: "?page_id=2&page_size=20&author=other&author=" + user.Username + "&created_after=2023-01-02T15:04:05Z&edited=false&sort=headline&order=desc",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListArticlesParams{
+					Viewer:       user.Username,
+					Authors:      []string{"other", user.Username},
+					CreatedAfter: sql.NullTime{Time: createdAfter, Valid: true},
+					Edited:       sql.NullBool{Bool: false, Valid: true},
+					SortBy:       db.ArticleSortHeadline,
+					SortDesc:     true,
+					Limit:        20,
+					Offset:       20,
+				}
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, got db.ListArticlesParams) ([]db.Article, error) {
+						require.True(t, got.CreatedAfter.Time.Equal(createdAfter))
+						got.CreatedAfter.Time = createdAfter
+						require.Equal(t, arg, got)
+						return articles, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []db.Article
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, len(articles))
+			},
+		},
+		{
+			name:  "InvalidSort",
+			query: "?page_id=1&page_size=5&sort=content",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidTime",
+			query: "?page_id=1&page_size=5&created_before=yesterday",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidAuthor",
+			query: "?page_id=1&page_size=5&author=x%27%20OR%201=1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "PageSizeTooLarge",
+			query: "?page_id=1&page_size=51",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Article{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			stubArticleDetails(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, http.MethodGet, "/articles"+tc.query)
+			server.config.ArticleMaxPageSize = 50
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListArticlesCursorAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	articles := make([]db.Article, 6)
+	for i := range articles {
+		articles[i] = randomArticle(user.Username)
+		articles[i].ID = int64(i + 1)
+		articles[i].CreatedAt = time.Date(2023, 1, i+1, 0, 0, 0, 0, time.UTC)
+	}
+	nextCursor := encodeCursor(newArticleCursor(articles[4], db.ArticleSortCreatedAt, false, false))
+	prevCursor := encodeCursor(newArticleCursor(articles[5], db.ArticleSortCreatedAt, false, true))
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "FirstPage",
+			query: "?limit=5&with_total=true",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListArticlesParams) ([]db.Article, error) {
+						require.Nil(t, arg.Cursor)
+						require.Equal(t, int32(6), arg.Limit)
+						return articles, nil
+					})
+				store.EXPECT().
+					CountArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(42), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "42", recorder.Header().Get(totalCountHeaderKey))
+
+				resp := requireBodyArticleList(t, recorder)
+				require.Len(t, resp.Articles, 5)
+				require.Equal(t, nextCursor, resp.NextCursor)
+				require.Empty(t, resp.PrevCursor)
+
+				link := recorder.Header().Get("Link")
+				require.Contains(t, link, `rel="first"`)
+				require.Contains(t, link, "cursor="+nextCursor)
+				require.Contains(t, link, `rel="next"`)
+				require.NotContains(t, link, `rel="prev"`)
+			},
+		},
+		{
+			name:  "NextPage",
+			query: "?limit=5&cursor=" + nextCursor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListArticlesParams) ([]db.Article, error) {
+						require.Equal(t, &db.ArticleCursor{Value: articles[4].CreatedAt, ID: articles[4].ID}, arg.Cursor)
+						return articles[5:], nil
+					})
+				store.EXPECT().
+					CountArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get(totalCountHeaderKey))
+
+				resp := requireBodyArticleList(t, recorder)
+				require.Len(t, resp.Articles, 1)
+				require.Empty(t, resp.NextCursor)
+				require.Equal(t, prevCursor, resp.PrevCursor)
+				require.Contains(t, recorder.Header().Get("Link"), `rel="prev"`)
+			},
+		},
+		{
+			name:  "PrevPage",
+			query: "?limit=5&cursor=" + prevCursor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListArticlesParams) ([]db.Article, error) {
+						require.True(t, arg.Cursor.Backward)
+						require.Equal(t, articles[5].ID, arg.Cursor.ID)
+						return articles[:5], nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				resp := requireBodyArticleList(t, recorder)
+				require.Len(t, resp.Articles, 5)
+				require.Equal(t, nextCursor, resp.NextCursor)
+				require.Empty(t, resp.PrevCursor)
+			},
+		},
+		{
+			name:  "CursorOfAnotherOrder",
+			query: "?limit=5&sort=headline&cursor=" + nextCursor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvalidCursor)
+			},
+		},
+		{
+			name:  "InvalidCursor",
+			query: "?limit=5&cursor=abc",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "CursorWithPageID",
+			query: "?page_id=2&page_size=5&cursor=" + nextCursor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errPagingMode)
+			},
+		},
+		{
+			name:  "PageIDLinks",
+			query: "?page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(articles[:5], nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				link := recorder.Header().Get("Link")
+				require.Contains(t, link, `page_id=1&page_size=5>; rel="prev"`)
+				require.Contains(t, link, `page_id=3&page_size=5>; rel="next"`)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			stubArticleDetails(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, http.MethodGet, "/articles"+tc.query)
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
 // Helper functions
 
+// stubArticleDetails lets the tags and stats of listed articles be loaded, for the cases that don't check them
+func stubArticleDetails(store *mockdb.MockStore) {
+	store.EXPECT().
+		ListTagsOfArticles(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return([]db.ListTagsOfArticlesRow{}, nil)
+	store.EXPECT().
+		CountCommentsOfArticles(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return([]db.CountCommentsOfArticlesRow{}, nil)
+	store.EXPECT().
+		CountFavoritesOfArticles(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return([]db.CountFavoritesOfArticlesRow{}, nil)
+	store.EXPECT().
+		CountReactionsOfArticles(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return([]db.CountReactionsOfArticlesRow{}, nil)
+}
+
+func requireBodyArticleList(t *testing.T, recorder *httptest.ResponseRecorder) listArticlesResponse {
+	var resp listArticlesResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	return resp
+}
+
 func randomArticle(author string) db.Article {
 	return db.Article{
 		ID:       util.RandomInt(1, 1000),
 		Author:   author,
 		Headline: util.RandomString(10),
 		Content:  util.RandomString(25),
+		Status:   util.ArticlePublished,
 	}
 }
 
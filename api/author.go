@@ -0,0 +1,140 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"gopkg.in/guregu/null.v3"
+)
+
+// Public projection of the user, safe to show to anyone
+type authorResponse struct {
+	Username        string    `json:"username"`
+	FullName        string    `json:"full_name"`
+	JoinedAt        time.Time `json:"joined_at"`
+	ArticleCount    int64     `json:"article_count"`
+	LastPublishedAt null.Time `json:"last_published_at" swaggertype:"string"`
+}
+
+func newAuthorResponse(author db.GetAuthorRow) authorResponse {
+	return authorResponse{
+		Username:        author.Username,
+		FullName:        author.FullName,
+		JoinedAt:        author.JoinedAt,
+		ArticleCount:    author.ArticleCount,
+		LastPublishedAt: null.NewTime(author.LastPublishedAt.Time, author.LastPublishedAt.Valid),
+	}
+}
+
+type getAuthorRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// GetAuthor godoc
+// @Summary      Get an author
+// @Description  Get public profile of an author
+// @Tags         authors
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Author username path param"
+// @Success      200  {object}  api.authorResponse
+// @Success      301  {object}  object{}  "Author has been renamed, Location header points to the current profile"
+// @Failure      400  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /authors/{username} [get]
+func (server *Server) getAuthor(ctx *gin.Context) {
+	var req getAuthorRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	author, err := server.store.GetAuthor(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			server.redirectRenamedAuthor(ctx, req.Username, err)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAuthorResponse(author))
+}
+
+// redirectRenamedAuthor redirects to the current profile of an author who used to have the username.
+// Existing users always take precedence over redirects
+func (server *Server) redirectRenamedAuthor(ctx *gin.Context, oldUsername string, notFoundErr error) {
+	username, err := server.store.GetUsernameRedirect(ctx, oldUsername)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(notFoundErr))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Redirect(http.StatusMovedPermanently, "/authors/"+url.PathEscape(username))
+}
+
+type listAuthorsRequest struct {
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+	SortBy   string `form:"sort_by" binding:"omitempty,oneof=article_count joined_at username"`
+	Order    string `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+// ListAuthors godoc
+// @Summary      Get the list of authors
+// @Description  Get the list of public author profiles, by default the most prolific first
+// @Tags         authors
+// @Accept       json
+// @Produce      json
+// @Param   page_id   query    int32   true  "Authors PageID query param"
+// @Param   page_size  query    int32   true  "Authors PageSize query param"
+// @Param   sort_by  query    string   false  "Sort field" Enums(article_count, joined_at, username)
+// @Param   order  query    string   false  "Sort order" Enums(asc, desc)
+// @Success      200  {object}  []api.authorResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /authors [get]
+func (server *Server) listAuthors(ctx *gin.Context) {
+	var req listAuthorsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.SortBy == "" {
+		req.SortBy = "article_count"
+	}
+	if req.Order == "" {
+		req.Order = "desc"
+	}
+
+	arg := db.ListAuthorsParams{
+		SortBy:     req.SortBy,
+		Descending: req.Order == "desc",
+		Limit:      req.PageSize,
+		Offset:     (req.PageID - 1) * req.PageSize,
+	}
+
+	authors, err := server.store.ListAuthors(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]authorResponse, len(authors))
+	for i, author := range authors {
+		resp[i] = newAuthorResponse(db.GetAuthorRow(author))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
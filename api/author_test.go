@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorAPI(t *testing.T) {
+	author := randomAuthor()
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			url:  fmt.Sprintf("/authors/%s", author.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthor(gomock.Any(), gomock.Eq(author.Username)).
+					Times(1).
+					Return(author, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAuthor(t, recorder.Body, author)
+			},
+		},
+		{
+			name: "NotFound",
+			url:  "/authors/notfound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthor(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetAuthorRow{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUsernameRedirect(gomock.Any(), gomock.Eq("notfound")).
+					Times(1).
+					Return("", sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "Renamed",
+			url:  "/authors/oldname",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetAuthor(gomock.Any(), gomock.Eq("oldname")).
+					Times(1).
+					Return(db.GetAuthorRow{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUsernameRedirect(gomock.Any(), gomock.Eq("oldname")).
+					Times(1).
+					Return(author.Username, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusMovedPermanently, recorder.Code)
+				require.Equal(t, fmt.Sprintf("/authors/%s", author.Username), recorder.Header().Get("Location"))
+			},
+		},
+		{
+			name: "OKList",
+			url:  "/authors?page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAuthorsParams{
+					SortBy:     "article_count",
+					Descending: true,
+					Limit:      5,
+					Offset:     5,
+				}
+				store.EXPECT().
+					ListAuthors(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ListAuthorsRow{db.ListAuthorsRow(author)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OKListSorted",
+			url:  "/authors?page_id=1&page_size=5&sort_by=username&order=asc",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAuthorsParams{
+					SortBy:     "username",
+					Descending: false,
+					Limit:      5,
+					Offset:     0,
+				}
+				store.EXPECT().
+					ListAuthors(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ListAuthorsRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidSort",
+			url:  "/authors?page_id=1&page_size=5&sort_by=email",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListAuthors(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// No authorization needed
+			server := newTestServer(t, store, nil, nil)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// Helper functions
+
+func randomAuthor() db.GetAuthorRow {
+	return db.GetAuthorRow{
+		Username:     util.RandomAuthor(),
+		FullName:     util.RandomAuthor(),
+		JoinedAt:     time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+		ArticleCount: util.RandomInt(1, 100),
+		LastPublishedAt: sql.NullTime{
+			Time:  time.Now().UTC().Truncate(time.Second),
+			Valid: true,
+		},
+	}
+}
+
+func requireBodyMatchAuthor(t *testing.T, body *bytes.Buffer, author db.GetAuthorRow) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotAuthor authorResponse
+	err = json.Unmarshal(data, &gotAuthor)
+	require.NoError(t, err)
+	require.Equal(t, newAuthorResponse(author), gotAuthor)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+)
+
+const (
+	avatarFormField      = "avatar"
+	defaultAvatarMaxSize = 5 << 20
+	// Room for multipart boundaries and headers on top of the file itself
+	multipartOverhead = 64 << 10
+)
+
+// Sizes of the square avatar variants in pixels
+var avatarSizes = []int{64, 128, 256}
+
+var errAvatarTooLarge = errors.New("avatar file is too large")
+
+func avatarKey(avatarID string, size int) string {
+	return fmt.Sprintf("avatars/%s/%d.jpg", avatarID, size)
+}
+
+// avatarURLs returns URLs of all avatar variants keyed by size, or nil if there is no avatar
+func (server *Server) avatarURLs(avatarID string) map[string]string {
+	if avatarID == "" || server.blobStore == nil {
+		return nil
+	}
+
+	urls := make(map[string]string, len(avatarSizes))
+	for _, size := range avatarSizes {
+		urls[strconv.Itoa(size)] = server.blobStore.URL(avatarKey(avatarID, size))
+	}
+	return urls
+}
+
+// UploadAvatar godoc
+// @Summary      Upload avatar
+// @Description  Upload a JPEG, PNG or WebP image as the avatar of the logged in user. It's cropped to a square and stored in a few sizes
+// @Tags         users
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param   avatar   formData    file    true  "Avatar image"
+// @Success      200  {object}  api.userResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      413  {object} object{error=string}
+// @Failure      415  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /users/me/avatar [put]
+func (server *Server) uploadAvatar(ctx *gin.Context) {
+	maxSize := server.config.AvatarMaxSize
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+multipartOverhead)
+
+	fileHeader, err := ctx.FormFile(avatarFormField)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(errAvatarTooLarge))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if fileHeader.Size > maxSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(errAvatarTooLarge))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	img, err := util.DecodeImage(data)
+	if err != nil {
+		if errors.Is(err, util.ErrUnsupportedImage) {
+			ctx.JSON(http.StatusUnsupportedMediaType, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Every upload gets a new ID, so that the URLs change and cached old images aren't served
+	avatarUUID, err := uuid.NewRandom()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	avatarID := avatarUUID.String()
+	uploaded := make([]string, 0, len(avatarSizes))
+	for _, size := range avatarSizes {
+		variant, err := util.EncodeJPEG(util.ResizeSquare(img, size))
+		if err == nil {
+			key := avatarKey(avatarID, size)
+			err = server.blobStore.Put(ctx, key, bytes.NewReader(variant), "image/jpeg")
+			uploaded = append(uploaded, key)
+		}
+		if err != nil {
+			server.deleteBlobs(ctx, uploaded)
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		server.deleteBlobs(ctx, uploaded)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	oldAvatarID := user.AvatarID
+
+	arg := db.SetUserAvatarParams{
+		Username: authPayload.Username,
+		AvatarID: avatarID,
+	}
+	user, err = server.store.SetUserAvatar(ctx, arg)
+	if err != nil {
+		server.deleteBlobs(ctx, uploaded)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if oldAvatarID != "" {
+		oldKeys := make([]string, len(avatarSizes))
+		for i, size := range avatarSizes {
+			oldKeys[i] = avatarKey(oldAvatarID, size)
+		}
+		server.deleteBlobs(ctx, oldKeys)
+	}
+
+	ctx.JSON(http.StatusOK, server.newUserResponse(user))
+}
+
+// deleteBlobs removes blobs that are no longer referenced. Failure only leaves garbage behind, so it's just logged
+func (server *Server) deleteBlobs(ctx *gin.Context, keys []string) {
+	for _, key := range keys {
+		if err := server.blobStore.Delete(ctx, key); err != nil {
+			log.Printf("cannot delete blob %s: %v", key, err)
+		}
+	}
+}
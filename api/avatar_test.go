@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kamilwrzyszcz/go_example/blob"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadAvatarAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user.AvatarID = "old-avatar"
+	pngImage := randomPNG(t, 300, 200)
+
+	testCases := []struct {
+		name          string
+		field         string
+		file          []byte
+		maxSize       int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, blobDir string)
+	}{
+		{
+			name:  "OK",
+			field: avatarFormField,
+			file:  pngImage,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					SetUserAvatar(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.SetUserAvatarParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotEmpty(t, arg.AvatarID)
+
+						updated := user
+						updated.AvatarID = arg.AvatarID
+						return updated, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, blobDir string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp.AvatarURLs, len(avatarSizes))
+
+				variants, err := filepath.Glob(filepath.Join(blobDir, "avatars", "*", "*.jpg"))
+				require.NoError(t, err)
+				require.Len(t, variants, len(avatarSizes))
+
+				// Previous avatar is removed
+				old, err := filepath.Glob(filepath.Join(blobDir, "avatars", "old-avatar", "*.jpg"))
+				require.NoError(t, err)
+				require.Empty(t, old)
+			},
+		},
+		{
+			name:  "UnsupportedType",
+			field: avatarFormField,
+			file:  []byte("GIF89a not really a gif"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserAvatar(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, blobDir string) {
+				require.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+			},
+		},
+		{
+			name:    "TooLarge",
+			field:   avatarFormField,
+			file:    pngImage,
+			maxSize: 100,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserAvatar(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, blobDir string) {
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errAvatarTooLarge)
+			},
+		},
+		{
+			name:  "MissingFile",
+			field: "picture",
+			file:  pngImage,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SetUserAvatar(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, blobDir string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			field: avatarFormField,
+			file:  pngImage,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					SetUserAvatar(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, blobDir string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+				// Uploaded variants are cleaned up, the old avatar is kept
+				variants, err := filepath.Glob(filepath.Join(blobDir, "avatars", "*", "*.jpg"))
+				require.NoError(t, err)
+				require.Len(t, variants, len(avatarSizes))
+				for _, variant := range variants {
+					require.Contains(t, variant, "old-avatar")
+				}
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// Old avatar already stored
+			blobDir := t.TempDir()
+			blobStore, err := blob.NewLocalStore(blobDir, "http://localhost:8080/blobs")
+			require.NoError(t, err)
+			for _, size := range avatarSizes {
+				err = blobStore.Put(context.Background(), avatarKey("old-avatar", size), bytes.NewReader([]byte("old")), "image/jpeg")
+				require.NoError(t, err)
+			}
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, err := writer.CreateFormFile(tc.field, "avatar.png")
+			require.NoError(t, err)
+			_, err = part.Write(tc.file)
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, http.MethodPut, "/users/me/avatar")
+			server.blobStore = blobStore
+			if tc.maxSize > 0 {
+				server.config.AvatarMaxSize = tc.maxSize
+			}
+			request.Body = io.NopCloser(body)
+			request.ContentLength = int64(body.Len())
+			request.Header.Set("Content-Type", writer.FormDataContentType())
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, blobDir)
+		})
+	}
+}
+
+func randomPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
@@ -0,0 +1,414 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultCommentEditWindow = 15 * time.Minute
+	commentModeFlat          = "flat"
+)
+
+var (
+	errCommentDeleted    = errors.New("comment was deleted")
+	errParentNotInThread = errors.New("parent comment belongs to another article")
+)
+
+// commentResponse hides the author of deleted comments, their content is already removed
+type commentResponse struct {
+	ID        int64      `json:"id"`
+	ArticleID int64      `json:"article_id"`
+	ParentID  *int64     `json:"parent_id"`
+	Author    string     `json:"author"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	Deleted   bool       `json:"deleted"`
+	// Only set when comments are listed as a tree
+	Replies []*commentResponse `json:"replies,omitempty"`
+}
+
+func newCommentResponse(comment db.Comment) *commentResponse {
+	resp := &commentResponse{
+		ID:        comment.ID,
+		ArticleID: comment.ArticleID,
+		Author:    comment.Author,
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt,
+		Deleted:   comment.DeletedAt.Valid,
+	}
+	if comment.ParentID.Valid {
+		resp.ParentID = &comment.ParentID.Int64
+	}
+	if comment.EditedAt.Valid {
+		resp.EditedAt = &comment.EditedAt.Time
+	}
+	if resp.Deleted {
+		resp.Author = ""
+	}
+	return resp
+}
+
+// newCommentTree puts the replies under the comments they answer, keeping their order
+func newCommentTree(roots []db.Comment, replies []db.Comment) []*commentResponse {
+	tree := make([]*commentResponse, len(roots))
+	byID := make(map[int64]*commentResponse, len(roots)+len(replies))
+	for i, comment := range roots {
+		tree[i] = newCommentResponse(comment)
+		byID[comment.ID] = tree[i]
+	}
+	// Replies are sorted by creation, so parents always come before their replies
+	for _, comment := range replies {
+		resp := newCommentResponse(comment)
+		byID[comment.ID] = resp
+		if parent, ok := byID[comment.ParentID.Int64]; ok {
+			parent.Replies = append(parent.Replies, resp)
+		}
+	}
+	return tree
+}
+
+// commentCounts counts the comments of the articles, articles without comments are missing from the map
+func (server *Server) commentCounts(ctx *gin.Context, articleIDs []int64) (map[int64]int64, error) {
+	rows, err := server.store.CountCommentsOfArticles(ctx, articleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ArticleID] = row.CommentCount
+	}
+	return counts, nil
+}
+
+type articleCommentsURI struct {
+	ArticleID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type createCommentRequest struct {
+	Content  string `json:"content" binding:"required,max=5000"`
+	ParentID int64  `json:"parent_id" binding:"omitempty,min=1"`
+}
+
+// CreateComment godoc
+// @Summary      Comment an article
+// @Description  Add a comment to an article. Give parent_id to reply to another comment of the article
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   payload   body    api.createCommentRequest    true  "Comment payload"
+// @Success      201  {object}  api.commentResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/comments [post]
+func (server *Server) createComment(ctx *gin.Context) {
+	var uri articleCommentsURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req createCommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, err := server.getReadableArticle(ctx, uri.ArticleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.CreateCommentParams{
+		ArticleID: uri.ArticleID,
+		Author:    authPayload.Username,
+		Content:   req.Content,
+	}
+
+	if req.ParentID != 0 {
+		parent, err := server.store.GetComment(ctx, req.ParentID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if parent.ArticleID != uri.ArticleID {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errParentNotInThread))
+			return
+		}
+		if parent.DeletedAt.Valid {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errCommentDeleted))
+			return
+		}
+		arg.ParentID = sql.NullInt64{Int64: parent.ID, Valid: true}
+	}
+
+	comment, err := server.store.CreateComment(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newCommentResponse(comment))
+}
+
+type listCommentsRequest struct {
+	Mode     string `form:"mode" binding:"omitempty,oneof=tree flat"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// ListComments godoc
+// @Summary      Get comments of an article
+// @Description  Get comments of an article, oldest first. As a tree the page contains top level comments with all their replies, flat lists every comment with its parent_id. Deleted comments that have replies are kept with empty content
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   mode   query    string   false  "Listing mode, tree by default"  Enums(tree, flat)
+// @Param   page_id   query    int32   true  "Comments PageID query param"
+// @Param   page_size  query    int32   true  "Comments PageSize query param"
+// @Success      200  {object}  []api.commentResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /articles/{id}/comments [get]
+func (server *Server) listComments(ctx *gin.Context) {
+	var uri articleCommentsURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listCommentsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, err := server.getReadableArticle(ctx, uri.ArticleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if req.Mode == commentModeFlat {
+		comments, err := server.store.ListComments(ctx, db.ListCommentsParams{
+			ArticleID: uri.ArticleID,
+			Limit:     req.PageSize,
+			Offset:    (req.PageID - 1) * req.PageSize,
+		})
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		resp := make([]*commentResponse, len(comments))
+		for i, comment := range comments {
+			resp[i] = newCommentResponse(comment)
+		}
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	// Pages are made of top level comments, the whole thread of each of them is included
+	roots, err := server.store.ListRootComments(ctx, db.ListRootCommentsParams{
+		ArticleID: uri.ArticleID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var replies []db.Comment
+	if len(roots) > 0 {
+		rootIDs := make([]int64, len(roots))
+		for i, root := range roots {
+			rootIDs[i] = root.ID
+		}
+		replies, err = server.store.ListCommentReplies(ctx, rootIDs)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newCommentTree(roots, replies))
+}
+
+type commentURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type updateCommentRequest struct {
+	Content string `json:"content" binding:"required,max=5000"`
+}
+
+// UpdateComment godoc
+// @Summary      Edit a comment
+// @Description  Edit a comment as its author. Comments can only be edited for a while after they were written
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Comment ID path param"
+// @Param   payload   body    api.updateCommentRequest    true  "Comment update payload"
+// @Success      200  {object}  api.commentResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /comments/{id} [patch]
+func (server *Server) updateComment(ctx *gin.Context) {
+	var uri commentURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req updateCommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	comment, err := server.store.GetComment(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if comment.DeletedAt.Valid {
+		ctx.JSON(http.StatusNotFound, errorResponse(errCommentDeleted))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if comment.Author != authPayload.Username {
+		err := errors.New("comment doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if time.Since(comment.CreatedAt) > server.config.CommentEditWindow {
+		err := fmt.Errorf("comments can only be edited within %s after they were written", server.config.CommentEditWindow)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	comment, err = server.store.UpdateComment(ctx, db.UpdateCommentParams{
+		ID:      comment.ID,
+		Content: req.Content,
+	})
+	if err != nil {
+		// Deleted in the meantime
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errCommentDeleted))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+// DeleteComment godoc
+// @Summary      Delete a comment
+// @Description  Delete a comment as its author or as the owner of the article. Comments with replies are only emptied, so that the thread stays in place
+// @Tags         comments
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Comment ID path param"
+// @Success      200  {object}  object{}
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /comments/{id} [delete]
+func (server *Server) deleteComment(ctx *gin.Context) {
+	var uri commentURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	comment, err := server.store.GetComment(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if comment.DeletedAt.Valid {
+		ctx.JSON(http.StatusNotFound, errorResponse(errCommentDeleted))
+		return
+	}
+
+	// Article owners moderate the comments under their articles
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if comment.Author != authPayload.Username {
+		article, err := server.store.GetArticle(ctx, comment.ArticleID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if article.Author != authPayload.Username {
+			err := errors.New("only the author of the comment or of the article can delete it")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+	}
+
+	deleted, err := server.store.DeleteComment(ctx, comment.ID)
+	if err != nil {
+		// A reply committed after the check for replies, so the comment is kept like any other with replies
+		pqErr, ok := err.(*pq.Error)
+		if !ok || pqErr.Code.Name() != "foreign_key_violation" {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+	if deleted == 0 {
+		err = server.store.MarkCommentDeleted(ctx, comment.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
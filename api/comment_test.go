@@ -0,0 +1,646 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentAPI(t *testing.T) {
+	owner, _ := randomUser(t)
+	commenter, _ := randomUser(t)
+	stranger, _ := randomUser(t)
+	article := randomArticle(owner.Username)
+	comment := randomComment(article.ID, commenter.Username)
+	otherComment := randomComment(article.ID+1, commenter.Username)
+	deletedComment := randomComment(article.ID, "")
+	deletedComment.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	oldComment := randomComment(article.ID, commenter.Username)
+	oldComment.CreatedAt = time.Now().Add(-time.Hour)
+	comment.ID, otherComment.ID, deletedComment.ID, oldComment.ID = 1, 2, 3, 4
+
+	testCases := []struct {
+		name          string
+		username      string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "Create",
+			username: commenter.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/comments", article.ID),
+			body:     gin.H{"content": comment.Content},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.CreateCommentParams{
+					ArticleID: article.ID,
+					Author:    commenter.Username,
+					Content:   comment.Content,
+				}
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(comment, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				resp := requireBodyComment(t, recorder.Body)
+				require.Equal(t, comment.ID, resp.ID)
+				require.Equal(t, commenter.Username, resp.Author)
+				require.Nil(t, resp.ParentID)
+			},
+		},
+		{
+			name:     "Reply",
+			username: stranger.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/comments", article.ID),
+			body:     gin.H{"content": "reply", "parent_id": comment.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				arg := db.CreateCommentParams{
+					ArticleID: article.ID,
+					ParentID:  sql.NullInt64{Int64: comment.ID, Valid: true},
+					Author:    stranger.Username,
+					Content:   "reply",
+				}
+				reply := randomComment(article.ID, stranger.Username)
+				reply.ParentID = arg.ParentID
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(reply, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				resp := requireBodyComment(t, recorder.Body)
+				require.NotNil(t, resp.ParentID)
+				require.Equal(t, comment.ID, *resp.ParentID)
+			},
+		},
+		{
+			name:     "ReplyToOtherArticle",
+			username: commenter.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/comments", article.ID),
+			body:     gin.H{"content": "reply", "parent_id": otherComment.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(otherComment.ID)).
+					Times(1).
+					Return(otherComment, nil)
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "ReplyToDeleted",
+			username: commenter.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/comments", article.ID),
+			body:     gin.H{"content": "reply", "parent_id": deletedComment.ID},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(deletedComment.ID)).
+					Times(1).
+					Return(deletedComment, nil)
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "CreateArticleNotFound",
+			username: commenter.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/comments", article.ID),
+			body:     gin.H{"content": "comment"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(db.Article{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "CreateEmptyContent",
+			username: commenter.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/comments", article.ID),
+			body:     gin.H{"content": ""},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "Update",
+			username: commenter.Username,
+			method:   http.MethodPatch,
+			url:      fmt.Sprintf("/comments/%d", comment.ID),
+			body:     gin.H{"content": "edited"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				edited := comment
+				edited.Content = "edited"
+				edited.EditedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					UpdateComment(gomock.Any(), gomock.Eq(db.UpdateCommentParams{ID: comment.ID, Content: "edited"})).
+					Times(1).
+					Return(edited, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyComment(t, recorder.Body)
+				require.Equal(t, "edited", resp.Content)
+				require.NotNil(t, resp.EditedAt)
+			},
+		},
+		{
+			name:     "UpdateNotAuthor",
+			username: owner.Username,
+			method:   http.MethodPatch,
+			url:      fmt.Sprintf("/comments/%d", comment.ID),
+			body:     gin.H{"content": "edited"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				store.EXPECT().
+					UpdateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "UpdateAfterEditWindow",
+			username: commenter.Username,
+			method:   http.MethodPatch,
+			url:      fmt.Sprintf("/comments/%d", oldComment.ID),
+			body:     gin.H{"content": "edited"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(oldComment.ID)).
+					Times(1).
+					Return(oldComment, nil)
+				store.EXPECT().
+					UpdateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "UpdateDeleted",
+			username: commenter.Username,
+			method:   http.MethodPatch,
+			url:      fmt.Sprintf("/comments/%d", deletedComment.ID),
+			body:     gin.H{"content": "edited"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(deletedComment.ID)).
+					Times(1).
+					Return(deletedComment, nil)
+				store.EXPECT().
+					UpdateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "DeleteByAuthor",
+			username: commenter.Username,
+			method:   http.MethodDelete,
+			url:      fmt.Sprintf("/comments/%d", comment.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DeleteComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					MarkCommentDeleted(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "DeleteWithRepliesByArticleOwner",
+			username: owner.Username,
+			method:   http.MethodDelete,
+			url:      fmt.Sprintf("/comments/%d", comment.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					DeleteComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					MarkCommentDeleted(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "DeleteRacingWithReply",
+			username: commenter.Username,
+			method:   http.MethodDelete,
+			url:      fmt.Sprintf("/comments/%d", comment.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				store.EXPECT().
+					DeleteComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(int64(0), &pq.Error{Code: "23503"})
+				store.EXPECT().
+					MarkCommentDeleted(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "DeleteByStranger",
+			username: stranger.Username,
+			method:   http.MethodDelete,
+			url:      fmt.Sprintf("/comments/%d", comment.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					DeleteComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "DeleteNotFound",
+			username: commenter.Username,
+			method:   http.MethodDelete,
+			url:      "/comments/2137",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Comment{}, sql.ErrNoRows)
+				store.EXPECT().
+					DeleteComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, tc.username, tc.method, tc.url)
+			if tc.body != nil {
+				data, err := json.Marshal(tc.body)
+				require.NoError(t, err)
+				request.Body = io.NopCloser(bytes.NewReader(data))
+			}
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListCommentsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	article := randomArticle(user.Username)
+
+	// root1 <- reply1 <- reply2, root2 without replies
+	root1 := randomComment(article.ID, user.Username)
+	root2 := randomComment(article.ID, user.Username)
+	reply1 := randomComment(article.ID, user.Username)
+	reply1.ParentID = sql.NullInt64{Int64: root1.ID, Valid: true}
+	reply2 := randomComment(article.ID, user.Username)
+	reply2.ParentID = sql.NullInt64{Int64: reply1.ID, Valid: true}
+	root1.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	root1.Content = ""
+	// The tree is built by id, random ones could collide
+	root1.ID, root2.ID, reply1.ID, reply2.ID = 1, 2, 3, 4
+	reply1.ParentID.Int64, reply2.ParentID.Int64 = root1.ID, reply1.ID
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "Tree",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.ListRootCommentsParams{
+					ArticleID: article.ID,
+					Limit:     5,
+					Offset:    0,
+				}
+				store.EXPECT().
+					ListRootComments(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Comment{root1, root2}, nil)
+				store.EXPECT().
+					ListCommentReplies(gomock.Any(), gomock.Eq([]int64{root1.ID, root2.ID})).
+					Times(1).
+					Return([]db.Comment{reply1, reply2}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []commentResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, 2)
+
+				require.Equal(t, root1.ID, resp[0].ID)
+				require.True(t, resp[0].Deleted)
+				require.Empty(t, resp[0].Author)
+				require.Len(t, resp[0].Replies, 1)
+				require.Equal(t, reply1.ID, resp[0].Replies[0].ID)
+				require.Len(t, resp[0].Replies[0].Replies, 1)
+				require.Equal(t, reply2.ID, resp[0].Replies[0].Replies[0].ID)
+
+				require.Equal(t, root2.ID, resp[1].ID)
+				require.Empty(t, resp[1].Replies)
+			},
+		},
+		{
+			name:  "Flat",
+			query: "?mode=flat&page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.ListCommentsParams{
+					ArticleID: article.ID,
+					Limit:     5,
+					Offset:    5,
+				}
+				store.EXPECT().
+					ListComments(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Comment{root1, reply1, root2, reply2}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []commentResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, 4)
+				require.Nil(t, resp[0].ParentID)
+				require.Equal(t, root1.ID, *resp[1].ParentID)
+				require.Empty(t, resp[1].Replies)
+			},
+		},
+		{
+			name:  "NoComments",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					ListRootComments(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Comment{}, nil)
+				store.EXPECT().
+					ListCommentReplies(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.JSONEq(t, "[]", recorder.Body.String())
+			},
+		},
+		{
+			name:  "ArticleNotFound",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(db.Article{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidMode",
+			query: "?mode=nested&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					ListRootComments(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Comment{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// Comments are readable without logging in
+			server := newTestServer(t, store, nil, nil)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/public/articles/%d/comments%s", article.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestArticleCommentCount(t *testing.T) {
+	user, _ := randomUser(t)
+	article := randomArticle(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+		Times(1).
+		Return(article, nil)
+	store.EXPECT().
+		ListTagsOfArticles(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.ListTagsOfArticlesRow{}, nil)
+	store.EXPECT().
+		CountCommentsOfArticles(gomock.Any(), gomock.Eq([]int64{article.ID})).
+		Times(1).
+		Return([]db.CountCommentsOfArticlesRow{{ArticleID: article.ID, CommentCount: 3}}, nil)
+	stubArticleDetails(store)
+
+	server := newTestServer(t, store, nil, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/public/articles/%d", article.ID), nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	resp := requireBodyArticleResponse(t, recorder.Body)
+	require.Equal(t, int64(3), resp.CommentCount)
+}
+
+func randomComment(articleID int64, author string) db.Comment {
+	return db.Comment{
+		ID:        util.RandomInt(1, 1000),
+		ArticleID: articleID,
+		Author:    author,
+		Content:   util.RandomString(20),
+		CreatedAt: time.Now(),
+	}
+}
+
+func requireBodyComment(t *testing.T, body *bytes.Buffer) commentResponse {
+	var resp commentResponse
+	err := json.NewDecoder(body).Decode(&resp)
+	require.NoError(t, err)
+	return resp
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// keysetCursor points at the last item of a page sorted by creation time and ID.
+// It's handed out to clients as an opaque string
+type keysetCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+}
+
+func encodeCursor(cursor interface{}) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func unmarshalCursor(s string, cursor interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return errInvalidCursor
+	}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return errInvalidCursor
+	}
+	return nil
+}
+
+func decodeCursor(s string) (keysetCursor, error) {
+	var cursor keysetCursor
+	if err := unmarshalCursor(s, &cursor); err != nil || cursor.ID <= 0 {
+		return cursor, errInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// articleCursor points at an article of a list read with the given sort order. Only the value of the sort column
+// is kept. A cursor can't be used with another order, the position would be meaningless
+type articleCursor struct {
+	Sort     string     `json:"s"`
+	Desc     bool       `json:"d,omitempty"`
+	Time     *time.Time `json:"t,omitempty"`
+	Headline string     `json:"h,omitempty"`
+	ID       int64      `json:"i"`
+	Backward bool       `json:"b,omitempty"`
+}
+
+func newArticleCursor(article db.Article, sort string, desc, backward bool) articleCursor {
+	cursor := articleCursor{Sort: sort, Desc: desc, ID: article.ID, Backward: backward}
+	switch sort {
+	case db.ArticleSortCreatedAt:
+		cursor.Time = &article.CreatedAt
+	case db.ArticleSortEditedAt:
+		if article.EditedAt.Valid {
+			cursor.Time = &article.EditedAt.Time
+		}
+	case db.ArticleSortHeadline:
+		cursor.Headline = article.Headline
+	}
+	return cursor
+}
+
+// decodeArticleCursor decodes the cursor and checks that it belongs to a list with the given sort order
+func decodeArticleCursor(s string, sort string, desc bool) (*db.ArticleCursor, error) {
+	var cursor articleCursor
+	if err := unmarshalCursor(s, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+	if cursor.Sort != sort || cursor.Desc != desc {
+		return nil, errInvalidCursor
+	}
+
+	dbCursor := &db.ArticleCursor{ID: cursor.ID, Backward: cursor.Backward}
+	switch sort {
+	case db.ArticleSortCreatedAt:
+		if cursor.Time == nil {
+			return nil, errInvalidCursor
+		}
+		dbCursor.Value = *cursor.Time
+	case db.ArticleSortEditedAt:
+		if cursor.Time != nil {
+			dbCursor.Value = *cursor.Time
+		}
+	case db.ArticleSortHeadline:
+		dbCursor.Value = cursor.Headline
+	}
+	return dbCursor, nil
+}
+
+// pageLink formats an RFC 8288 link to the current list with some query params replaced. Empty values remove params
+func pageLink(ctx *gin.Context, rel string, params map[string]string) string {
+	link := *ctx.Request.URL
+	query := link.Query()
+	for key, value := range params {
+		if value == "" {
+			query.Del(key)
+		} else {
+			query.Set(key, value)
+		}
+	}
+	link.RawQuery = query.Encode()
+	return fmt.Sprintf("<%s>; rel=\"%s\"", link.RequestURI(), rel)
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+)
+
+const (
+	deviceCodeGrantType       = "urn:ietf:params:oauth:grant-type:device_code"
+	defaultDevicePollInterval = 5 * time.Second
+	// Added to the polling interval whenever a client polls too often
+	devicePollSlowDown = 5 * time.Second
+)
+
+// Error codes from RFC 8628, returned as the error message
+var (
+	errAuthorizationPending = errors.New("authorization_pending")
+	errSlowDown             = errors.New("slow_down")
+	errExpiredToken         = errors.New("expired_token")
+	errAccessDenied         = errors.New("access_denied")
+	errInvalidGrant         = errors.New("invalid_grant")
+	errUnsupportedGrantType = errors.New("unsupported_grant_type")
+)
+
+var errUserCodeNotFound = errors.New("user code not found or expired")
+
+type deviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	// Seconds until the codes expire
+	ExpiresIn int64 `json:"expires_in"`
+	// Minimum number of seconds between polling requests
+	Interval int64 `json:"interval"`
+}
+
+// CreateDeviceCode godoc
+// @Summary      Start device authorization
+// @Description  Start the device authorization grant (RFC 8628). Show the user code to the user, who approves it while logged in elsewhere, then poll /oauth/token with the device code
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  api.deviceCodeResponse
+// @Failure      500  {object} object{error=string}
+// @Router       /oauth/device/code [post]
+func (server *Server) createDeviceCode(ctx *gin.Context) {
+	deviceCode, err := util.GenerateDeviceCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	userCode, err := util.GenerateUserCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.CreateDeviceAuthorizationParams{
+		HashedDeviceCode: util.HashDeviceCode(deviceCode),
+		UserCode:         userCode,
+		PollInterval:     int32(server.config.DevicePollInterval / time.Second),
+		ExpiresAt:        time.Now().Add(server.config.DeviceCodeDuration),
+	}
+
+	authorization, err := server.store.CreateDeviceAuthorization(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := deviceCodeResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                authorization.UserCode,
+		VerificationURI:         server.config.DeviceVerifyURL,
+		VerificationURIComplete: server.config.DeviceVerifyURL + "?user_code=" + url.QueryEscape(authorization.UserCode),
+		ExpiresIn:               int64(time.Until(authorization.ExpiresAt).Round(time.Second) / time.Second),
+		Interval:                int64(authorization.PollInterval),
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type approveDeviceCodeRequest struct {
+	UserCode string `json:"user_code" binding:"required,max=16"`
+	// Deny the device instead of approving it
+	Deny bool `json:"deny"`
+}
+
+// ApproveDeviceCode godoc
+// @Summary      Approve device
+// @Description  Approve or deny the device showing the user code. Once approved, the device gets a session of the logged in user
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.approveDeviceCodeRequest    true  "Approve device payload"
+// @Success      200  {object} object{}
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /oauth/device/approve [post]
+func (server *Server) approveDeviceCode(ctx *gin.Context) {
+	var req approveDeviceCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.DecideDeviceAuthorizationParams{
+		Status:   util.DeviceAuthorizationApproved,
+		Username: sql.NullString{String: authPayload.Username, Valid: true},
+		UserCode: util.NormalizeUserCode(req.UserCode),
+	}
+	if req.Deny {
+		arg.Status = util.DeviceAuthorizationDenied
+	}
+
+	_, err := server.store.DecideDeviceAuthorization(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errUserCodeNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+type exchangeDeviceCodeRequest struct {
+	GrantType  string `json:"grant_type" form:"grant_type" binding:"required"`
+	DeviceCode string `json:"device_code" form:"device_code" binding:"required"`
+}
+
+// ExchangeDeviceCode godoc
+// @Summary      Poll for device session
+// @Description  Poll with the device code until the user approves the device. Errors follow RFC 8628: authorization_pending, slow_down, expired_token, access_denied and invalid_grant
+// @Tags         oauth
+// @Accept       json,x-www-form-urlencoded
+// @Produce      json
+// @Param   payload   body    api.exchangeDeviceCodeRequest    true  "Device token payload"
+// @Success      200  {object}  api.loginUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /oauth/token [post]
+func (server *Server) exchangeDeviceCode(ctx *gin.Context) {
+	var req exchangeDeviceCodeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.GrantType != deviceCodeGrantType {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errUnsupportedGrantType))
+		return
+	}
+
+	authorization, err := server.store.GetDeviceAuthorization(ctx, util.HashDeviceCode(req.DeviceCode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidGrant))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if time.Now().After(authorization.ExpiresAt) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errExpiredToken))
+		return
+	}
+
+	// Polling too often slows the client down for all the following requests too
+	interval := time.Duration(authorization.PollInterval) * time.Second
+	tooFast := authorization.LastPolledAt.Valid && time.Since(authorization.LastPolledAt.Time) < interval
+	if tooFast {
+		interval += devicePollSlowDown
+	}
+
+	authorization, err = server.store.PollDeviceAuthorization(ctx, db.PollDeviceAuthorizationParams{
+		ID:           authorization.ID,
+		PollInterval: int32(interval / time.Second),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if tooFast {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errSlowDown))
+		return
+	}
+
+	switch authorization.Status {
+	case util.DeviceAuthorizationPending:
+		ctx.JSON(http.StatusBadRequest, errorResponse(errAuthorizationPending))
+		return
+	case util.DeviceAuthorizationDenied:
+		ctx.JSON(http.StatusBadRequest, errorResponse(errAccessDenied))
+		return
+	}
+
+	// The device code can be exchanged only once, concurrent requests can't both get a session
+	rows, err := server.store.DeleteDeviceAuthorization(ctx, authorization.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidGrant))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, authorization.Username.String)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidGrant))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !server.checkUserCanLogin(ctx, user) {
+		return
+	}
+
+	resp, err := server.newUserSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
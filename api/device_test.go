@@ -0,0 +1,389 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDeviceCodeAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateDeviceAuthorization(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateDeviceAuthorizationParams) (db.DeviceAuthorization, error) {
+			require.NotEmpty(t, arg.HashedDeviceCode)
+			require.Equal(t, int32(5), arg.PollInterval)
+			return db.DeviceAuthorization{
+				ID:               1,
+				HashedDeviceCode: arg.HashedDeviceCode,
+				UserCode:         arg.UserCode,
+				Status:           util.DeviceAuthorizationPending,
+				PollInterval:     arg.PollInterval,
+				ExpiresAt:        arg.ExpiresAt,
+			}, nil
+		})
+
+	server := newTestServer(t, store, nil, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodPost, "/oauth/device/code", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp deviceCodeResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.DeviceCode)
+	require.Equal(t, resp.UserCode, util.NormalizeUserCode(resp.UserCode))
+	require.Equal(t, "http://localhost:3000/device", resp.VerificationURI)
+	require.Contains(t, resp.VerificationURIComplete, resp.UserCode)
+	require.Equal(t, int64(60), resp.ExpiresIn)
+	require.Equal(t, int64(5), resp.Interval)
+}
+
+func TestApproveDeviceCodeAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Approve",
+			body: gin.H{"user_code": "bcdf ghjk"},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.DecideDeviceAuthorizationParams{
+					Status:   util.DeviceAuthorizationApproved,
+					Username: sql.NullString{String: user.Username, Valid: true},
+					UserCode: "BCDF-GHJK",
+				}
+				store.EXPECT().
+					DecideDeviceAuthorization(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.DeviceAuthorization{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Deny",
+			body: gin.H{"user_code": "BCDF-GHJK", "deny": true},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.DecideDeviceAuthorizationParams{
+					Status:   util.DeviceAuthorizationDenied,
+					Username: sql.NullString{String: user.Username, Valid: true},
+					UserCode: "BCDF-GHJK",
+				}
+				store.EXPECT().
+					DecideDeviceAuthorization(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.DeviceAuthorization{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			body: gin.H{"user_code": "BCDF-GHJK"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DecideDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.DeviceAuthorization{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errUserCodeNotFound)
+			},
+		},
+		{
+			name: "MissingUserCode",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DecideDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, http.MethodPost, "/oauth/device/approve")
+			request.Body = io.NopCloser(bytes.NewReader(data))
+			recorder := httptest.NewRecorder()
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestExchangeDeviceCodeAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	deviceCode := "device-code"
+
+	authorization := db.DeviceAuthorization{
+		ID:               1,
+		HashedDeviceCode: util.HashDeviceCode(deviceCode),
+		UserCode:         "BCDF-GHJK",
+		Status:           util.DeviceAuthorizationPending,
+		PollInterval:     5,
+		ExpiresAt:        time.Now().Add(time.Minute),
+	}
+	polledAt := func(authorization db.DeviceAuthorization, ago time.Duration) db.DeviceAuthorization {
+		authorization.LastPolledAt = sql.NullTime{Time: time.Now().Add(-ago), Valid: true}
+		return authorization
+	}
+	withStatus := func(authorization db.DeviceAuthorization, status string) db.DeviceAuthorization {
+		authorization.Status = status
+		if status == util.DeviceAuthorizationApproved {
+			authorization.Username = sql.NullString{String: user.Username, Valid: true}
+		}
+		return authorization
+	}
+
+	testCases := []struct {
+		name          string
+		form          url.Values
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			form: url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {deviceCode}},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				approved := withStatus(authorization, util.DeviceAuthorizationApproved)
+				store.EXPECT().
+					GetDeviceAuthorization(gomock.Any(), gomock.Eq(authorization.HashedDeviceCode)).
+					Times(1).
+					Return(approved, nil)
+				store.EXPECT().
+					PollDeviceAuthorization(gomock.Any(), gomock.Eq(db.PollDeviceAuthorizationParams{ID: 1, PollInterval: 5})).
+					Times(1).
+					Return(approved, nil)
+				store.EXPECT().
+					DeleteDeviceAuthorization(gomock.Any(), gomock.Eq(authorization.ID)).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.AccessToken)
+				require.NotEmpty(t, resp.RefreshToken)
+				require.Equal(t, user.Username, resp.User.Username)
+			},
+		},
+		{
+			name: "Pending",
+			form: url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {deviceCode}},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				pending := polledAt(authorization, 10*time.Second)
+				store.EXPECT().
+					GetDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(pending, nil)
+				store.EXPECT().
+					PollDeviceAuthorization(gomock.Any(), gomock.Eq(db.PollDeviceAuthorizationParams{ID: 1, PollInterval: 5})).
+					Times(1).
+					Return(pending, nil)
+				store.EXPECT().
+					DeleteDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errAuthorizationPending)
+			},
+		},
+		{
+			name: "SlowDown",
+			form: url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {deviceCode}},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				// Approved already, but the client has to wait anyway
+				approved := polledAt(withStatus(authorization, util.DeviceAuthorizationApproved), time.Second)
+				store.EXPECT().
+					GetDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(approved, nil)
+				store.EXPECT().
+					PollDeviceAuthorization(gomock.Any(), gomock.Eq(db.PollDeviceAuthorizationParams{ID: 1, PollInterval: 10})).
+					Times(1).
+					Return(approved, nil)
+				store.EXPECT().
+					DeleteDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errSlowDown)
+			},
+		},
+		{
+			name: "Expired",
+			form: url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {deviceCode}},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				expired := authorization
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				store.EXPECT().
+					GetDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(expired, nil)
+				store.EXPECT().
+					PollDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errExpiredToken)
+			},
+		},
+		{
+			name: "Denied",
+			form: url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {deviceCode}},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				denied := withStatus(authorization, util.DeviceAuthorizationDenied)
+				store.EXPECT().
+					GetDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(denied, nil)
+				store.EXPECT().
+					PollDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(denied, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errAccessDenied)
+			},
+		},
+		{
+			name: "AlreadyExchanged",
+			form: url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {deviceCode}},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				approved := withStatus(authorization, util.DeviceAuthorizationApproved)
+				store.EXPECT().
+					GetDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(approved, nil)
+				store.EXPECT().
+					PollDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(approved, nil)
+				store.EXPECT().
+					DeleteDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvalidGrant)
+			},
+		},
+		{
+			name: "UnknownDeviceCode",
+			form: url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {"unknown"}},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetDeviceAuthorization(gomock.Any(), gomock.Eq(util.HashDeviceCode("unknown"))).
+					Times(1).
+					Return(db.DeviceAuthorization{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvalidGrant)
+			},
+		},
+		{
+			name: "UnsupportedGrantType",
+			form: url.Values{"grant_type": {"password"}, "device_code": {deviceCode}},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetDeviceAuthorization(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errUnsupportedGrantType)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			tc.buildStubs(store, sessionClient)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			// RFC 8628 clients send the request form encoded
+			request, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tc.form.Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+)
+
+const (
+	etagHeaderKey    = "ETag"
+	ifMatchHeaderKey = "If-Match"
+)
+
+var (
+	errArticleChanged  = errors.New("article was changed in the meantime")
+	errIfMatchRequired = errors.New("If-Match header with the ETag of the article is required")
+)
+
+// articleETag is a strong ETag made of the version of the article
+func articleETag(article db.Article) string {
+	return fmt.Sprintf(`"%d"`, article.Version)
+}
+
+// checkIfMatch checks the If-Match header of a request about to change the article, responding with
+// an error when the precondition fails. It returns the version the write has to be guarded with,
+// as the article can still change between the read and the write. It's null when no version was asked for
+func (server *Server) checkIfMatch(ctx *gin.Context, article db.Article) (sql.NullInt32, bool) {
+	header := strings.TrimSpace(ctx.GetHeader(ifMatchHeaderKey))
+	if header == "" {
+		if server.config.ArticleNeedsIfMatch {
+			ctx.JSON(http.StatusPreconditionRequired, errorResponse(errIfMatchRequired))
+			return sql.NullInt32{}, false
+		}
+		return sql.NullInt32{}, true
+	}
+
+	// Any version of an existing article will do
+	if header == "*" {
+		return sql.NullInt32{}, true
+	}
+
+	if !etagMatches(header, articleETag(article)) {
+		ctx.JSON(http.StatusPreconditionFailed, errorResponse(errArticleChanged))
+		return sql.NullInt32{}, false
+	}
+	return sql.NullInt32{Int32: article.Version, Valid: true}, true
+}
+
+// etagMatches uses the strong comparison If-Match calls for, so weak ETags never match
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleETagAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	article := randomArticle(user.Username)
+	article.Version = 3
+
+	updated := article
+	updated.Headline = "new headline"
+	updated.Version = 4
+
+	version := sql.NullInt32{Int32: article.Version, Valid: true}
+	updateArg := func(version sql.NullInt32) db.UpdateArticleTxParams {
+		return db.UpdateArticleTxParams{
+			UpdateArticleParams: db.UpdateArticleParams{
+				ID:       article.ID,
+				Headline: sql.NullString{String: updated.Headline, Valid: true},
+				Version:  version,
+			},
+			Editor: user.Username,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		ifMatch       string
+		needsIfMatch  bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Get",
+			method: http.MethodGet,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, `"3"`, recorder.Header().Get(etagHeaderKey))
+			},
+		},
+		{
+			name:    "UpdateMatching",
+			method:  http.MethodPatch,
+			ifMatch: `"3"`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(updateArg(version))).
+					Times(1).
+					Return(db.ArticleTxResult{Article: updated, Tags: []string{}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, `"4"`, recorder.Header().Get(etagHeaderKey))
+			},
+		},
+		{
+			name:    "UpdateMatchingOneOfMany",
+			method:  http.MethodPatch,
+			ifMatch: `"1", "3"`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(updateArg(version))).
+					Times(1).
+					Return(db.ArticleTxResult{Article: updated, Tags: []string{}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "UpdateAnyVersion",
+			method:  http.MethodPatch,
+			ifMatch: "*",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(updateArg(sql.NullInt32{}))).
+					Times(1).
+					Return(db.ArticleTxResult{Article: updated, Tags: []string{}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "UpdateStale",
+			method:  http.MethodPatch,
+			ifMatch: `"2"`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+			},
+		},
+		{
+			name:    "UpdateWeakETag",
+			method:  http.MethodPatch,
+			ifMatch: `W/"3"`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+			},
+		},
+		{
+			name:    "UpdateChangedInTheMeantime",
+			method:  http.MethodPatch,
+			ifMatch: `"3"`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(updateArg(version))).
+					Times(1).
+					Return(db.ArticleTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+			},
+		},
+		{
+			name:         "UpdateNeedsIfMatch",
+			method:       http.MethodPatch,
+			needsIfMatch: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionRequired, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateWithoutIfMatch",
+			method: http.MethodPatch,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(updateArg(sql.NullInt32{}))).
+					Times(1).
+					Return(db.ArticleTxResult{Article: updated, Tags: []string{}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "DeleteMatching",
+			method:  http.MethodDelete,
+			ifMatch: `"3"`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					DeleteArticle(gomock.Any(), gomock.Eq(db.DeleteArticleParams{ID: article.ID, Version: version})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:    "DeleteStale",
+			method:  http.MethodDelete,
+			ifMatch: `"2"`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					DeleteArticle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+			},
+		},
+		{
+			name:    "DeleteChangedInTheMeantime",
+			method:  http.MethodDelete,
+			ifMatch: `"3"`,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					DeleteArticle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+			},
+		},
+		{
+			name:         "DeleteNeedsIfMatch",
+			method:       http.MethodDelete,
+			needsIfMatch: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					DeleteArticle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusPreconditionRequired, recorder.Code)
+			},
+		},
+		{
+			name:   "DeleteWithoutIfMatch",
+			method: http.MethodDelete,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					DeleteArticle(gomock.Any(), gomock.Eq(db.DeleteArticleParams{ID: article.ID})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubArticleDetails(store)
+
+			url := fmt.Sprintf("/articles/%d", article.ID)
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, tc.method, url)
+			server.config.ArticleNeedsIfMatch = tc.needsIfMatch
+			if tc.method == http.MethodPatch {
+				data, err := json.Marshal(gin.H{"headline": updated.Headline})
+				require.NoError(t, err)
+				request.Body = io.NopCloser(bytes.NewReader(data))
+			}
+			if tc.ifMatch != "" {
+				request.Header.Set(ifMatchHeaderKey, tc.ifMatch)
+			}
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+)
+
+type favoriteRequest struct {
+	ArticleID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// FavoriteArticle godoc
+// @Summary      Favorite an article
+// @Description  Add an article to the favorites of the logged in user. Favoriting it again changes nothing
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Success      200  {object}  api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/favorite [post]
+func (server *Server) favoriteArticle(ctx *gin.Context) {
+	server.setFavorite(ctx, true)
+}
+
+// UnfavoriteArticle godoc
+// @Summary      Unfavorite an article
+// @Description  Remove an article from the favorites of the logged in user. Unfavoriting it again changes nothing
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Success      200  {object}  api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/favorite [delete]
+func (server *Server) unfavoriteArticle(ctx *gin.Context) {
+	server.setFavorite(ctx, false)
+}
+
+// setFavorite responds with the article, so that the client gets the current count
+func (server *Server) setFavorite(ctx *gin.Context, favorite bool) {
+	var req favoriteRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	article, err := server.getReadableArticle(ctx, req.ArticleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if favorite {
+		err = server.store.AddFavorite(ctx, db.AddFavoriteParams{
+			Username:  authPayload.Username,
+			ArticleID: article.ID,
+		})
+	} else {
+		err = server.store.DeleteFavorite(ctx, db.DeleteFavoriteParams{
+			Username:  authPayload.Username,
+			ArticleID: article.ID,
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := server.newSingleArticleResponse(ctx, article)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type listFavoritesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// ListFavorites godoc
+// @Summary      Get favorite articles
+// @Description  Get the articles the logged in user favorited, most recently favorited first
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   page_id   query    int32   true  "Favorites PageID query param"
+// @Param   page_size  query    int32   true  "Favorites PageSize query param"
+// @Success      200  {object}  []api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /users/me/favorites [get]
+func (server *Server) listFavorites(ctx *gin.Context) {
+	var req listFavoritesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.ListFavoriteArticlesParams{
+		Username: authPayload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	}
+
+	articles, err := server.store.ListFavoriteArticles(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := server.newArticleResponses(ctx, articles)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type reactionRequest struct {
+	ArticleID int64  `uri:"id" binding:"required,min=1"`
+	Reaction  string `uri:"reaction" binding:"required"`
+}
+
+// AddReaction godoc
+// @Summary      React to an article
+// @Description  Leave a reaction on an article. A reader can leave each reaction once, adding it again changes nothing
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   reaction   path    string   true  "Reaction name"  Enums(thumbs_up, heart, laugh, hooray, confused, eyes)
+// @Success      200  {object}  api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/reactions/{reaction} [post]
+func (server *Server) addReaction(ctx *gin.Context) {
+	server.setReaction(ctx, true)
+}
+
+// RemoveReaction godoc
+// @Summary      Remove a reaction
+// @Description  Remove a reaction the logged in user left on an article
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   reaction   path    string   true  "Reaction name"  Enums(thumbs_up, heart, laugh, hooray, confused, eyes)
+// @Success      200  {object}  api.articleResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/reactions/{reaction} [delete]
+func (server *Server) removeReaction(ctx *gin.Context) {
+	server.setReaction(ctx, false)
+}
+
+func (server *Server) setReaction(ctx *gin.Context, react bool) {
+	var req reactionRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !util.IsSupportedReaction(req.Reaction) {
+		err := fmt.Errorf("unsupported reaction: %s", req.Reaction)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	article, err := server.getReadableArticle(ctx, req.ArticleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if react {
+		err = server.store.AddReaction(ctx, db.AddReactionParams{
+			Username:  authPayload.Username,
+			ArticleID: article.ID,
+			Reaction:  req.Reaction,
+		})
+	} else {
+		err = server.store.DeleteReaction(ctx, db.DeleteReactionParams{
+			Username:  authPayload.Username,
+			ArticleID: article.ID,
+			Reaction:  req.Reaction,
+		})
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := server.newSingleArticleResponse(ctx, article)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFavoriteAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	author, _ := randomUser(t)
+	article := randomArticle(author.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Favorite",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/articles/%d/favorite", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					AddFavorite(gomock.Any(), gomock.Eq(db.AddFavoriteParams{Username: user.Username, ArticleID: article.ID})).
+					Times(1).
+					Return(nil)
+				arg := db.CountFavoritesOfArticlesParams{
+					Username:   user.Username,
+					ArticleIds: []int64{article.ID},
+				}
+				store.EXPECT().
+					CountFavoritesOfArticles(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.CountFavoritesOfArticlesRow{{ArticleID: article.ID, FavoriteCount: 7, Favorited: true}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, int64(7), resp.FavoriteCount)
+				require.True(t, resp.Favorited)
+			},
+		},
+		{
+			name:   "Unfavorite",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/articles/%d/favorite", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					DeleteFavorite(gomock.Any(), gomock.Eq(db.DeleteFavoriteParams{Username: user.Username, ArticleID: article.ID})).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Zero(t, resp.FavoriteCount)
+				require.False(t, resp.Favorited)
+			},
+		},
+		{
+			name:   "NotFound",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/articles/%d/favorite", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(db.Article{}, sql.ErrNoRows)
+				store.EXPECT().
+					AddFavorite(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidID",
+			method: http.MethodPost,
+			url:    "/articles/0/favorite",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "React",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/articles/%d/reactions/%s", article.ID, util.ReactionHeart),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.AddReactionParams{
+					Username:  user.Username,
+					ArticleID: article.ID,
+					Reaction:  util.ReactionHeart,
+				}
+				store.EXPECT().
+					AddReaction(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CountReactionsOfArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.CountReactionsOfArticlesRow{
+						{ArticleID: article.ID, Reaction: util.ReactionEyes, ReactionCount: 1},
+						{ArticleID: article.ID, Reaction: util.ReactionHeart, ReactionCount: 3, Reacted: true},
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, map[string]int64{util.ReactionEyes: 1, util.ReactionHeart: 3}, resp.Reactions)
+				require.Equal(t, []string{util.ReactionHeart}, resp.MyReactions)
+			},
+		},
+		{
+			name:   "RemoveReaction",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/articles/%d/reactions/%s", article.ID, util.ReactionHeart),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.DeleteReactionParams{
+					Username:  user.Username,
+					ArticleID: article.ID,
+					Reaction:  util.ReactionHeart,
+				}
+				store.EXPECT().
+					DeleteReaction(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Empty(t, resp.Reactions)
+				require.Empty(t, resp.MyReactions)
+			},
+		},
+		{
+			name:   "UnsupportedReaction",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/articles/%d/reactions/angry", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					AddReaction(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "ReactInternalError",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/articles/%d/reactions/%s", article.ID, util.ReactionThumbsUp),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					AddReaction(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubArticleDetails(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, tc.method, tc.url)
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListFavoritesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	author, _ := randomUser(t)
+	articles := []db.Article{randomArticle(author.Username), randomArticle(author.Username)}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListFavoriteArticlesParams{
+					Username: user.Username,
+					Limit:    5,
+					Offset:   5,
+				}
+				store.EXPECT().
+					ListFavoriteArticles(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(articles, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []articleResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, len(articles))
+				for i := range resp {
+					require.Equal(t, articles[i], resp[i].Article)
+				}
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "?page_id=1&page_size=50",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListFavoriteArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListFavoriteArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Article{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubArticleDetails(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, http.MethodGet, "/users/me/favorites"+tc.query)
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestAnonymousArticleStats(t *testing.T) {
+	author, _ := randomUser(t)
+	article := randomArticle(author.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+		Times(1).
+		Return(article, nil)
+	// Nobody is logged in, so nothing is favorited by the reader
+	arg := db.CountFavoritesOfArticlesParams{
+		Username:   "",
+		ArticleIds: []int64{article.ID},
+	}
+	store.EXPECT().
+		CountFavoritesOfArticles(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return([]db.CountFavoritesOfArticlesRow{{ArticleID: article.ID, FavoriteCount: 2}}, nil)
+	stubArticleDetails(store)
+
+	server := newTestServer(t, store, nil, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/public/articles/%d", article.ID), nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	resp := requireBodyArticleResponse(t, recorder.Body)
+	require.Equal(t, int64(2), resp.FavoriteCount)
+	require.False(t, resp.Favorited)
+}
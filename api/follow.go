@@ -0,0 +1,264 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/lib/pq"
+)
+
+var (
+	errFollowSelf     = errors.New("users can't follow themselves")
+	errAuthorNotFound = errors.New("author not found")
+)
+
+type followAuthorRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// FollowAuthor godoc
+// @Summary      Follow an author
+// @Description  Follow an author to see their articles in the feed. Following an author twice is not an error
+// @Tags         authors
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Author username path param"
+// @Success      200  {object} object{}
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /authors/{username}/follow [post]
+func (server *Server) followAuthor(ctx *gin.Context) {
+	var req followAuthorRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if req.Username == authPayload.Username {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errFollowSelf))
+		return
+	}
+
+	arg := db.CreateFollowParams{
+		Follower: authPayload.Username,
+		Followee: req.Username,
+	}
+
+	err := server.store.CreateFollow(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "foreign_key_violation":
+				ctx.JSON(http.StatusNotFound, errorResponse(errAuthorNotFound))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+// UnfollowAuthor godoc
+// @Summary      Unfollow an author
+// @Description  Stop following an author. Unfollowing an author who isn't followed is not an error
+// @Tags         authors
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Author username path param"
+// @Success      200  {object} object{}
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /authors/{username}/follow [delete]
+func (server *Server) unfollowAuthor(ctx *gin.Context) {
+	var req followAuthorRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.DeleteFollowParams{
+		Follower: authPayload.Username,
+		Followee: req.Username,
+	}
+
+	err := server.store.DeleteFollow(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{})
+}
+
+type listFollowsUriRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type listFollowsQueryRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// ListFollowers godoc
+// @Summary      Get the list of followers
+// @Description  Get the list of users following an author, most recent first
+// @Tags         authors
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Author username path param"
+// @Param   page_id   query    int32   true  "Followers PageID query param"
+// @Param   page_size  query    int32   true  "Followers PageSize query param"
+// @Success      200  {object}  []db.ListFollowersRow
+// @Failure      400  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /authors/{username}/followers [get]
+func (server *Server) listFollowers(ctx *gin.Context) {
+	var uriReq listFollowsUriRequest
+	if err := ctx.ShouldBindUri(&uriReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listFollowsQueryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListFollowersParams{
+		Followee: uriReq.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	}
+
+	followers, err := server.store.ListFollowers(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, followers)
+}
+
+// ListFollowing godoc
+// @Summary      Get the list of followed authors
+// @Description  Get the list of authors a user follows, most recent first
+// @Tags         authors
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Author username path param"
+// @Param   page_id   query    int32   true  "Following PageID query param"
+// @Param   page_size  query    int32   true  "Following PageSize query param"
+// @Success      200  {object}  []db.ListFollowingRow
+// @Failure      400  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /authors/{username}/following [get]
+func (server *Server) listFollowing(ctx *gin.Context) {
+	var uriReq listFollowsUriRequest
+	if err := ctx.ShouldBindUri(&uriReq); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req listFollowsQueryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListFollowingParams{
+		Follower: uriReq.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	}
+
+	following, err := server.store.ListFollowing(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, following)
+}
+
+type getFeedRequest struct {
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
+	Cursor   string `form:"cursor"`
+}
+
+type feedResponse struct {
+	Articles []articleResponse `json:"articles"`
+	// Empty when there are no more articles
+	NextCursor string `json:"next_cursor"`
+}
+
+// GetFeed godoc
+// @Summary      Get the feed
+// @Description  Get articles of followed authors, newest first. Pass next_cursor of the previous page to get the next one
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   page_size  query    int32   true  "Feed PageSize query param"
+// @Param   cursor  query    string   false  "Cursor returned with the previous page"
+// @Success      200  {object}  api.feedResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /feed [get]
+func (server *Server) getFeed(ctx *gin.Context) {
+	var req getFeedRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// One more article than asked for is fetched to know if there is a next page
+	arg := db.ListFeedArticlesParams{
+		Follower: authPayload.Username,
+		Limit:    req.PageSize + 1,
+	}
+	if req.Cursor != "" {
+		cursor, err := decodeCursor(req.Cursor)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.CursorCreatedAt.Time, arg.CursorCreatedAt.Valid = cursor.CreatedAt, true
+		arg.CursorID.Int64, arg.CursorID.Valid = cursor.ID, true
+	}
+
+	articles, err := server.store.ListFeedArticles(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var resp feedResponse
+	if len(articles) > int(req.PageSize) {
+		articles = articles[:req.PageSize]
+		last := articles[len(articles)-1]
+		resp.NextCursor = encodeCursor(keysetCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	resp.Articles, err = server.newArticleResponses(ctx, articles)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,356 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/session"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFollowAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	author, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Follow",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/authors/%s/follow", author.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateFollowParams{
+					Follower: user.Username,
+					Followee: author.Username,
+				}
+				store.EXPECT().
+					CreateFollow(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "FollowSelf",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/authors/%s/follow", user.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFollow(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errFollowSelf)
+			},
+		},
+		{
+			name:   "FollowNotFound",
+			method: http.MethodPost,
+			url:    "/authors/notfound/follow",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateFollow(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&pq.Error{Code: "23503"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errAuthorNotFound)
+			},
+		},
+		{
+			name:   "Unfollow",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/authors/%s/follow", author.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.DeleteFollowParams{
+					Follower: user.Username,
+					Followee: author.Username,
+				}
+				store.EXPECT().
+					DeleteFollow(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UnfollowInternalError",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/authors/%s/follow", author.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteFollow(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, tc.method, tc.url)
+			recorder := httptest.NewRecorder()
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListFollowsAPI(t *testing.T) {
+	author, _ := randomUser(t)
+	follower, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Followers",
+			url:  fmt.Sprintf("/authors/%s/followers?page_id=2&page_size=5", author.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListFollowersParams{
+					Followee: author.Username,
+					Limit:    5,
+					Offset:   5,
+				}
+				store.EXPECT().
+					ListFollowers(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ListFollowersRow{{Username: follower.Username, FullName: follower.FullName}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []db.ListFollowersRow
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got, 1)
+				require.Equal(t, follower.Username, got[0].Username)
+			},
+		},
+		{
+			name: "Following",
+			url:  fmt.Sprintf("/authors/%s/following?page_id=1&page_size=5", follower.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListFollowingParams{
+					Follower: follower.Username,
+					Limit:    5,
+					Offset:   0,
+				}
+				store.EXPECT().
+					ListFollowing(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ListFollowingRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPageSize",
+			url:  fmt.Sprintf("/authors/%s/followers?page_id=1&page_size=50", author.Username),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListFollowers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// No authorization needed
+			server := newTestServer(t, store, nil, nil)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestFeedAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	author, _ := randomUser(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	articles := make([]db.Article, 6)
+	for i := range articles {
+		articles[i] = randomArticle(author.Username)
+		articles[i].ID = int64(len(articles) - i)
+		articles[i].CreatedAt = now.Add(-time.Duration(i) * time.Minute)
+	}
+	cursor := keysetCursor{CreatedAt: articles[4].CreatedAt, ID: articles[4].ID}
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "FirstPage",
+			url:  "/feed?page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListFeedArticlesParams{
+					Follower: user.Username,
+					Limit:    6,
+				}
+				store.EXPECT().
+					ListFeedArticles(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(articles, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				resp := requireBodyFeed(t, recorder)
+				require.Len(t, resp.Articles, 5)
+				require.Equal(t, encodeCursor(cursor), resp.NextCursor)
+			},
+		},
+		{
+			name: "LastPage",
+			url:  "/feed?page_size=5&cursor=" + encodeCursor(cursor),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListFeedArticlesParams{
+					Follower:        user.Username,
+					CursorCreatedAt: sql.NullTime{Time: cursor.CreatedAt, Valid: true},
+					CursorID:        sql.NullInt64{Int64: cursor.ID, Valid: true},
+					Limit:           6,
+				}
+				store.EXPECT().
+					ListFeedArticles(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(articles[5:], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				resp := requireBodyFeed(t, recorder)
+				require.Len(t, resp.Articles, 1)
+				require.Empty(t, resp.NextCursor)
+			},
+		},
+		{
+			name: "InvalidCursor",
+			url:  "/feed?page_size=5&cursor=invalid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListFeedArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvalidCursor)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			stubArticleDetails(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, http.MethodGet, tc.url)
+			recorder := httptest.NewRecorder()
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+// newAuthorizedTestRequest creates a test server together with a bodyless request
+// authorized as the user, with the session lookup and the account check already stubbed
+func newAuthorizedTestRequest(
+	t *testing.T,
+	ctrl *gomock.Controller,
+	store *mockdb.MockStore,
+	username string,
+	method string,
+	url string,
+) (*Server, *http.Request) {
+	sessionID, err := uuid.NewRandom()
+	require.NoError(t, err)
+
+	sessionClient := mockSession.NewMockSessionClient(ctrl)
+	sessionClient.EXPECT().
+		Get(gomock.Any(), gomock.Eq(sessionID.String())).
+		Times(1).
+		Return(&session.Session{ID: sessionID.String(), Username: username}, nil)
+	expectAccountAccess(store, username, util.RoleUser)
+
+	server := newTestServer(t, store, sessionClient, nil)
+
+	request, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, sessionID, authorizationTypeBearer, username, time.Minute)
+	return server, request
+}
+
+func requireBodyFeed(t *testing.T, recorder *httptest.ResponseRecorder) feedResponse {
+	var resp feedResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	return resp
+}
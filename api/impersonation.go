@@ -0,0 +1,177 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/session"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+)
+
+const defaultImpersonateDuration = 30 * time.Minute
+
+var (
+	errImpersonateSelf  = errors.New("admins can't impersonate themselves")
+	errImpersonateAdmin = errors.New("other admins can't be impersonated")
+)
+
+type impersonateUserResponse struct {
+	SessionID            uuid.UUID    `json:"session_id"`
+	AccessToken          string       `json:"access_token"`
+	AccessTokenExpiresAt time.Time    `json:"access_token_expires_at"`
+	User                 userResponse `json:"user"`
+	ImpersonatedBy       string       `json:"impersonated_by"`
+}
+
+// ImpersonateUser godoc
+// @Summary      Impersonate a user
+// @Description  Create a short session acting as the user. There is no refresh token, destructive actions are refused and every request is written to the impersonation log. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   username   path    string   true  "Username path param"
+// @Success      201  {object}  api.impersonateUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/users/{username}/impersonate [post]
+func (server *Server) impersonateUser(ctx *gin.Context) {
+	var req adminUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if req.Username == authPayload.Username {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errImpersonateSelf))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.Role == util.RoleAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(errImpersonateAdmin))
+		return
+	}
+
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateImpersonationToken(
+		sessionID,
+		user.Username,
+		authPayload.Username,
+		server.config.ImpersonateDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Start of the impersonation is logged before the session exists, so that no session goes unrecorded
+	arg := db.CreateImpersonationLogEntryParams{
+		SessionID:    sessionID,
+		Impersonator: authPayload.Username,
+		Username:     user.Username,
+		Method:       ctx.Request.Method,
+		Path:         ctx.Request.URL.RequestURI(),
+		Status:       http.StatusCreated,
+		ClientIp:     ctx.ClientIP(),
+	}
+	err = server.store.CreateImpersonationLogEntry(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	session := &session.Session{
+		ID:           sessionID.String(),
+		Username:     user.Username,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    accessPayload.ExpiredAt,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIP:     ctx.ClientIP(),
+		Impersonator: authPayload.Username,
+	}
+	err = server.sessionClient.Set(ctx, session.ID, session)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := impersonateUserResponse{
+		SessionID:            sessionID,
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+		User:                 server.newUserResponse(user),
+		ImpersonatedBy:       authPayload.Username,
+	}
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+type listImpersonationLogRequest struct {
+	Impersonator string `form:"impersonator" binding:"omitempty,alphanum"`
+	Username     string `form:"username" binding:"omitempty,alphanum"`
+	PageID       int32  `form:"page_id" binding:"required,min=1"`
+	PageSize     int32  `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// ListImpersonationLog godoc
+// @Summary      Get the impersonation log
+// @Description  Get requests made while impersonating users, newest first. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   impersonator   query    string   false  "Admin username filter"
+// @Param   username   query    string   false  "Impersonated username filter"
+// @Param   page_id   query    int32   true  "Log PageID query param"
+// @Param   page_size  query    int32   true  "Log PageSize query param"
+// @Success      200  {object}  []db.ImpersonationLog
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/impersonation_log [get]
+func (server *Server) listImpersonationLog(ctx *gin.Context) {
+	var req listImpersonationLogRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListImpersonationLogParams{
+		Impersonator: sql.NullString{String: req.Impersonator, Valid: req.Impersonator != ""},
+		Username:     sql.NullString{String: req.Username, Valid: req.Username != ""},
+		Limit:        req.PageSize,
+		Offset:       (req.PageID - 1) * req.PageSize,
+	}
+
+	entries, err := server.store.ListImpersonationLog(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,469 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/session"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonateUserAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.RoleAdmin
+	otherAdmin, _ := randomUser(t)
+	otherAdmin.Role = util.RoleAdmin
+	user, _ := randomUser(t)
+	user.Role = util.RoleUser
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/users/%s/impersonate", user.Username),
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateImpersonationLogEntry(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateImpersonationLogEntryParams) error {
+						require.Equal(t, admin.Username, arg.Impersonator)
+						require.Equal(t, user.Username, arg.Username)
+						return nil
+					})
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, _ string, session *session.Session) error {
+						require.Equal(t, user.Username, session.Username)
+						require.Equal(t, admin.Username, session.Impersonator)
+						require.Empty(t, session.RefreshToken)
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var resp impersonateUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.AccessToken)
+				require.Equal(t, user.Username, resp.User.Username)
+				require.Equal(t, admin.Username, resp.ImpersonatedBy)
+			},
+		},
+		{
+			name:   "Self",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/users/%s/impersonate", admin.Username),
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errImpersonateSelf)
+			},
+		},
+		{
+			name:   "OtherAdmin",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/users/%s/impersonate", otherAdmin.Username),
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(otherAdmin.Username)).
+					Times(1).
+					Return(otherAdmin, nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errImpersonateAdmin)
+			},
+		},
+		{
+			name:   "NotFound",
+			method: http.MethodPost,
+			url:    "/admin/users/notfound/impersonate",
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq("notfound")).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "LogFailure",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/admin/users/%s/impersonate", user.Username),
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateImpersonationLogEntry(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+				// No session without a log entry
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:   "ListLog",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/admin/impersonation_log?username=%s&page_id=1&page_size=5", user.Username),
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				arg := db.ListImpersonationLogParams{
+					Username: sql.NullString{String: user.Username, Valid: true},
+					Limit:    5,
+					Offset:   0,
+				}
+				store.EXPECT().
+					ListImpersonationLog(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ImpersonationLog{{ID: 1, Impersonator: admin.Username, Username: user.Username}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []db.ImpersonationLog
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got, 1)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			sessionID, err := uuid.NewRandom()
+			require.NoError(t, err)
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAccountAccess(store, admin.Username, util.RoleAdmin)
+
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			sessionClient.EXPECT().
+				Get(gomock.Any(), gomock.Eq(sessionID.String())).
+				Times(1).
+				Return(&session.Session{ID: sessionID.String(), Username: admin.Username}, nil)
+			tc.buildStubs(store, sessionClient)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, sessionID, authorizationTypeBearer, admin.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestImpersonatedRequest(t *testing.T) {
+	admin, _ := randomUser(t)
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		impersonator  string
+		accounts      []db.ListUserAccessRow
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:         "Allowed",
+			method:       http.MethodGet,
+			url:          "/feed?page_size=5",
+			impersonator: admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListFeedArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Article{}, nil)
+				arg := db.CreateImpersonationLogEntryParams{
+					Impersonator: admin.Username,
+					Username:     user.Username,
+					Method:       http.MethodGet,
+					Path:         "/feed?page_size=5",
+					Status:       http.StatusOK,
+				}
+				store.EXPECT().
+					CreateImpersonationLogEntry(gomock.Any(), eqLogEntry(arg)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, admin.Username, recorder.Header().Get(impersonatedByHeaderKey))
+			},
+		},
+		{
+			name:         "Restricted",
+			method:       http.MethodPost,
+			url:          "/users/me/password",
+			impersonator: admin.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				// Refused requests are logged too
+				arg := db.CreateImpersonationLogEntryParams{
+					Impersonator: admin.Username,
+					Username:     user.Username,
+					Method:       http.MethodPost,
+					Path:         "/users/me/password",
+					Status:       http.StatusForbidden,
+				}
+				store.EXPECT().
+					CreateImpersonationLogEntry(gomock.Any(), eqLogEntry(arg)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errImpersonationRestricted)
+			},
+		},
+		{
+			name:         "ImpersonatorDemoted",
+			method:       http.MethodGet,
+			url:          "/feed?page_size=5",
+			impersonator: admin.Username,
+			accounts: []db.ListUserAccessRow{
+				{Username: user.Username, Role: util.RoleUser},
+				{Username: admin.Username, Role: util.RoleUser},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListFeedArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:         "ImpersonatorDisabled",
+			method:       http.MethodGet,
+			url:          "/feed?page_size=5",
+			impersonator: admin.Username,
+			accounts: []db.ListUserAccessRow{
+				{Username: user.Username, Role: util.RoleUser},
+				{Username: admin.Username, Role: util.RoleAdmin, DisabledAt: sql.NullTime{Time: time.Now(), Valid: true}},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListFeedArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:         "NotImpersonated",
+			method:       http.MethodGet,
+			url:          "/feed?page_size=5",
+			impersonator: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListFeedArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Article{}, nil)
+				store.EXPECT().
+					CreateImpersonationLogEntry(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get(impersonatedByHeaderKey))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			sessionID, err := uuid.NewRandom()
+			require.NoError(t, err)
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// The impersonator is still an admin unless the case says otherwise
+			usernames := []string{user.Username}
+			accounts := []db.ListUserAccessRow{{Username: user.Username, Role: util.RoleUser}}
+			if tc.impersonator != "" {
+				usernames = append(usernames, tc.impersonator)
+				accounts = append(accounts, db.ListUserAccessRow{Username: tc.impersonator, Role: util.RoleAdmin})
+			}
+			if tc.accounts != nil {
+				accounts = tc.accounts
+			}
+			store.EXPECT().
+				ListUserAccess(gomock.Any(), gomock.Eq(usernames)).
+				Times(1).
+				Return(accounts, nil)
+
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			sessionClient.EXPECT().
+				Get(gomock.Any(), gomock.Eq(sessionID.String())).
+				Times(1).
+				Return(&session.Session{ID: sessionID.String(), Username: user.Username, Impersonator: tc.impersonator}, nil)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader([]byte("{}")))
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateImpersonationToken(sessionID, user.Username, tc.impersonator, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestImpersonationRestrictedRoutes(t *testing.T) {
+	admin, _ := randomUser(t)
+	user, _ := randomUser(t)
+
+	routes := []struct {
+		method string
+		url    string
+	}{
+		{http.MethodPut, "/users/me/avatar"},
+		{http.MethodDelete, "/articles/1"},
+		{http.MethodPost, "/articles/1/unpublish"},
+		{http.MethodPost, "/articles/1/archive"},
+		{http.MethodPost, "/articles/1/revisions/1/restore"},
+		{http.MethodDelete, "/comments/1"},
+	}
+
+	for i := range routes {
+		route := routes[i]
+
+		t.Run(route.method+" "+route.url, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			sessionID, err := uuid.NewRandom()
+			require.NoError(t, err)
+
+			// Nothing but the audit log is touched
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ListUserAccess(gomock.Any(), gomock.Eq([]string{user.Username, admin.Username})).
+				Times(1).
+				Return([]db.ListUserAccessRow{
+					{Username: user.Username, Role: util.RoleUser},
+					{Username: admin.Username, Role: util.RoleAdmin},
+				}, nil)
+			arg := db.CreateImpersonationLogEntryParams{
+				Impersonator: admin.Username,
+				Username:     user.Username,
+				Method:       route.method,
+				Path:         route.url,
+				Status:       http.StatusForbidden,
+			}
+			store.EXPECT().
+				CreateImpersonationLogEntry(gomock.Any(), eqLogEntry(arg)).
+				Times(1).
+				Return(nil)
+
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			sessionClient.EXPECT().
+				Get(gomock.Any(), gomock.Eq(sessionID.String())).
+				Times(1).
+				Return(&session.Session{ID: sessionID.String(), Username: user.Username, Impersonator: admin.Username}, nil)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(route.method, route.url, bytes.NewReader([]byte("{}")))
+			require.NoError(t, err)
+
+			accessToken, _, err := server.tokenMaker.CreateImpersonationToken(sessionID, user.Username, admin.Username, time.Minute)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusForbidden, recorder.Code)
+			requireBodyMatchError(t, recorder.Body, errImpersonationRestricted)
+		})
+	}
+}
+
+type eqLogEntryMatcher struct {
+	arg db.CreateImpersonationLogEntryParams
+}
+
+// Session ID and client IP are ignored
+func (e eqLogEntryMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateImpersonationLogEntryParams)
+	if !ok {
+		return false
+	}
+
+	arg.SessionID = e.arg.SessionID
+	arg.ClientIp = e.arg.ClientIp
+	return arg == e.arg
+}
+
+func (e eqLogEntryMatcher) String() string {
+	return fmt.Sprintf("matches log entry %v", e.arg)
+}
+
+func eqLogEntry(arg db.CreateImpersonationLogEntryParams) gomock.Matcher {
+	return eqLogEntryMatcher{arg}
+}
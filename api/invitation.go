@@ -0,0 +1,190 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"gopkg.in/guregu/null.v3"
+)
+
+type invitationResponse struct {
+	ID        int64       `json:"id"`
+	Email     null.String `json:"email" swaggertype:"string"`
+	CreatedBy string      `json:"created_by"`
+	UsedBy    null.String `json:"used_by" swaggertype:"string"`
+	UsedAt    null.Time   `json:"used_at" swaggertype:"string"`
+	RevokedAt null.Time   `json:"revoked_at" swaggertype:"string"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// func to cover the hashed code, which shouldn't be leaked in response
+func newInvitationResponse(invitation db.Invitation) invitationResponse {
+	return invitationResponse{
+		ID:        invitation.ID,
+		Email:     null.String{NullString: invitation.Email},
+		CreatedBy: invitation.CreatedBy,
+		UsedBy:    null.String{NullString: invitation.UsedBy},
+		UsedAt:    null.NewTime(invitation.UsedAt.Time, invitation.UsedAt.Valid),
+		RevokedAt: null.NewTime(invitation.RevokedAt.Time, invitation.RevokedAt.Valid),
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
+
+type createInvitationRequest struct {
+	// Optional. When set, only a user with this email can register with the invitation
+	Email string `json:"email" binding:"omitempty,email"`
+	// Optional. Defaults to the configured invitation duration
+	ValidForHours int32 `json:"valid_for_hours" binding:"omitempty,min=1,max=8760"`
+}
+
+type createInvitationResponse struct {
+	// Code is returned only once, only its hash is stored
+	Code       string             `json:"code"`
+	Invitation invitationResponse `json:"invitation"`
+}
+
+// CreateInvitation godoc
+// @Summary      Create an invitation
+// @Description  Create a new invitation code. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.createInvitationRequest    true  "Invitation payload"
+// @Success      201  {object}  api.createInvitationResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/invitations [post]
+func (server *Server) createInvitation(ctx *gin.Context) {
+	var req createInvitationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	duration := server.config.InvitationDuration
+	if req.ValidForHours > 0 {
+		duration = time.Duration(req.ValidForHours) * time.Hour
+	}
+
+	code, err := util.GenerateInvitationCode()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.CreateInvitationParams{
+		HashedCode: util.HashInvitationCode(code),
+		Email:      sql.NullString{String: req.Email, Valid: req.Email != ""},
+		CreatedBy:  authPayload.Username,
+		ExpiresAt:  time.Now().Add(duration),
+	}
+
+	invitation, err := server.store.CreateInvitation(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := createInvitationResponse{
+		Code:       code,
+		Invitation: newInvitationResponse(invitation),
+	}
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+type listInvitationsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// ListInvitations godoc
+// @Summary      Get the list of invitations
+// @Description  Get the list of invitations, newest first. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   page_id   query    int32   true  "Invitation PageID query param"
+// @Param   page_size  query    int32   true  "Invitation PageSize query param"
+// @Success      200  {object}  []api.invitationResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/invitations [get]
+func (server *Server) listInvitations(ctx *gin.Context) {
+	var req listInvitationsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListInvitationsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	invitations, err := server.store.ListInvitations(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]invitationResponse, len(invitations))
+	for i, invitation := range invitations {
+		resp[i] = newInvitationResponse(invitation)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type revokeInvitationRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// RevokeInvitation godoc
+// @Summary      Revoke an invitation
+// @Description  Revoke an invitation that hasn't been used yet. Admin only
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Invitation ID path param"
+// @Success      200  {object}  api.invitationResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /admin/invitations/{id} [delete]
+func (server *Server) revokeInvitation(ctx *gin.Context) {
+	var req revokeInvitationRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Used or already revoked invitations are reported as not found
+	invitation, err := server.store.RevokeInvitation(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newInvitationResponse(invitation))
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/session"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationAPI(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = util.RoleAdmin
+	user, _ := randomUser(t)
+	user.Role = util.RoleUser
+	invitation := randomInvitation(admin.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		caller        db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Created",
+			method: http.MethodPost,
+			url:    "/admin/invitations",
+			body:   gin.H{"email": "invited@example.com", "valid_for_hours": 24},
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateInvitation(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateInvitationParams) (db.Invitation, error) {
+						require.Equal(t, admin.Username, arg.CreatedBy)
+						require.Equal(t, sql.NullString{String: "invited@example.com", Valid: true}, arg.Email)
+						require.WithinDuration(t, time.Now().Add(24*time.Hour), arg.ExpiresAt, time.Second)
+						require.NotEmpty(t, arg.HashedCode)
+						return invitation, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var resp createInvitationResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.Code)
+				require.Equal(t, invitation.ID, resp.Invitation.ID)
+				require.NotContains(t, recorder.Body.String(), "hashed_code")
+			},
+		},
+		{
+			name:   "InvalidEmail",
+			method: http.MethodPost,
+			url:    "/admin/invitations",
+			body:   gin.H{"email": "invalid"},
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateInvitation(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "NotAdmin",
+			method: http.MethodPost,
+			url:    "/admin/invitations",
+			body:   gin.H{},
+			caller: user,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateInvitation(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "OKList",
+			method: http.MethodGet,
+			url:    "/admin/invitations?page_id=1&page_size=5",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListInvitationsParams{
+					Limit:  5,
+					Offset: 0,
+				}
+				store.EXPECT().
+					ListInvitations(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Invitation{invitation}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "Revoked",
+			method: http.MethodDelete,
+			url:    "/admin/invitations/1",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := invitation
+				revoked.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					RevokeInvitation(gomock.Any(), gomock.Eq(int64(1))).
+					Times(1).
+					Return(revoked, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "RevokeNotFound",
+			method: http.MethodDelete,
+			url:    "/admin/invitations/1",
+			caller: admin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RevokeInvitation(gomock.Any(), gomock.Eq(int64(1))).
+					Times(1).
+					Return(db.Invitation{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			sessionID, err := uuid.NewRandom()
+			require.NoError(t, err)
+
+			store := mockdb.NewMockStore(ctrl)
+			expectAccountAccess(store, tc.caller.Username, tc.caller.Role)
+			tc.buildStubs(store)
+
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			sessionClient.EXPECT().
+				Get(gomock.Any(), gomock.Eq(sessionID.String())).
+				Times(1).
+				Return(&session.Session{ID: sessionID.String(), Username: tc.caller.Username}, nil)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			var body []byte
+			if tc.body != nil {
+				body, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, sessionID, authorizationTypeBearer, tc.caller.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomInvitation(createdBy string) db.Invitation {
+	return db.Invitation{
+		ID:         util.RandomInt(1, 1000),
+		HashedCode: util.HashInvitationCode(util.RandomString(24)),
+		CreatedBy:  createdBy,
+		ExpiresAt:  time.Now().Add(time.Hour),
+		CreatedAt:  time.Now(),
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kamilwrzyszcz/go_example/limiter"
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid username or password")
+	errInvalidMFACode     = errors.New("invalid verification code")
+	errTooManyAttempts    = errors.New("too many failed attempts, try again later")
+)
+
+func loginUserKey(username string) string {
+	return "login:user:" + username
+}
+
+func loginIPKey(clientIP string) string {
+	return "login:ip:" + clientIP
+}
+
+func mfaUserKey(username string) string {
+	return "mfa:user:" + username
+}
+
+func magicLinkKey(login string) string {
+	return "magic:login:" + login
+}
+
+func (server *Server) loginUserPolicy() limiter.Policy {
+	return limiter.Policy{
+		MaxAttempts: server.config.LoginMaxAttempts,
+		BaseDelay:   server.config.LoginLockoutDelay,
+		MaxDelay:    server.config.LoginLockoutMaxDelay,
+		Window:      server.config.LoginAttemptWindow,
+	}
+}
+
+func (server *Server) loginIPPolicy() limiter.Policy {
+	policy := server.loginUserPolicy()
+	policy.MaxAttempts = server.config.LoginIPMaxAttempts
+	return policy
+}
+
+// checkLoginThrottle aborts with 429 and Retry-After header if any of the keys is locked.
+// Returns false if the request shouldn't be processed any further.
+func (server *Server) checkLoginThrottle(ctx *gin.Context, keys ...string) bool {
+	wait, err := server.loginLimiter.Check(ctx, keys...)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if wait > 0 {
+		setRetryAfter(ctx, wait)
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errTooManyAttempts))
+		return false
+	}
+
+	return true
+}
+
+// rejectLogin registers a failed login attempt for both the username and the client IP
+// and responds with the same error no matter if the user exists or the password was wrong
+func (server *Server) rejectLogin(ctx *gin.Context, username string) {
+	userDelay, err := server.loginLimiter.Fail(ctx, loginUserKey(username), server.loginUserPolicy())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ipDelay, err := server.loginLimiter.Fail(ctx, loginIPKey(ctx.ClientIP()), server.loginIPPolicy())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if delay := maxDuration(userDelay, ipDelay); delay > 0 {
+		setRetryAfter(ctx, delay)
+	}
+	ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+}
+
+// rejectMFACode registers a failed second factor attempt and responds with the given status
+func (server *Server) rejectMFACode(ctx *gin.Context, username string, status int) {
+	delay, err := server.loginLimiter.Fail(ctx, mfaUserKey(username), server.loginUserPolicy())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if delay > 0 {
+		setRetryAfter(ctx, delay)
+	}
+	ctx.JSON(status, errorResponse(errInvalidMFACode))
+}
+
+// Retry-After header takes whole seconds
+func setRetryAfter(ctx *gin.Context, wait time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
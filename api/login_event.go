@@ -0,0 +1,122 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/mail"
+	"github.com/kamilwrzyszcz/go_example/token"
+)
+
+// recordLoginEvent stores a login attempt in the login history. Username is empty when no user matched the login.
+// Failure is only logged, it shouldn't lock users out
+func (server *Server) recordLoginEvent(ctx *gin.Context, login, username, outcome string) {
+	arg := db.CreateLoginEventParams{
+		Login:     login,
+		Username:  sql.NullString{String: username, Valid: username != ""},
+		Outcome:   outcome,
+		Ip:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+	if err := server.store.CreateLoginEvent(ctx, arg); err != nil {
+		log.Printf("cannot record login event for %s: %v", login, err)
+	}
+}
+
+// notifyNewDevice emails the user when the password was correct from an IP and user agent combination
+// that never logged in before. The very first login of an account isn't reported
+func (server *Server) notifyNewDevice(ctx *gin.Context, user db.User) {
+	history, err := server.store.GetLoginDeviceHistory(ctx, db.GetLoginDeviceHistoryParams{
+		Username:  user.Username,
+		Ip:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	})
+	if err != nil {
+		log.Printf("cannot check login history of %s: %v", user.Username, err)
+		return
+	}
+	if !history.HasLogins || history.KnownDevice {
+		return
+	}
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "New login to your account",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nyour account was just logged into from a new device.\n\nTime: %s\nIP address: %s\nBrowser: %s\n\n"+
+				"If it wasn't you, change your password right away.\n",
+			user.FullName, time.Now().UTC().Format(time.RFC1123), ctx.ClientIP(), ctx.Request.UserAgent(),
+		),
+	}
+	server.sendMail(msg, "new device notification to "+user.Username)
+}
+
+type loginEventResponse struct {
+	ID        int64     `json:"id"`
+	Outcome   string    `json:"outcome"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newLoginEventResponse(event db.LoginEvent) loginEventResponse {
+	return loginEventResponse{
+		ID:        event.ID,
+		Outcome:   event.Outcome,
+		IP:        event.Ip,
+		UserAgent: event.UserAgent,
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+type listLoginEventsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// ListLoginEvents godoc
+// @Summary      Get login history
+// @Description  Get the login attempts on the account of the logged in user, most recent first. Outcome is one of success, mfa_required, failed or blocked
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   page_id   query    int32   true  "Login history PageID query param"
+// @Param   page_size  query    int32   true  "Login history PageSize query param"
+// @Success      200  {object}  []api.loginEventResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /users/me/logins [get]
+func (server *Server) listLoginEvents(ctx *gin.Context) {
+	var req listLoginEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	arg := db.ListLoginEventsParams{
+		Username: authPayload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	}
+
+	events, err := server.store.ListLoginEvents(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]loginEventResponse, len(events))
+	for i, event := range events {
+		resp[i] = newLoginEventResponse(event)
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	mockLimiter "github.com/kamilwrzyszcz/go_example/limiter/mock"
+	"github.com/kamilwrzyszcz/go_example/mail"
+	mockMail "github.com/kamilwrzyszcz/go_example/mail/mock"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginNewDeviceNotification(t *testing.T) {
+	user, password := randomUser(t)
+	userAgent := "Mozilla/5.0 (X11; Linux x86_64)"
+
+	testCases := []struct {
+		name      string
+		history   db.GetLoginDeviceHistoryRow
+		buildMail func(mailer *mockMail.MockMailer)
+	}{
+		{
+			name:    "NewDevice",
+			history: db.GetLoginDeviceHistoryRow{HasLogins: true, KnownDevice: false},
+			buildMail: func(mailer *mockMail.MockMailer) {
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, msg mail.Message) error {
+						require.Equal(t, user.Email, msg.To)
+						require.Contains(t, msg.Body, userAgent)
+						return nil
+					})
+			},
+		},
+		{
+			name:    "KnownDevice",
+			history: db.GetLoginDeviceHistoryRow{HasLogins: true, KnownDevice: true},
+			buildMail: func(mailer *mockMail.MockMailer) {
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+		},
+		{
+			name:    "FirstLogin",
+			history: db.GetLoginDeviceHistoryRow{HasLogins: false, KnownDevice: false},
+			buildMail: func(mailer *mockMail.MockMailer) {
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			loginLimiter := mockLimiter.NewMockLimiter(ctrl)
+			mailer := mockMail.NewMockMailer(ctrl)
+
+			loginLimiter.EXPECT().
+				Check(gomock.Any(), gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(time.Duration(0), nil)
+			store.EXPECT().
+				GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+				Times(1).
+				Return(user, nil)
+			loginLimiter.EXPECT().
+				Reset(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(nil)
+			store.EXPECT().
+				GetLoginDeviceHistory(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ interface{}, arg db.GetLoginDeviceHistoryParams) (db.GetLoginDeviceHistoryRow, error) {
+					require.Equal(t, user.Username, arg.Username)
+					require.Equal(t, userAgent, arg.UserAgent)
+					return tc.history, nil
+				})
+			sessionClient.EXPECT().
+				Set(gomock.Any(), gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(nil)
+			store.EXPECT().
+				CreateLoginEvent(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(nil)
+			tc.buildMail(mailer)
+
+			server := newTestServer(t, store, sessionClient, loginLimiter)
+			server.mailer = mailer
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"login": user.Username, "password": password})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.Header.Set("User-Agent", userAgent)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			server.mailing.Wait()
+		})
+	}
+}
+
+func TestListLoginEventsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 5
+	events := make([]db.LoginEvent, n)
+	for i := range events {
+		events[i] = db.LoginEvent{
+			ID:        int64(n - i),
+			Login:     user.Username,
+			Username:  sql.NullString{String: user.Username, Valid: true},
+			Outcome:   util.LoginSucceeded,
+			Ip:        "192.0.2.1",
+			UserAgent: "curl/8.0",
+			CreatedAt: time.Now(),
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListLoginEventsParams{
+					Username: user.Username,
+					Limit:    5,
+					Offset:   5,
+				}
+				store.EXPECT().
+					ListLoginEvents(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(events, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []loginEventResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, n)
+				for i, event := range resp {
+					require.Equal(t, events[i].ID, event.ID)
+					require.Equal(t, events[i].Outcome, event.Outcome)
+					require.Equal(t, events[i].Ip, event.IP)
+					require.Equal(t, events[i].UserAgent, event.UserAgent)
+				}
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "?page_id=1&page_size=100",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListLoginEvents(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListLoginEvents(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.LoginEvent{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, http.MethodGet, "/users/me/logins"+tc.query)
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/mail"
+	"github.com/kamilwrzyszcz/go_example/token"
+)
+
+var errMagicLinkUsed = errors.New("login link has already been used")
+
+type sendMagicLinkRequest struct {
+	// Username or email, both are case insensitive
+	Login string `json:"login" binding:"required,max=254"`
+}
+
+// SendMagicLink godoc
+// @Summary      Send login link
+// @Description  Email a single-use login link to the user. The response is the same whether the user exists or not
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.sendMagicLinkRequest    true  "Magic link payload"
+// @Success      202  {object} object{}
+// @Failure      400  {object} object{error=string}
+// @Failure      429  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /users/login/magic [post]
+func (server *Server) sendMagicLink(ctx *gin.Context) {
+	var req sendMagicLinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	login := strings.ToLower(strings.TrimSpace(req.Login))
+	if !server.checkLoginThrottle(ctx, magicLinkKey(login), loginIPKey(ctx.ClientIP())) {
+		return
+	}
+
+	// Every request counts, so that the endpoint can't be used to flood someone's inbox
+	// or to send links to many users from one address
+	_, err := server.loginLimiter.Fail(ctx, magicLinkKey(login), server.loginUserPolicy())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	_, err = server.loginLimiter.Fail(ctx, loginIPKey(ctx.ClientIP()), server.loginIPPolicy())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByLogin(ctx, login)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusAccepted, gin.H{})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	linkToken, _, err := server.tokenMaker.CreatePurposeToken(
+		token.PurposeMagicLink,
+		user.Username,
+		server.config.MagicLinkDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The configured URL may already have a query of its own
+	link, err := url.Parse(server.config.MagicLinkURL)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	query := link.Query()
+	query.Set("token", linkToken)
+	link.RawQuery = query.Encode()
+
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nuse the link below to log in. It works once and expires in %s.\n\n%s\n\n"+
+				"If you didn't ask for it, you can ignore this email.\n",
+			user.FullName, server.config.MagicLinkDuration, link,
+		),
+	}
+
+	// Sent in the background, waiting for the mail server would reveal that the user exists
+	server.sendMail(msg, "login link to "+user.Username)
+
+	ctx.JSON(http.StatusAccepted, gin.H{})
+}
+
+type consumeMagicLinkRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ConsumeMagicLink godoc
+// @Summary      Login with login link
+// @Description  Exchange the token from a login link for a session. Each link works only once
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.consumeMagicLinkRequest    true  "Magic link token payload"
+// @Success      200  {object}  api.loginUserResponse
+// @Success      202  {object}  api.mfaChallengeResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /users/login/magic/consume [post]
+func (server *Server) consumeMagicLink(ctx *gin.Context) {
+	var req consumeMagicLinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.Token)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if err := payload.CheckPurpose(token.PurposeMagicLink); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	arg := db.ConsumeTokenParams{
+		ID:        payload.ID,
+		Purpose:   payload.Purpose,
+		ExpiresAt: payload.ExpiredAt,
+	}
+	rows, err := server.store.ConsumeToken(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errMagicLinkUsed))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrInvalidToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !server.checkUserCanLogin(ctx, user) {
+		return
+	}
+
+	// The link replaces the password only, the second factor is still required
+	if user.TotpEnabled {
+		server.startMFAChallenge(ctx, user)
+		return
+	}
+
+	resp, err := server.newUserSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
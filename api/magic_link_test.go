@@ -0,0 +1,420 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	mockLimiter "github.com/kamilwrzyszcz/go_example/limiter/mock"
+	"github.com/kamilwrzyszcz/go_example/mail"
+	mockMail "github.com/kamilwrzyszcz/go_example/mail/mock"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendMagicLinkAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	// Holds the mailer of SlowMailer until the response is checked
+	release := make(chan struct{})
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		linkURL       string
+		buildStubs    func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter, mailer *mockMail.MockMailer)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"login": strings.ToUpper(user.Email)},
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter, mailer *mockMail.MockMailer) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), magicLinkKey(user.Email), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), magicLinkKey(user.Email), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				// Counted per address too, so one client can't send links to many users
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), loginIPKey(""), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, msg mail.Message) error {
+						require.Equal(t, user.Email, msg.To)
+						require.Contains(t, msg.Body, "http://localhost:3000/login/magic?token=")
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name:    "LinkURLWithQuery",
+			body:    gin.H{"login": user.Username},
+			linkURL: "http://localhost:3000/login?method=magic",
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter, mailer *mockMail.MockMailer) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, msg mail.Message) error {
+						require.Contains(t, msg.Body, "http://localhost:3000/login?method=magic&token=")
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{"login": "notfound"},
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter, mailer *mockMail.MockMailer) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				// Same response as for an existing user
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "Throttled",
+			body: gin.H{"login": user.Username},
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter, mailer *mockMail.MockMailer) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Minute, nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Any()).
+					Times(0)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "MailerError",
+			body: gin.H{"login": user.Username},
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter, mailer *mockMail.MockMailer) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "SlowMailer",
+			body: gin.H{"login": user.Username},
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter, mailer *mockMail.MockMailer) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, _ mail.Message) error {
+						<-release
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				// Responds before the email is sent, so timing doesn't tell that the user exists
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				close(release)
+			},
+		},
+		{
+			name: "MissingLogin",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter, mailer *mockMail.MockMailer) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+				mailer.EXPECT().
+					Send(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			loginLimiter := mockLimiter.NewMockLimiter(ctrl)
+			mailer := mockMail.NewMockMailer(ctrl)
+			tc.buildStubs(store, loginLimiter, mailer)
+
+			server := newTestServer(t, store, nil, loginLimiter)
+			server.mailer = mailer
+			if tc.linkURL != "" {
+				server.config.MagicLinkURL = tc.linkURL
+			}
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/magic", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+			server.mailing.Wait()
+		})
+	}
+}
+
+func TestConsumeMagicLinkAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	mfaUser, _ := randomUser(t)
+	mfaUser.TotpEnabled = true
+
+	linkToken := func(t *testing.T, tokenMaker token.Maker, purpose string, username string) string {
+		linkToken, _, err := tokenMaker.CreatePurposeToken(purpose, username, time.Minute)
+		require.NoError(t, err)
+		return linkToken
+	}
+
+	testCases := []struct {
+		name          string
+		token         func(t *testing.T, tokenMaker token.Maker) string
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			token: func(t *testing.T, tokenMaker token.Maker) string {
+				return linkToken(t, tokenMaker, token.PurposeMagicLink, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ConsumeTokenParams) (int64, error) {
+						require.Equal(t, token.PurposeMagicLink, arg.Purpose)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						return 1, nil
+					})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.NotEmpty(t, resp.AccessToken)
+				require.Equal(t, user.Username, resp.User.Username)
+			},
+		},
+		{
+			name: "AlreadyUsed",
+			token: func(t *testing.T, tokenMaker token.Maker) string {
+				return linkToken(t, tokenMaker, token.PurposeMagicLink, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errMagicLinkUsed)
+			},
+		},
+		{
+			name: "WrongPurpose",
+			token: func(t *testing.T, tokenMaker token.Maker) string {
+				return linkToken(t, tokenMaker, token.PurposeMFAChallenge, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidToken",
+			token: func(t *testing.T, tokenMaker token.Maker) string {
+				return "invalid"
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MFARequired",
+			token: func(t *testing.T, tokenMaker token.Maker) string {
+				return linkToken(t, tokenMaker, token.PurposeMagicLink, mfaUser.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(mfaUser.Username)).
+					Times(1).
+					Return(mfaUser, nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				var resp mfaChallengeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.True(t, resp.MFARequired)
+			},
+		},
+		{
+			name: "UserNotFound",
+			token: func(t *testing.T, tokenMaker token.Maker) string {
+				return linkToken(t, tokenMaker, token.PurposeMagicLink, "deleted")
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			tc.buildStubs(store, sessionClient)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"token": tc.token(t, server.tokenMaker)})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/magic/consume", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
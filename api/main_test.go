@@ -6,25 +6,59 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
 	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/limiter"
 	"github.com/kamilwrzyszcz/go_example/session"
 	"github.com/kamilwrzyszcz/go_example/util"
 	"github.com/stretchr/testify/require"
 )
 
 // Create new test server to be used in api tests. Services are supposed to be mocked
-func newTestServer(t *testing.T, store db.Store, sessionClient session.SessionClient) *Server {
+func newTestServer(
+	t *testing.T,
+	store db.Store,
+	sessionClient session.SessionClient,
+	loginLimiter limiter.Limiter,
+) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:    util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		LoginMaxAttempts:     5,
+		LoginIPMaxAttempts:   20,
+		LoginLockoutDelay:    time.Second,
+		LoginLockoutMaxDelay: time.Minute,
+		LoginAttemptWindow:   time.Hour,
+		MFATokenDuration:     time.Minute,
+		TOTPIssuer:           "GoExample",
+		PasswordMinLength:    6,
+		PasswordMaxLength:    64,
+		MagicLinkDuration:    time.Minute,
+		MagicLinkURL:         "http://localhost:3000/login/magic",
+		DeviceCodeDuration:   time.Minute,
+		DevicePollInterval:   5 * time.Second,
+		DeviceVerifyURL:      "http://localhost:3000/device",
+		ResetTokenDuration:   time.Minute,
+		PasswordResetURL:     "http://localhost:3000/password/reset",
 	}
 
-	server, err := NewServer(config, store, sessionClient)
+	server, err := NewServer(config, store, sessionClient, loginLimiter, nil, nil)
 	require.NoError(t, err)
 
 	return server
 }
 
+// expectAccountAccess expects authMiddleware to check the account of an authorized request once.
+// The account is enabled and has the given role
+func expectAccountAccess(store *mockdb.MockStore, username string, role string) {
+	store.EXPECT().
+		ListUserAccess(gomock.Any(), gomock.Eq([]string{username})).
+		Times(1).
+		Return([]db.ListUserAccessRow{{Username: username, Role: role}}, nil)
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 
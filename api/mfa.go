@@ -0,0 +1,387 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+)
+
+const recoveryCodesCount = 10
+
+type mfaChallengeResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+// startMFAChallenge responds with a short-lived token which has to be exchanged,
+// together with the second factor, for a session at /users/login/mfa
+func (server *Server) startMFAChallenge(ctx *gin.Context, user db.User) {
+	mfaToken, mfaPayload, err := server.tokenMaker.CreatePurposeToken(
+		token.PurposeMFAChallenge,
+		user.Username,
+		server.config.MFATokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := mfaChallengeResponse{
+		MFARequired:       true,
+		MFAToken:          mfaToken,
+		MFATokenExpiresAt: mfaPayload.ExpiredAt,
+	}
+
+	ctx.JSON(http.StatusAccepted, resp)
+}
+
+type loginUserMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// LoginUserMFA godoc
+// @Summary      Finish login with second factor
+// @Description  Exchange MFA challenge token and TOTP or recovery code for a session
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.loginUserMFARequest    true  "MFA login payload"
+// @Success      200  {object}  api.loginUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      429  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /users/login/mfa [post]
+func (server *Server) loginUserMFA(ctx *gin.Context) {
+	var req loginUserMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	mfaPayload, err := server.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if err := mfaPayload.CheckPurpose(token.PurposeMFAChallenge); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !server.checkLoginThrottle(ctx, mfaUserKey(mfaPayload.Username)) {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, mfaPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !user.TotpEnabled {
+		err := errors.New("two-factor authentication is not enabled")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	// The challenge token only carries the username, so it stands for the login in the history
+	if !server.checkUserCanLogin(ctx, user) {
+		server.recordLoginEvent(ctx, user.Username, user.Username, util.LoginBlocked)
+		return
+	}
+
+	ok, err := server.verifySecondFactor(ctx, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		server.recordLoginEvent(ctx, user.Username, user.Username, util.LoginFailed)
+		server.rejectMFACode(ctx, user.Username, http.StatusUnauthorized)
+		return
+	}
+
+	err = server.loginLimiter.Reset(ctx, mfaUserKey(user.Username))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := server.newUserSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.recordLoginEvent(ctx, user.Username, user.Username, util.LoginSucceeded)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// verifySecondFactor checks either the TOTP code or, when it's not given, the one-time recovery code
+func (server *Server) verifySecondFactor(ctx *gin.Context, user db.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := util.ValidateTOTP(user.TotpSecret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		// Code can be used only once, even though it stays valid for the whole time step
+		arg := db.UseUserTOTPStepParams{
+			Username: user.Username,
+			Step:     step,
+		}
+		rows, err := server.store.UseUserTOTPStep(ctx, arg)
+		if err != nil {
+			return false, err
+		}
+		return rows == 1, nil
+	}
+
+	arg := db.UseRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: util.HashRecoveryCode(recoveryCode),
+	}
+	_, err := server.store.UseRecoveryCode(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+type enrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// EnrollTOTP godoc
+// @Summary      Start TOTP enrolment
+// @Description  Generate a new TOTP secret and otpauth URI for the authenticated user
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  api.enrollTOTPResponse
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /users/me/totp [post]
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.TotpEnabled {
+		err := errors.New("two-factor authentication is already enabled")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.SetUserTOTPSecretParams{
+		Username:   user.Username,
+		TotpSecret: secret,
+	}
+	_, err = server.store.SetUserTOTPSecret(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := enrollTOTPResponse{
+		Secret: secret,
+		URI:    util.TOTPURI(server.config.TOTPIssuer, user.Username, secret),
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type confirmTOTPResponse struct {
+	RecoveryCodes []string     `json:"recovery_codes"`
+	User          userResponse `json:"user"`
+}
+
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP enrolment
+// @Description  Enable two-factor authentication with the first code and get one-time recovery codes
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.confirmTOTPRequest    true  "TOTP code payload"
+// @Success      200  {object}  api.confirmTOTPResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      429  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /users/me/totp/confirm [post]
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if !server.checkLoginThrottle(ctx, mfaUserKey(authPayload.Username)) {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if user.TotpEnabled {
+		err := errors.New("two-factor authentication is already enabled")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+	if user.TotpSecret == "" {
+		err := errors.New("two-factor authentication enrolment has not been started")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	ok, err := server.verifySecondFactor(ctx, user, req.Code, "")
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		server.rejectMFACode(ctx, user.Username, http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := util.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.EnableTOTPTxParams{
+		Username:            user.Username,
+		HashedRecoveryCodes: make([]string, len(recoveryCodes)),
+	}
+	for i, code := range recoveryCodes {
+		arg.HashedRecoveryCodes[i] = util.HashRecoveryCode(code)
+	}
+
+	user, err = server.store.EnableTOTPTx(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := confirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+		User:          server.newUserResponse(user),
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type disableTOTPRequest struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// DisableTOTP godoc
+// @Summary      Disable TOTP
+// @Description  Disable two-factor authentication with a TOTP or recovery code
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.disableTOTPRequest    true  "TOTP code payload"
+// @Success      200  {object}  api.userResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      429  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /users/me/totp/disable [post]
+func (server *Server) disableTOTP(ctx *gin.Context) {
+	var req disableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if !server.checkLoginThrottle(ctx, mfaUserKey(authPayload.Username)) {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !user.TotpEnabled {
+		err := errors.New("two-factor authentication is not enabled")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	ok, err := server.verifySecondFactor(ctx, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !ok {
+		server.rejectMFACode(ctx, user.Username, http.StatusBadRequest)
+		return
+	}
+
+	user, err = server.store.DisableTOTPTx(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.newUserResponse(user))
+}
@@ -0,0 +1,527 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	mockLimiter "github.com/kamilwrzyszcz/go_example/limiter/mock"
+	"github.com/kamilwrzyszcz/go_example/session"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginUserMFAAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	user.TotpSecret = secret
+	user.TotpEnabled = true
+
+	code, err := util.TOTPCode(secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	recoveryCode := "abcde-fghij"
+
+	mfaToken := func(t *testing.T, tokenMaker token.Maker) string {
+		mfaToken, _, err := tokenMaker.CreatePurposeToken(token.PurposeMFAChallenge, user.Username, time.Minute)
+		require.NoError(t, err)
+		return mfaToken
+	}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T, tokenMaker token.Maker) gin.H
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker), "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), mfaUserKey(user.Username)).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), mfaUserKey(user.Username)).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginSucceeded, arg.Outcome)
+						require.Equal(t, user.Username, arg.Login)
+						require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OKRecoveryCode",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker), "recovery_code": recoveryCode}
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				arg := db.UseRecoveryCodeParams{
+					Username:   user.Username,
+					HashedCode: util.HashRecoveryCode(recoveryCode),
+				}
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.RecoveryCode{}, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginSucceeded, arg.Outcome)
+						require.Equal(t, user.Username, arg.Login)
+						require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker), "code": "000000x"}
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), mfaUserKey(user.Username), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginFailed, arg.Outcome)
+						require.Equal(t, user.Username, arg.Login)
+						require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvalidMFACode)
+			},
+		},
+		{
+			name: "ReusedCode",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker), "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginFailed, arg.Outcome)
+						require.Equal(t, user.Username, arg.Login)
+						require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionTokenInsteadOfMFAToken",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				sessionToken, _, err := tokenMaker.CreateToken(uuid.New(), user.Username, time.Minute)
+				require.NoError(t, err)
+				return gin.H{"mfa_token": sessionToken, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NoCode",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker)}
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "Disabled",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker), "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				disabledUser := user
+				disabledUser.DisabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(disabledUser, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginBlocked, arg.Outcome)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "MFANotEnabled",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker), "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				disabledUser := user
+				disabledUser.TotpEnabled = false
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(disabledUser, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			loginLimiter := mockLimiter.NewMockLimiter(ctrl)
+			tc.buildStubs(store, sessionClient, loginLimiter)
+
+			server := newTestServer(t, store, sessionClient, loginLimiter)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body(t, server.tokenMaker))
+			require.NoError(t, err)
+
+			url := "/users/login/mfa"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	user.TotpSecret = secret
+
+	code, err := util.TOTPCode(secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+
+	sessionID, err := uuid.NewRandom()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), mfaUserKey(user.Username)).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: "000000x",
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), mfaUserKey(user.Username), gomock.Any()).
+					Times(1).
+					Return(time.Minute, nil)
+				store.EXPECT().
+					EnableTOTPTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+				requireBodyMatchError(t, recorder.Body, errInvalidMFACode)
+			},
+		},
+		{
+			name: "Throttled",
+			code: code,
+			buildStubs: func(store *mockdb.MockStore, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), mfaUserKey(user.Username)).
+					Times(1).
+					Return(time.Minute, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			loginLimiter := mockLimiter.NewMockLimiter(ctrl)
+			tc.buildStubs(store, loginLimiter)
+
+			sessionClient.EXPECT().
+				Get(gomock.Any(), gomock.Eq(sessionID.String())).
+				Times(1).
+				Return(&session.Session{ID: sessionID.String(), Username: user.Username}, nil)
+			expectAccountAccess(store, user.Username, util.RoleUser)
+
+			server := newTestServer(t, store, sessionClient, loginLimiter)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"code": tc.code})
+			require.NoError(t, err)
+
+			url := "/users/me/totp/confirm"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, sessionID, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewareRejectsMFAToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	sessionClient := mockSession.NewMockSessionClient(ctrl)
+	sessionClient.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, nil, sessionClient, nil)
+
+	mfaToken, _, err := server.tokenMaker.CreatePurposeToken(token.PurposeMFAChallenge, "user", time.Minute)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users/logout", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+mfaToken)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestLoginUserMFARequired(t *testing.T) {
+	user, password := randomUser(t)
+	user.TotpEnabled = true
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	sessionClient := mockSession.NewMockSessionClient(ctrl)
+	loginLimiter := mockLimiter.NewMockLimiter(ctrl)
+
+	loginLimiter.EXPECT().
+		Check(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(time.Duration(0), nil)
+	store.EXPECT().
+		GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user, nil)
+	loginLimiter.EXPECT().
+		Reset(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+	sessionClient.EXPECT().
+		Set(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(0)
+	store.EXPECT().
+		GetLoginDeviceHistory(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.GetLoginDeviceHistoryRow{HasLogins: true, KnownDevice: true}, nil)
+	store.EXPECT().
+		CreateLoginEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+			require.Equal(t, util.LoginMFARequired, arg.Outcome)
+			return nil
+		})
+
+	server := newTestServer(t, store, sessionClient, loginLimiter)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+
+	var resp mfaChallengeResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.True(t, resp.MFARequired)
+
+	payload, err := server.tokenMaker.VerifyToken(resp.MFAToken)
+	require.NoError(t, err)
+	require.Equal(t, token.PurposeMFAChallenge, payload.Purpose)
+	require.Equal(t, user.Username, payload.Username)
+}
@@ -2,21 +2,28 @@ package api
 
 import (
 	"errors"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
 	"github.com/kamilwrzyszcz/go_example/session"
 	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
 )
 
 const (
 	authorizationHeaderKey  = "authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "authorization_payload"
+	authorizationRoleKey    = "authorization_role"
+	impersonatedByHeaderKey = "X-Impersonated-By"
 )
 
-func authMiddleware(tokenMaker token.Maker, sessionClient session.SessionClient) gin.HandlerFunc {
+var errImpersonationRestricted = errors.New("action is not allowed while impersonating a user")
+
+func authMiddleware(tokenMaker token.Maker, sessionClient session.SessionClient, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
@@ -25,45 +32,205 @@ func authMiddleware(tokenMaker token.Maker, sessionClient session.SessionClient)
 			return
 		}
 
-		fields := strings.Fields(authorizationHeader)
-		if len(fields) < 2 {
-			err := errors.New("invalid authorization header format")
+		payload, err := authenticate(ctx, tokenMaker, sessionClient, authorizationHeader)
+		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
+		if !checkAccountAccess(ctx, store, payload) {
+			return
+		}
 
-		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := errors.New("unsupported authorization type")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		setAuthorizationPayload(ctx, payload)
+		ctx.Next()
+	}
+}
+
+// optionalAuthMiddleware lets anonymous requests through, so that public routes can still tell who is reading.
+// Authorization that is given but invalid is rejected rather than silently ignored
+func optionalAuthMiddleware(tokenMaker token.Maker, sessionClient session.SessionClient, store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// Responses may differ for logged in readers, so shared caches must not mix them up
+		ctx.Header("Vary", "Authorization")
+
+		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+		if len(authorizationHeader) == 0 {
+			ctx.Next()
 			return
 		}
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		payload, err := authenticate(ctx, tokenMaker, sessionClient, authorizationHeader)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
+		if !checkAccountAccess(ctx, store, payload) {
+			return
+		}
 
-		session, err := sessionClient.Get(ctx, payload.ID.String())
-		if err != nil {
-			err = errors.New("couldn't find a session")
+		setAuthorizationPayload(ctx, payload)
+		ctx.Next()
+	}
+}
+
+// authenticate verifies the access token from the authorization header and the session it belongs to
+func authenticate(
+	ctx *gin.Context,
+	tokenMaker token.Maker,
+	sessionClient session.SessionClient,
+	authorizationHeader string,
+) (*token.Payload, error) {
+	fields := strings.Fields(authorizationHeader)
+	if len(fields) < 2 {
+		return nil, errors.New("invalid authorization header format")
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		return nil, errors.New("unsupported authorization type")
+	}
+
+	accessToken := fields[1]
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := payload.CheckPurpose(token.PurposeSession); err != nil {
+		return nil, err
+	}
+
+	session, err := sessionClient.Get(ctx, payload.ID.String())
+	if err != nil {
+		return nil, errors.New("couldn't find a session")
+	}
+	if session.IsBlocked {
+		return nil, errors.New("session has been blocked")
+	}
+	if session.Username != payload.Username {
+		return nil, errors.New("mismatching user between session and token")
+	}
+	if session.Impersonator != payload.Impersonator {
+		return nil, errors.New("mismatching impersonator between session and token")
+	}
+
+	return payload, nil
+}
+
+// checkAccountAccess aborts with an error and returns false when the account can't be used anymore.
+// Sessions are deleted when an account is disabled, but a login that was already past its checks
+// can still create one, so the account itself is checked on every request.
+// Impersonation sessions live in the index of the impersonated user, so the impersonator
+// is checked here too: it has to stay an enabled admin for the whole session
+func checkAccountAccess(ctx *gin.Context, store db.Store, payload *token.Payload) bool {
+	usernames := []string{payload.Username}
+	if payload.Impersonator != "" {
+		usernames = append(usernames, payload.Impersonator)
+	}
+
+	rows, err := store.ListUserAccess(ctx, usernames)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	users := make(map[string]db.ListUserAccessRow, len(rows))
+	for _, row := range rows {
+		users[row.Username] = row
+	}
+
+	user, ok := users[payload.Username]
+	if !ok {
+		err = errors.New("user doesn't exist")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+	if user.DisabledAt.Valid {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errAccountDisabled))
+		return false
+	}
+
+	if payload.Impersonator != "" {
+		impersonator, ok := users[payload.Impersonator]
+		if !ok || impersonator.DisabledAt.Valid || impersonator.Role != util.RoleAdmin {
+			err = errors.New("impersonator is no longer an admin")
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return false
+		}
+	}
+
+	// Kept for adminMiddleware, so that the role isn't read again
+	ctx.Set(authorizationRoleKey, user.Role)
+	return true
+}
+
+func setAuthorizationPayload(ctx *gin.Context, payload *token.Payload) {
+	// Clients can show that someone else is acting as the user
+	if payload.Impersonator != "" {
+		ctx.Header(impersonatedByHeaderKey, payload.Impersonator)
+	}
+
+	ctx.Set(authorizationPayloadKey, payload)
+}
+
+// optionalAuthPayload returns the payload set by optionalAuthMiddleware, or nil for anonymous readers
+func optionalAuthPayload(ctx *gin.Context) *token.Payload {
+	payload, ok := ctx.Get(authorizationPayloadKey)
+	if !ok {
+		return nil
+	}
+	return payload.(*token.Payload)
+}
+
+// adminMiddleware has to be used after authMiddleware.
+// Role is the one authMiddleware read from the db, so that revoking it takes effect immediately
+func adminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString(authorizationRoleKey) != util.RoleAdmin {
+			err := errors.New("admin role is required")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
 			return
 		}
-		if session.IsBlocked {
-			err = errors.New("session has been blocked")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+
+		ctx.Next()
+	}
+}
+
+// impersonationAuditMiddleware has to be used after authMiddleware or optionalAuthMiddleware.
+// Every request made while impersonating a user is written to the log once it's handled
+func impersonationAuditMiddleware(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		authPayload := optionalAuthPayload(ctx)
+		if authPayload == nil || authPayload.Impersonator == "" {
 			return
 		}
-		if session.Username != payload.Username {
-			err = errors.New("mismatching user between session and token")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+
+		arg := db.CreateImpersonationLogEntryParams{
+			SessionID:    authPayload.ID,
+			Impersonator: authPayload.Impersonator,
+			Username:     authPayload.Username,
+			Method:       ctx.Request.Method,
+			Path:         ctx.Request.URL.RequestURI(),
+			Status:       int32(ctx.Writer.Status()),
+			ClientIp:     ctx.ClientIP(),
+		}
+		// Response has been sent already, so failure can only be logged
+		if err := store.CreateImpersonationLogEntry(ctx, arg); err != nil {
+			log.Printf("cannot write impersonation log entry of %s as %s: %v", arg.Impersonator, arg.Username, err)
+		}
+	}
+}
+
+// denyImpersonation has to be used after authMiddleware.
+// It guards actions that can't be undone or would lock the user out of their account
+func denyImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if authPayload.Impersonator != "" {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errImpersonationRestricted))
 			return
 		}
 
-		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
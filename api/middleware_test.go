@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -11,9 +12,12 @@ import (
 	"github.com/go-redis/redis/v9"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
 	"github.com/kamilwrzyszcz/go_example/session"
 	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
 	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,10 +44,12 @@ func TestAuthMiddleware(t *testing.T) {
 	username := "user"
 
 	testCases := []struct {
-		name          string
-		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker, sessionID uuid.UUID)
-		buildStubs    func(sessionClient *mockSession.MockSessionClient)
-		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+		name       string
+		setupAuth  func(t *testing.T, request *http.Request, tokenMaker token.Maker, sessionID uuid.UUID)
+		buildStubs func(sessionClient *mockSession.MockSessionClient)
+		// Optional, cases rejected before the account is checked leave it out
+		buildStoreStubs func(store *mockdb.MockStore)
+		checkResponse   func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name: "OK",
@@ -67,6 +73,9 @@ func TestAuthMiddleware(t *testing.T) {
 					Times(1).
 					Return(arg, nil)
 			},
+			buildStoreStubs: func(store *mockdb.MockStore) {
+				expectAccountAccess(store, username, util.RoleUser)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
@@ -194,6 +203,97 @@ func TestAuthMiddleware(t *testing.T) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name: "ImpersonatorMismatch",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, sessionID uuid.UUID) {
+				addAuthorization(t, request, tokenMaker, sessionID, authorizationTypeBearer, "user", time.Minute)
+			},
+			buildStubs: func(sessionClient *mockSession.MockSessionClient) {
+				arg := &session.Session{
+					ID:           sessionID.String(),
+					Username:     username,
+					CreatedAt:    time.Now(),
+					ExpiresAt:    time.Now().Add(time.Minute),
+					Impersonator: "admin",
+				}
+
+				sessionClient.EXPECT().
+					Get(gomock.Any(), gomock.Eq(sessionID.String())).
+					Times(1).
+					Return(arg, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "DisabledUser",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, sessionID uuid.UUID) {
+				addAuthorization(t, request, tokenMaker, sessionID, authorizationTypeBearer, username, time.Minute)
+			},
+			buildStubs: func(sessionClient *mockSession.MockSessionClient) {
+				sessionClient.EXPECT().
+					Get(gomock.Any(), gomock.Eq(sessionID.String())).
+					Times(1).
+					Return(&session.Session{ID: sessionID.String(), Username: username}, nil)
+			},
+			buildStoreStubs: func(store *mockdb.MockStore) {
+				// The session outlived the disabling, e.g. a login finished at the same time
+				store.EXPECT().
+					ListUserAccess(gomock.Any(), gomock.Eq([]string{username})).
+					Times(1).
+					Return([]db.ListUserAccessRow{{
+						Username:   username,
+						Role:       util.RoleUser,
+						DisabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+					}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "DeletedUser",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, sessionID uuid.UUID) {
+				addAuthorization(t, request, tokenMaker, sessionID, authorizationTypeBearer, username, time.Minute)
+			},
+			buildStubs: func(sessionClient *mockSession.MockSessionClient) {
+				sessionClient.EXPECT().
+					Get(gomock.Any(), gomock.Eq(sessionID.String())).
+					Times(1).
+					Return(&session.Session{ID: sessionID.String(), Username: username}, nil)
+			},
+			buildStoreStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListUserAccess(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ListUserAccessRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccountCheckError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, sessionID uuid.UUID) {
+				addAuthorization(t, request, tokenMaker, sessionID, authorizationTypeBearer, username, time.Minute)
+			},
+			buildStubs: func(sessionClient *mockSession.MockSessionClient) {
+				sessionClient.EXPECT().
+					Get(gomock.Any(), gomock.Eq(sessionID.String())).
+					Times(1).
+					Return(&session.Session{ID: sessionID.String(), Username: username}, nil)
+			},
+			buildStoreStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListUserAccess(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -206,12 +306,17 @@ func TestAuthMiddleware(t *testing.T) {
 			sessionClient := mockSession.NewMockSessionClient(ctrl)
 			tc.buildStubs(sessionClient)
 
-			server := newTestServer(t, nil, sessionClient)
+			store := mockdb.NewMockStore(ctrl)
+			if tc.buildStoreStubs != nil {
+				tc.buildStoreStubs(store)
+			}
+
+			server := newTestServer(t, store, sessionClient, nil)
 
 			authPath := "/fake"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker, sessionClient),
+				authMiddleware(server.tokenMaker, sessionClient, store),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+)
+
+// Same as the length required before the policy was configurable
+const defaultPasswordMinLength = 6
+
+type passwordPolicyResponse struct {
+	Error      string                   `json:"error"`
+	Violations []util.PasswordViolation `json:"violations"`
+}
+
+// validateNewPassword checks the password against the policy and the breached passwords corpus.
+// Responds with 400 listing every failed rule and returns false if the password can't be used.
+func (server *Server) validateNewPassword(ctx *gin.Context, password, username, email string) bool {
+	var violations []util.PasswordViolation
+
+	err := server.passwordPolicy.Validate(password, username, email)
+	if err != nil {
+		var policyErr *util.PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+		violations = policyErr.Violations
+	}
+
+	if server.breachedPasswords != nil {
+		breached, err := server.breachedPasswords.IsBreached(password)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+		if breached {
+			violations = append(violations, util.PasswordViolation{
+				Rule:    util.PasswordRuleBreached,
+				Message: "has appeared in a data breach and can't be used",
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		policyErr := &util.PasswordPolicyError{Violations: violations}
+		ctx.JSON(http.StatusBadRequest, passwordPolicyResponse{
+			Error:      policyErr.Error(),
+			Violations: violations,
+		})
+		return false
+	}
+
+	return true
+}
+
+var (
+	errResetLinkUsed            = errors.New("password reset link has already been used")
+	errCurrentPasswordIncorrect = errors.New("current password is incorrect")
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Change password of the authenticated user. Wrong current passwords are throttled like logins, other sessions of the user are logged out
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.changePasswordRequest    true  "Change password payload"
+// @Success      200  {object}  api.userResponse
+// @Failure      400  {object}  api.passwordPolicyResponse
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      429  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /users/me/password [post]
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// Shares the counter with logins, otherwise a stolen access token would allow unlimited guesses
+	throttleKey := loginUserKey(strings.ToLower(authPayload.Username))
+	if !server.checkLoginThrottle(ctx, throttleKey) {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.hasher.Check(req.CurrentPassword, user.HashedPassword)
+	if err != nil {
+		delay, err := server.loginLimiter.Fail(ctx, throttleKey, server.loginUserPolicy())
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if delay > 0 {
+			setRetryAfter(ctx, delay)
+		}
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errCurrentPasswordIncorrect))
+		return
+	}
+
+	err = server.loginLimiter.Reset(ctx, throttleKey)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !server.validateNewPassword(ctx, req.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := server.hasher.Hash(req.NewPassword)
+	if err != nil {
+		if errors.Is(err, util.ErrPasswordTooLong) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.UpdateUserPasswordParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	}
+	user, err = server.store.UpdateUserPassword(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Whoever knew the old password is logged out everywhere but on the device that changed it
+	if err := server.deleteOtherSessions(ctx, user.Username, authPayload.ID.String()); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.newUserResponse(user))
+}
+
+// deleteOtherSessions deletes every session of the user except the current one
+func (server *Server) deleteOtherSessions(ctx *gin.Context, username, currentID string) error {
+	sessions, err := server.sessionClient.ListByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == currentID {
+			continue
+		}
+		if err := server.sessionClient.Del(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password using the token from the password reset email. Each link works only once and all sessions of the user are logged out
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.resetPasswordRequest    true  "Reset password payload"
+// @Success      200  {object}  api.userResponse
+// @Failure      400  {object}  api.passwordPolicyResponse
+// @Failure      401  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /users/password/reset [post]
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.Token)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if err := payload.CheckPurpose(token.PurposePasswordReset); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrInvalidToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Validated before the link is used up, so that a rejected password can be corrected
+	if !server.validateNewPassword(ctx, req.NewPassword, user.Username, user.Email) {
+		return
+	}
+
+	hashedPassword, err := server.hasher.Hash(req.NewPassword)
+	if err != nil {
+		if errors.Is(err, util.ErrPasswordTooLong) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rows, err := server.store.ConsumeToken(ctx, db.ConsumeTokenParams{
+		ID:        payload.ID,
+		Purpose:   payload.Purpose,
+		ExpiresAt: payload.ExpiredAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errResetLinkUsed))
+		return
+	}
+
+	arg := db.UpdateUserPasswordParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	}
+	user, err = server.store.UpdateUserPassword(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.sessionClient.DelByUsername(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.newUserResponse(user))
+}
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	mockLimiter "github.com/kamilwrzyszcz/go_example/limiter/mock"
+	"github.com/kamilwrzyszcz/go_example/session"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser(t)
+	sessionID, err := uuid.NewRandom()
+	require.NoError(t, err)
+	throttleKey := loginUserKey(strings.ToLower(user.Username))
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	breachedDir := t.TempDir()
+	err = os.WriteFile(filepath.Join(breachedDir, "5BAA6"), []byte("1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n"), 0o644)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     "new-" + password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), throttleKey).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), throttleKey).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				// Only the session the password was changed from is kept
+				sessionClient.EXPECT().
+					ListByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return([]*session.Session{
+						{ID: sessionID.String(), Username: user.Username},
+						{ID: "other", Username: user.Username},
+					}, nil)
+				sessionClient.EXPECT().
+					Del(gomock.Any(), gomock.Eq("other")).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Del(gomock.Any(), gomock.Eq(sessionID.String())).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "IncorrectCurrentPassword",
+			body: gin.H{
+				"current_password": "incorrect",
+				"new_password":     "new-" + password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), throttleKey).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), throttleKey, gomock.Any()).
+					Times(1).
+					Return(time.Minute, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+				requireBodyMatchError(t, recorder.Body, errCurrentPasswordIncorrect)
+			},
+		},
+		{
+			name: "PolicyViolation",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     user.Username,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), throttleKey).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), throttleKey).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchViolations(t, recorder.Body, util.PasswordRuleContainsUsername)
+			},
+		},
+		{
+			name: "BreachedPassword",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     "password",
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), throttleKey).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), throttleKey).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchViolations(t, recorder.Body, util.PasswordRuleBreached)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     "new-" + password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), throttleKey).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "Throttled",
+			body: gin.H{
+				"current_password": password,
+				"new_password":     "new-" + password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), throttleKey).
+					Times(1).
+					Return(time.Minute, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			loginLimiter := mockLimiter.NewMockLimiter(ctrl)
+			tc.buildStubs(store, sessionClient, loginLimiter)
+
+			arg := &session.Session{
+				ID:           sessionID.String(),
+				Username:     user.Username,
+				RefreshToken: "refresh_token",
+				CreatedAt:    time.Now(),
+				ExpiresAt:    time.Now().Add(time.Hour * 24),
+				UserAgent:    "Mozilla",
+				ClientIP:     "0.0.0.0",
+				IsBlocked:    false,
+			}
+			sessionClient.EXPECT().
+				Get(gomock.Any(), gomock.Eq(sessionID.String())).
+				Times(1).
+				Return(arg, nil)
+			expectAccountAccess(store, user.Username, util.RoleUser)
+
+			server := newTestServer(t, store, sessionClient, loginLimiter)
+			server.breachedPasswords, err = util.NewLocalBreachedPasswords(breachedDir)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/users/me/password"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, sessionID, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	user.PasswordResetRequired = true
+	newPassword := util.RandomString(8)
+
+	resetToken := func(t *testing.T, tokenMaker token.Maker, purpose string) string {
+		resetToken, _, err := tokenMaker.CreatePurposeToken(purpose, user.Username, time.Minute)
+		require.NoError(t, err)
+		return resetToken
+	}
+
+	testCases := []struct {
+		name          string
+		purpose       string
+		password      string
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			purpose:  token.PurposePasswordReset,
+			password: newPassword,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UpdateUserPasswordParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPassword(newPassword, arg.HashedPassword))
+
+						updated := user
+						updated.HashedPassword = arg.HashedPassword
+						updated.PasswordResetRequired = false
+						return updated, nil
+					})
+				sessionClient.EXPECT().
+					DelByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "AlreadyUsed",
+			purpose:  token.PurposePasswordReset,
+			password: newPassword,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					UpdateUserPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errResetLinkUsed)
+			},
+		},
+		{
+			name:     "WeakPassword",
+			purpose:  token.PurposePasswordReset,
+			password: "abc",
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				// The link isn't used up
+				store.EXPECT().
+					ConsumeToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchViolations(t, recorder.Body, util.PasswordRuleMinLength)
+			},
+		},
+		{
+			name:     "WrongPurpose",
+			purpose:  token.PurposeMagicLink,
+			password: newPassword,
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			tc.buildStubs(store, sessionClient)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"token":        resetToken(t, server.tokenMaker, tc.purpose),
+				"new_password": tc.password,
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func requireBodyMatchViolations(t *testing.T, body *bytes.Buffer, rules ...string) {
+	var resp passwordPolicyResponse
+	err := json.Unmarshal(body.Bytes(), &resp)
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Error)
+
+	gotRules := make([]string, len(resp.Violations))
+	for i, violation := range resp.Violations {
+		gotRules[i] = violation.Rule
+	}
+	require.Equal(t, rules, gotRules)
+}
+
+func TestNewServerPasswordPolicy(t *testing.T) {
+	config := util.Config{TokenSymmetricKey: util.RandomString(32)}
+
+	server, err := NewServer(config, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, defaultPasswordMinLength, server.passwordPolicy.MinLength)
+	require.Zero(t, server.passwordPolicy.MaxLength)
+
+	// bcrypt can't hash longer passwords
+	config.PasswordHashAlgo = util.PasswordAlgorithmBcrypt
+	server, err = NewServer(config, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, util.BcryptMaxPasswordLength, server.passwordPolicy.MaxLength)
+
+	config.PasswordMaxLength = util.BcryptMaxPasswordLength + 1
+	_, err = NewServer(config, nil, nil, nil, nil, nil)
+	require.Error(t, err)
+}
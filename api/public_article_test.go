@@ -0,0 +1,223 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/session"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicArticleAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	reader, _ := randomUser(t)
+	article := randomArticle(user.Username)
+	sessionID, err := uuid.NewRandom()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		url           string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "AnonymousGet",
+			url:       fmt.Sprintf("/public/articles/%d", article.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				sessionClient.EXPECT().
+					Get(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "Authorization", recorder.Header().Get("Vary"))
+				requireBodyMatchArticle(t, recorder.Body, article)
+			},
+		},
+		{
+			name:      "NotFound",
+			url:       "/public/articles/2137",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Article{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "AnonymousList",
+			url:       "/public/articles?page_id=2&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				arg := db.ListPublicArticlesParams{
+					Limit:  5,
+					Offset: 5,
+				}
+				store.EXPECT().
+					ListPublicArticles(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Article{article, randomArticle(reader.Username)}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "AuthorFilter",
+			url:       fmt.Sprintf("/public/articles?page_id=1&page_size=5&author=%s", user.Username),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				arg := db.ListPublicArticlesParams{
+					Author: sql.NullString{String: user.Username, Valid: true},
+					Limit:  5,
+					Offset: 0,
+				}
+				store.EXPECT().
+					ListPublicArticles(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Article{article}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "LoggedInReader",
+			url:  "/public/articles?page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, sessionID, authorizationTypeBearer, reader.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				sessionClient.EXPECT().
+					Get(gomock.Any(), gomock.Eq(sessionID.String())).
+					Times(1).
+					Return(&session.Session{ID: sessionID.String(), Username: reader.Username}, nil)
+				expectAccountAccess(store, reader.Username, util.RoleUser)
+				store.EXPECT().
+					ListPublicArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Article{article}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "DisabledReader",
+			url:  "/public/articles?page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, sessionID, authorizationTypeBearer, reader.Username, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				sessionClient.EXPECT().
+					Get(gomock.Any(), gomock.Eq(sessionID.String())).
+					Times(1).
+					Return(&session.Session{ID: sessionID.String(), Username: reader.Username}, nil)
+				store.EXPECT().
+					ListUserAccess(gomock.Any(), gomock.Eq([]string{reader.Username})).
+					Times(1).
+					Return([]db.ListUserAccessRow{{
+						Username:   reader.Username,
+						Role:       util.RoleUser,
+						DisabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+					}}, nil)
+				store.EXPECT().
+					ListPublicArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// Authorization that is given but can't be used isn't ignored
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidToken",
+			url:  "/public/articles?page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, sessionID, authorizationTypeBearer, reader.Username, -time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ListPublicArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidPageSize",
+			url:       "/public/articles?page_id=1&page_size=50",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ListPublicArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			url:       "/public/articles?page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ListPublicArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Article{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			tc.buildStubs(store, sessionClient)
+
+			stubArticleDetails(store)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/token"
+	"github.com/kamilwrzyszcz/go_example/util"
+)
+
+// getOwnArticle gets an article of the logged in user, responding with an error when it can't
+func (server *Server) getOwnArticle(ctx *gin.Context, id int64) (db.Article, bool) {
+	article, err := server.getReadableArticle(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.Article{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.Article{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if article.Author != authPayload.Username {
+		err := errors.New("article doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return db.Article{}, false
+	}
+	return article, true
+}
+
+type listRevisionsRequest struct {
+	ArticleID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listRevisionsQuery struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// ListRevisions godoc
+// @Summary      Get revisions of an article
+// @Description  Get the revision history of an article of the logged in user, newest first. The content is left out, it can be fetched revision by revision
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   page_id   query    int32   true  "Revisions PageID query param"
+// @Param   page_size  query    int32   true  "Revisions PageSize query param"
+// @Success      200  {object}  []db.ListArticleRevisionsRow
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/revisions [get]
+func (server *Server) listRevisions(ctx *gin.Context) {
+	var req listRevisionsRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var query listRevisionsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	article, ok := server.getOwnArticle(ctx, req.ArticleID)
+	if !ok {
+		return
+	}
+
+	revisions, err := server.store.ListArticleRevisions(ctx, db.ListArticleRevisionsParams{
+		ArticleID: article.ID,
+		Limit:     query.PageSize,
+		Offset:    (query.PageID - 1) * query.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revisions)
+}
+
+type revisionRequest struct {
+	ArticleID int64 `uri:"id" binding:"required,min=1"`
+	Revision  int32 `uri:"revision" binding:"required,min=1"`
+}
+
+// GetRevision godoc
+// @Summary      Get a revision of an article
+// @Description  Get the headline and content an article of the logged in user had in the given revision
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   revision   path    int32   true  "Revision number path param"
+// @Success      200  {object}  db.ArticleRevision
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/revisions/{revision} [get]
+func (server *Server) getRevision(ctx *gin.Context) {
+	var req revisionRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	article, ok := server.getOwnArticle(ctx, req.ArticleID)
+	if !ok {
+		return
+	}
+
+	revision, ok := server.getRevisionOf(ctx, article.ID, req.Revision)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revision)
+}
+
+// getRevisionOf gets a revision of an article, responding with an error when it can't
+func (server *Server) getRevisionOf(ctx *gin.Context, articleID int64, number int32) (db.ArticleRevision, bool) {
+	revision, err := server.store.GetArticleRevision(ctx, db.GetArticleRevisionParams{
+		ArticleID: articleID,
+		Revision:  number,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.ArticleRevision{}, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.ArticleRevision{}, false
+	}
+	return revision, true
+}
+
+type diffRevisionsQuery struct {
+	From int32  `form:"from" binding:"required,min=1"`
+	To   int32  `form:"to" binding:"required,min=1"`
+	Mode string `form:"mode" binding:"omitempty,oneof=line word"`
+}
+
+type revisionDiffResponse struct {
+	From int32  `json:"from"`
+	To   int32  `json:"to"`
+	Mode string `json:"mode"`
+	// Headlines are always compared word by word
+	Headline []util.DiffChunk `json:"headline"`
+	Content  []util.DiffChunk `json:"content"`
+}
+
+// DiffRevisions godoc
+// @Summary      Compare two revisions of an article
+// @Description  Get the changes between two revisions of an article of the logged in user, line by line (default) or word by word.
+// @Description  Chunks are equal, inserted or deleted, joining the equal and deleted ones gives the text of from, the equal and inserted ones the text of to
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   from   query    int32   true  "Older revision number"
+// @Param   to   query    int32   true  "Newer revision number"
+// @Param   mode   query    string   false  "Diff mode"  Enums(line, word)
+// @Success      200  {object}  api.revisionDiffResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/revisions/diff [get]
+func (server *Server) diffRevisions(ctx *gin.Context) {
+	var req listRevisionsRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var query diffRevisionsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if query.Mode == "" {
+		query.Mode = util.DiffModeLine
+	}
+
+	article, ok := server.getOwnArticle(ctx, req.ArticleID)
+	if !ok {
+		return
+	}
+
+	from, ok := server.getRevisionOf(ctx, article.ID, query.From)
+	if !ok {
+		return
+	}
+	to, ok := server.getRevisionOf(ctx, article.ID, query.To)
+	if !ok {
+		return
+	}
+
+	resp := revisionDiffResponse{
+		From:     from.Revision,
+		To:       to.Revision,
+		Mode:     query.Mode,
+		Headline: util.DiffWords(from.Headline, to.Headline),
+	}
+	if query.Mode == util.DiffModeWord {
+		resp.Content = util.DiffWords(from.Content, to.Content)
+	} else {
+		resp.Content = util.DiffLines(from.Content, to.Content)
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// RestoreRevision godoc
+// @Summary      Restore a revision of an article
+// @Description  Bring back the headline and content of an older revision. The history is kept, the restored text becomes a new revision
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   id   path    int64   true  "Article ID path param"
+// @Param   revision   path    int32   true  "Revision number path param"
+// @Param   If-Match   header    string   false  "ETag of the article, the restore fails when it changed since"
+// @Success      200  {object}  api.articleResponse
+// @Header       200  {string}  ETag  "ETag of the restored article"
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      412  {object} object{error=string}
+// @Failure      428  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/{id}/revisions/{revision}/restore [post]
+func (server *Server) restoreRevision(ctx *gin.Context) {
+	var req revisionRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	article, ok := server.getOwnArticle(ctx, req.ArticleID)
+	if !ok {
+		return
+	}
+
+	revision, ok := server.getRevisionOf(ctx, article.ID, req.Revision)
+	if !ok {
+		return
+	}
+
+	// Restoring overwrites the article like an update does
+	version, ok := server.checkIfMatch(ctx, article)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.UpdateArticleTx(ctx, db.UpdateArticleTxParams{
+		UpdateArticleParams: db.UpdateArticleParams{
+			ID:       article.ID,
+			Headline: sql.NullString{String: revision.Headline, Valid: true},
+			Content:  sql.NullString{String: revision.Content, Valid: true},
+			Version:  version,
+		},
+		Editor:       authPayload.Username,
+		RestoredFrom: sql.NullInt32{Int32: revision.Revision, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows && version.Valid {
+			ctx.JSON(http.StatusPreconditionFailed, errorResponse(errArticleChanged))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := []articleResponse{newArticleResponse(result.Article, result.Tags)}
+	err = server.addArticleStats(ctx, resp)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header(etagHeaderKey, articleETag(result.Article))
+	ctx.JSON(http.StatusOK, resp[0])
+}
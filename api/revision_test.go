@@ -0,0 +1,355 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	article := randomArticle(user.Username)
+
+	first := db.ArticleRevision{
+		ArticleID: article.ID,
+		Revision:  1,
+		Headline:  "Old headline",
+		Content:   "first line\nsecond line\n",
+		Editor:    user.Username,
+	}
+	second := db.ArticleRevision{
+		ArticleID: article.ID,
+		Revision:  2,
+		Headline:  "New headline",
+		Content:   "first line\nchanged line\n",
+		Editor:    user.Username,
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		method        string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "List",
+			username: user.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions?page_id=2&page_size=5", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.ListArticleRevisionsParams{
+					ArticleID: article.ID,
+					Limit:     5,
+					Offset:    5,
+				}
+				store.EXPECT().
+					ListArticleRevisions(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ListArticleRevisionsRow{{ArticleID: article.ID, Revision: 1}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var revisions []db.ListArticleRevisionsRow
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &revisions))
+				require.Len(t, revisions, 1)
+			},
+		},
+		{
+			name:     "ListInvalidPage",
+			username: user.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions?page_id=1&page_size=100", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticleRevisions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "ListOfAnotherUser",
+			username: other.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions?page_id=1&page_size=5", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					ListArticleRevisions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "Get",
+			username: user.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions/1", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Eq(db.GetArticleRevisionParams{ArticleID: article.ID, Revision: 1})).
+					Times(1).
+					Return(first, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				var revision db.ArticleRevision
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &revision))
+				require.Equal(t, first, revision)
+			},
+		},
+		{
+			name:     "GetNotFound",
+			username: user.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions/7", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ArticleRevision{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "DiffLines",
+			username: user.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions/diff?from=1&to=2", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Eq(db.GetArticleRevisionParams{ArticleID: article.ID, Revision: 1})).
+					Times(1).
+					Return(first, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Eq(db.GetArticleRevisionParams{ArticleID: article.ID, Revision: 2})).
+					Times(1).
+					Return(second, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyRevisionDiff(t, recorder)
+				require.Equal(t, util.DiffModeLine, resp.Mode)
+				require.Equal(t, []util.DiffChunk{
+					{Op: util.DiffDelete, Text: "Old"},
+					{Op: util.DiffInsert, Text: "New"},
+					{Op: util.DiffEqual, Text: " headline"},
+				}, resp.Headline)
+				require.Equal(t, []util.DiffChunk{
+					{Op: util.DiffEqual, Text: "first line\n"},
+					{Op: util.DiffDelete, Text: "second line\n"},
+					{Op: util.DiffInsert, Text: "changed line\n"},
+				}, resp.Content)
+			},
+		},
+		{
+			name:     "DiffWords",
+			username: user.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions/diff?from=1&to=2&mode=word", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(func(_ interface{}, arg db.GetArticleRevisionParams) (db.ArticleRevision, error) {
+						if arg.Revision == 1 {
+							return first, nil
+						}
+						return second, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyRevisionDiff(t, recorder)
+				require.Equal(t, util.DiffModeWord, resp.Mode)
+				require.Equal(t, []util.DiffChunk{
+					{Op: util.DiffEqual, Text: "first line\n"},
+					{Op: util.DiffDelete, Text: "second"},
+					{Op: util.DiffInsert, Text: "changed"},
+					{Op: util.DiffEqual, Text: " line\n"},
+				}, resp.Content)
+			},
+		},
+		{
+			name:     "DiffInvalidMode",
+			username: user.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions/diff?from=1&to=2&mode=char", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "DiffRevisionNotFound",
+			username: user.Username,
+			method:   http.MethodGet,
+			url:      fmt.Sprintf("/articles/%d/revisions/diff?from=1&to=9", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Eq(db.GetArticleRevisionParams{ArticleID: article.ID, Revision: 1})).
+					Times(1).
+					Return(first, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Eq(db.GetArticleRevisionParams{ArticleID: article.ID, Revision: 9})).
+					Times(1).
+					Return(db.ArticleRevision{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "Restore",
+			username: user.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/revisions/1/restore", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Eq(db.GetArticleRevisionParams{ArticleID: article.ID, Revision: 1})).
+					Times(1).
+					Return(first, nil)
+				arg := db.UpdateArticleTxParams{
+					UpdateArticleParams: db.UpdateArticleParams{
+						ID:       article.ID,
+						Headline: sql.NullString{String: first.Headline, Valid: true},
+						Content:  sql.NullString{String: first.Content, Valid: true},
+					},
+					Editor:       user.Username,
+					RestoredFrom: sql.NullInt32{Int32: 1, Valid: true},
+				}
+				restored := article
+				restored.Headline = first.Headline
+				restored.Content = first.Content
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ArticleTxResult{Article: restored, Tags: []string{}, Revision: 3}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, first.Headline, resp.Headline)
+				require.Equal(t, first.Content, resp.Content)
+			},
+		},
+		{
+			name:     "RestoreOfAnotherUser",
+			username: other.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/revisions/1/restore", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "RestoreInternalError",
+			username: user.Username,
+			method:   http.MethodPost,
+			url:      fmt.Sprintf("/articles/%d/revisions/1/restore", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					GetArticleRevision(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(first, nil)
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ArticleTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubArticleDetails(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, tc.username, tc.method, tc.url)
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func requireBodyRevisionDiff(t *testing.T, recorder *httptest.ResponseRecorder) revisionDiffResponse {
+	var resp revisionDiffResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	return resp
+}
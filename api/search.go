@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+)
+
+const defaultSearchLanguage = "english"
+
+type searchArticlesRequest struct {
+	Query    string `form:"q" binding:"required,max=200"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=1"`
+}
+
+// SearchArticles godoc
+// @Summary      Search articles
+// @Description  Full-text search over headlines and content, best matches first. The query supports "quoted phrases", OR and -excluded words.
+// @Description  Snippets of the headline and content are HTML escaped text, where only the matched words are wrapped in <b></b>
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   q   query    string   true  "Search query"
+// @Param   page_id   query    int32   true  "Search PageID query param"
+// @Param   page_size  query    int32   true  "Search PageSize query param, limited by the server config"
+// @Success      200  {object}  []db.SearchArticlesRow
+// @Failure      400  {object} object{error=string}
+// @Failure      401  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /articles/search [get]
+func (server *Server) searchArticles(ctx *gin.Context) {
+	var req searchArticlesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.PageSize > server.config.ArticleMaxPageSize {
+		err := fmt.Errorf("page size can't be larger than %d", server.config.ArticleMaxPageSize)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.SearchArticlesParams{
+		Language: server.config.SearchLanguage,
+		Query:    req.Query,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	}
+
+	results, err := server.store.SearchArticles(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, results)
+}
+
+// ReindexSearch rebuilds the search index of articles written under another SEARCH_LANGUAGE.
+// It has to run before serving, otherwise those articles can't be found
+func (server *Server) ReindexSearch(ctx context.Context) error {
+	n, err := server.store.ReindexArticleSearch(ctx, server.config.SearchLanguage)
+	if err != nil {
+		return fmt.Errorf("cannot reindex articles: %w", err)
+	}
+	if n > 0 {
+		log.Printf("reindexed %d articles with %s text search configuration", n, server.config.SearchLanguage)
+	}
+	return nil
+}
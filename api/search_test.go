@@ -0,0 +1,133 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchArticlesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	article := randomArticle(user.Username)
+	results := []db.SearchArticlesRow{
+		{
+			ID:              article.ID,
+			Author:          article.Author,
+			Headline:        article.Headline,
+			Content:         article.Content,
+			Rank:            0.6,
+			HeadlineSnippet: "<b>" + article.Headline + "</b>",
+			ContentSnippet:  article.Content,
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?q=" + url.QueryEscape(`"go modules" -vendor`) + "&page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SearchArticlesParams{
+					Language: defaultSearchLanguage,
+					Query:    `"go modules" -vendor`,
+					Limit:    5,
+					Offset:   5,
+				}
+				store.EXPECT().
+					SearchArticles(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(results, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []db.SearchArticlesRow
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Equal(t, results, resp)
+			},
+		},
+		{
+			name:  "MissingQuery",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "PageSizeTooLarge",
+			query: "?q=go&page_id=1&page_size=11",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?q=go&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.SearchArticlesRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, http.MethodGet, "/articles/search"+tc.query)
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestNewServerSearchLanguage(t *testing.T) {
+	config := util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		SearchLanguage:    "klingon",
+	}
+
+	// Every search would fail on the database otherwise
+	_, err := NewServer(config, nil, nil, nil, nil, nil)
+	require.EqualError(t, err, "unsupported search language: klingon")
+
+	config.SearchLanguage = "german"
+	_, err = NewServer(config, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+}
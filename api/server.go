@@ -1,11 +1,18 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kamilwrzyszcz/go_example/blob"
 	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
 	_ "github.com/kamilwrzyszcz/go_example/docs"
+	"github.com/kamilwrzyszcz/go_example/limiter"
+	"github.com/kamilwrzyszcz/go_example/mail"
 	"github.com/kamilwrzyszcz/go_example/session"
 	"github.com/kamilwrzyszcz/go_example/token"
 	"github.com/kamilwrzyszcz/go_example/util"
@@ -18,21 +25,111 @@ type Server struct {
 	config        util.Config
 	store         db.Store
 	sessionClient session.SessionClient
+	loginLimiter  limiter.Limiter
+	blobStore     blob.BlobStore
+	mailer        mail.Mailer
 	tokenMaker    token.Maker
+	hasher        util.PasswordHasher
 	router        *gin.Engine
+
+	passwordPolicy    util.PasswordPolicy
+	breachedPasswords util.BreachedPasswordChecker
+
+	dummyPasswordOnce sync.Once
+	dummyPassword     string
+
+	// Emails sent in the background, tests wait for them before checking the mailer
+	mailing sync.WaitGroup
 }
 
 // NewServer creates a new HTTP server and setup routing
-func NewServer(config util.Config, store db.Store, sessionClient session.SessionClient) (*Server, error) {
+func NewServer(
+	config util.Config,
+	store db.Store,
+	sessionClient session.SessionClient,
+	loginLimiter limiter.Limiter,
+	blobStore blob.BlobStore,
+	mailer mail.Mailer,
+) (*Server, error) {
 	tokenMaker, err := token.NewJWTMaker(config.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	if config.RegistrationMode == "" {
+		config.RegistrationMode = util.RegistrationOpen
+	}
+	if !util.IsSupportedRegistrationMode(config.RegistrationMode) {
+		return nil, fmt.Errorf("unsupported registration mode: %s", config.RegistrationMode)
+	}
+	if config.AvatarMaxSize <= 0 {
+		config.AvatarMaxSize = defaultAvatarMaxSize
+	}
+	if config.DevicePollInterval < time.Second {
+		config.DevicePollInterval = defaultDevicePollInterval
+	}
+	if config.ImpersonateDuration <= 0 {
+		config.ImpersonateDuration = defaultImpersonateDuration
+	}
+	if config.ArticleMaxPageSize <= 0 {
+		config.ArticleMaxPageSize = defaultArticleMaxPageSize
+	}
+	if config.SearchLanguage == "" {
+		config.SearchLanguage = defaultSearchLanguage
+	}
+	if !util.IsSupportedSearchLanguage(config.SearchLanguage) {
+		return nil, fmt.Errorf("unsupported search language: %s", config.SearchLanguage)
+	}
+	if config.CommentEditWindow <= 0 {
+		config.CommentEditWindow = defaultCommentEditWindow
+	}
+	if config.PasswordMinLength <= 0 {
+		config.PasswordMinLength = defaultPasswordMinLength
+	}
+	// Longer passwords would pass the policy and then fail to hash
+	if config.PasswordHashAlgo == util.PasswordAlgorithmBcrypt {
+		if config.PasswordMaxLength == 0 {
+			config.PasswordMaxLength = util.BcryptMaxPasswordLength
+		}
+		if config.PasswordMaxLength > util.BcryptMaxPasswordLength {
+			return nil, fmt.Errorf("password max length can't be more than %d with bcrypt", util.BcryptMaxPasswordLength)
+		}
+	}
+	hasher, err := util.NewPasswordHasher(
+		config.PasswordHashAlgo,
+		util.Argon2idParams{
+			Memory:      config.Argon2Memory,
+			Iterations:  config.Argon2Iterations,
+			Parallelism: config.Argon2Parallelism,
+		},
+		config.BcryptCost,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
 	server := &Server{
 		config:        config,
 		store:         store,
 		sessionClient: sessionClient,
+		loginLimiter:  loginLimiter,
+		blobStore:     blobStore,
+		mailer:        mailer,
 		tokenMaker:    tokenMaker,
+		hasher:        hasher,
+		passwordPolicy: util.PasswordPolicy{
+			MinLength:     config.PasswordMinLength,
+			MaxLength:     config.PasswordMaxLength,
+			RequireUpper:  config.PasswordNeedsUpper,
+			RequireLower:  config.PasswordNeedsLower,
+			RequireDigit:  config.PasswordNeedsDigit,
+			RequireSymbol: config.PasswordNeedsSymbol,
+		},
+	}
+
+	if config.BreachedPasswordsDir != "" {
+		server.breachedPasswords, err = util.NewLocalBreachedPasswords(config.BreachedPasswordsDir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create breached passwords checker: %w", err)
+		}
 	}
 
 	server.setupRouter()
@@ -60,20 +157,101 @@ func (server *Server) setupRouter() {
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Blobs in S3 are served by the storage itself
+	if server.config.BlobStore == "local" && server.config.BlobLocalDir != "" {
+		router.Static("/blobs", server.config.BlobLocalDir)
+	}
+
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login/mfa", server.loginUserMFA)
+	router.POST("/users/login/magic", server.sendMagicLink)
+	router.POST("/users/login/magic/consume", server.consumeMagicLink)
+	router.POST("/users/password/reset", server.resetPassword)
 
 	router.POST("/tokens/renew_access", server.renewAccessToken)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.sessionClient))
+	router.POST("/oauth/device/code", server.createDeviceCode)
+	router.POST("/oauth/token", server.exchangeDeviceCode)
+
+	router.GET("/authors", server.listAuthors)
+	router.GET("/authors/:username", server.getAuthor)
+	router.GET("/authors/:username/followers", server.listFollowers)
+	router.GET("/authors/:username/following", server.listFollowing)
+	router.GET("/tags", server.listTags)
+
+	// Anyone can read, a valid token only identifies the reader
+	publicRoutes := router.Group("/public").Use(
+		optionalAuthMiddleware(server.tokenMaker, server.sessionClient, server.store),
+		impersonationAuditMiddleware(server.store),
+	)
+
+	publicRoutes.GET("/articles", server.listPublicArticles)
+	publicRoutes.GET("/articles/:id", server.getPublicArticle)
+	publicRoutes.GET("/articles/:id/comments", server.listComments)
+
+	authRoutes := router.Group("/").Use(
+		authMiddleware(server.tokenMaker, server.sessionClient, server.store),
+		impersonationAuditMiddleware(server.store),
+	)
 
 	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.POST("/users/me/password", denyImpersonation(), server.changePassword)
+	authRoutes.POST("/users/me/username", denyImpersonation(), server.changeUsername)
+	authRoutes.PUT("/users/me/avatar", denyImpersonation(), server.uploadAvatar)
+	authRoutes.GET("/users/me/logins", server.listLoginEvents)
+	authRoutes.GET("/users/me/favorites", server.listFavorites)
+	authRoutes.POST("/users/me/totp", denyImpersonation(), server.enrollTOTP)
+	authRoutes.POST("/users/me/totp/confirm", denyImpersonation(), server.confirmTOTP)
+	authRoutes.POST("/users/me/totp/disable", denyImpersonation(), server.disableTOTP)
+
+	authRoutes.POST("/oauth/device/approve", denyImpersonation(), server.approveDeviceCode)
+
+	authRoutes.POST("/authors/:username/follow", server.followAuthor)
+	authRoutes.DELETE("/authors/:username/follow", server.unfollowAuthor)
+	authRoutes.GET("/feed", server.getFeed)
 
 	authRoutes.POST("/articles", server.createArticle)
 	authRoutes.GET("/articles/:id", server.getArticle)
 	authRoutes.GET("/articles", server.listArticles)
-	authRoutes.DELETE("/articles/:id", server.deleteArticle)
+	authRoutes.GET("/articles/search", server.searchArticles)
+	authRoutes.DELETE("/articles/:id", denyImpersonation(), server.deleteArticle)
 	authRoutes.PATCH("/articles/:id", server.updateArticle)
+	authRoutes.POST("/articles/:id/publish", server.publishArticle)
+	authRoutes.POST("/articles/:id/schedule", server.scheduleArticle)
+	authRoutes.POST("/articles/:id/unpublish", denyImpersonation(), server.unpublishArticle)
+	authRoutes.POST("/articles/:id/archive", denyImpersonation(), server.archiveArticle)
+	authRoutes.GET("/articles/:id/revisions", server.listRevisions)
+	authRoutes.GET("/articles/:id/revisions/diff", server.diffRevisions)
+	authRoutes.GET("/articles/:id/revisions/:revision", server.getRevision)
+	authRoutes.POST("/articles/:id/revisions/:revision/restore", denyImpersonation(), server.restoreRevision)
+	authRoutes.POST("/articles/:id/favorite", server.favoriteArticle)
+	authRoutes.DELETE("/articles/:id/favorite", server.unfavoriteArticle)
+	authRoutes.POST("/articles/:id/reactions/:reaction", server.addReaction)
+	authRoutes.DELETE("/articles/:id/reactions/:reaction", server.removeReaction)
+	authRoutes.POST("/articles/:id/comments", server.createComment)
+	authRoutes.GET("/articles/:id/comments", server.listComments)
+	authRoutes.PATCH("/comments/:id", server.updateComment)
+	authRoutes.DELETE("/comments/:id", denyImpersonation(), server.deleteComment)
+
+	adminRoutes := router.Group("/admin").Use(
+		authMiddleware(server.tokenMaker, server.sessionClient, server.store),
+		impersonationAuditMiddleware(server.store),
+		adminMiddleware(),
+	)
+
+	adminRoutes.POST("/invitations", server.createInvitation)
+	adminRoutes.GET("/invitations", server.listInvitations)
+	adminRoutes.DELETE("/invitations/:id", server.revokeInvitation)
+
+	adminRoutes.GET("/users", server.listUsers)
+	adminRoutes.GET("/users/:username", server.getUserDetails)
+	adminRoutes.POST("/users/:username/disable", server.disableUser)
+	adminRoutes.POST("/users/:username/enable", server.enableUser)
+	adminRoutes.POST("/users/:username/password_reset", server.forcePasswordReset)
+	adminRoutes.PUT("/users/:username/role", server.changeUserRole)
+	adminRoutes.POST("/users/:username/impersonate", server.impersonateUser)
+	adminRoutes.GET("/impersonation_log", server.listImpersonationLog)
 
 	server.router = router
 }
@@ -83,6 +261,24 @@ func (server *Server) Start(address string) error {
 	return server.router.Run(address)
 }
 
+// mailTimeout bounds an email sent in the background, there is no request to cancel it anymore
+const mailTimeout = time.Minute
+
+// sendMail sends the message in the background, so that a slow mail server doesn't hold up the response.
+// Failure can only be logged, what describes the email in the log
+func (server *Server) sendMail(msg mail.Message, what string) {
+	server.mailing.Add(1)
+	go func() {
+		defer server.mailing.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := server.mailer.Send(ctx, msg); err != nil {
+			log.Printf("cannot send %s: %v", what, err)
+		}
+	}()
+}
+
 // Reusable error response func
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
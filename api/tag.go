@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+)
+
+type listTagsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// ListTags godoc
+// @Summary      Get the list of tags
+// @Description  Get the tags used by articles, most used first
+// @Tags         articles
+// @Accept       json
+// @Produce      json
+// @Param   page_id   query    int32   true  "Tags PageID query param"
+// @Param   page_size  query    int32   true  "Tags PageSize query param"
+// @Success      200  {object}  []db.ListTagsRow
+// @Failure      400  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Router       /tags [get]
+func (server *Server) listTags(ctx *gin.Context) {
+	var req listTagsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ListTagsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	}
+
+	tags, err := server.store.ListTags(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tags)
+}
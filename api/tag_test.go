@@ -0,0 +1,391 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleTagsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	article := randomArticle(user.Username)
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "CreateWithTags",
+			method: http.MethodPost,
+			url:    "/articles",
+			body: gin.H{
+				"headline": article.Headline,
+				"content":  article.Content,
+				"tags":     []string{"Go Modules", "go_modules", "SQL"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateArticleTxParams{
+					CreateArticleParams: db.CreateArticleParams{
+						Author:         user.Username,
+						Headline:       article.Headline,
+						Content:        article.Content,
+						Status:         util.ArticleDraft,
+						SearchLanguage: defaultSearchLanguage,
+					},
+					Tags: []string{"go-modules", "sql"},
+				}
+				store.EXPECT().
+					CreateArticleTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ArticleTxResult{Article: article, Tags: arg.Tags}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, article, resp.Article)
+				require.Equal(t, []string{"go-modules", "sql"}, resp.Tags)
+			},
+		},
+		{
+			name:   "CreateInvalidTag",
+			method: http.MethodPost,
+			url:    "/articles",
+			body: gin.H{
+				"headline": article.Headline,
+				"content":  article.Content,
+				"tags":     []string{"go", "--"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateArticleTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateTooManyTags",
+			method: http.MethodPost,
+			url:    "/articles",
+			body: gin.H{
+				"headline": article.Headline,
+				"content":  article.Content,
+				"tags":     []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateArticleTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateReplacesTags",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/articles/%d", article.ID),
+			body:   gin.H{"tags": []string{"Databases"}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.UpdateArticleTxParams{
+					UpdateArticleParams: db.UpdateArticleParams{ID: article.ID},
+					Tags:                []string{"databases"},
+					Editor:              user.Username,
+				}
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ArticleTxResult{Article: article, Tags: arg.Tags}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, []string{"databases"}, resp.Tags)
+			},
+		},
+		{
+			name:   "UpdateClearsTags",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/articles/%d", article.ID),
+			body:   gin.H{"tags": []string{}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.UpdateArticleTxParams{
+					UpdateArticleParams: db.UpdateArticleParams{ID: article.ID},
+					Tags:                []string{},
+					Editor:              user.Username,
+				}
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ArticleTxResult{Article: article}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Empty(t, resp.Tags)
+			},
+		},
+		{
+			name:   "UpdateKeepsTags",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/articles/%d", article.ID),
+			body:   gin.H{"headline": "new headline"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				arg := db.UpdateArticleTxParams{
+					UpdateArticleParams: db.UpdateArticleParams{
+						ID:       article.ID,
+						Headline: sql.NullString{String: "new headline", Valid: true},
+					},
+					Editor: user.Username,
+				}
+				store.EXPECT().
+					UpdateArticleTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ArticleTxResult{Article: article, Tags: []string{"go"}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, []string{"go"}, resp.Tags)
+			},
+		},
+		{
+			name:   "GetWithTags",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/articles/%d", article.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetArticle(gomock.Any(), gomock.Eq(article.ID)).
+					Times(1).
+					Return(article, nil)
+				store.EXPECT().
+					ListTagsOfArticles(gomock.Any(), gomock.Eq([]int64{article.ID})).
+					Times(1).
+					Return([]db.ListTagsOfArticlesRow{
+						{ArticleID: article.ID, Name: "go"},
+						{ArticleID: article.ID, Name: "sql"},
+					}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				resp := requireBodyArticleResponse(t, recorder.Body)
+				require.Equal(t, []string{"go", "sql"}, resp.Tags)
+			},
+		},
+		{
+			name:   "ListByAnyTag",
+			method: http.MethodGet,
+			url:    "/articles?page_id=1&page_size=5&tag=Go&tag=SQL",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListArticlesParams) ([]db.Article, error) {
+						require.Equal(t, []string{"go", "sql"}, arg.Tags)
+						require.False(t, arg.AllTags)
+						return []db.Article{article}, nil
+					})
+				store.EXPECT().
+					ListTagsOfArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ListTagsOfArticlesRow{{ArticleID: article.ID, Name: "go"}}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []articleResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Len(t, resp, 1)
+				require.Equal(t, []string{"go"}, resp[0].Tags)
+			},
+		},
+		{
+			name:   "ListByAllTags",
+			method: http.MethodGet,
+			url:    "/articles?page_id=1&page_size=5&tag=go&tag=sql&tag_mode=all",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ListArticlesParams) ([]db.Article, error) {
+						require.Equal(t, []string{"go", "sql"}, arg.Tags)
+						require.True(t, arg.AllTags)
+						return []db.Article{}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ListInvalidTagMode",
+			method: http.MethodGet,
+			url:    "/articles?page_id=1&page_size=5&tag=go&tag_mode=some",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "ListInvalidTag",
+			method: http.MethodGet,
+			url:    "/articles?page_id=1&page_size=5&tag=%2B%2B",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListArticles(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			stubArticleDetails(store)
+
+			server, request := newAuthorizedTestRequest(t, ctrl, store, user.Username, tc.method, tc.url)
+			if tc.body != nil {
+				data, err := json.Marshal(tc.body)
+				require.NoError(t, err)
+				request.Body = io.NopCloser(bytes.NewReader(data))
+			}
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListTagsAPI(t *testing.T) {
+	tags := []db.ListTagsRow{
+		{Name: "go", ArticleCount: 12},
+		{Name: "sql", ArticleCount: 3},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListTagsParams{
+					Limit:  5,
+					Offset: 5,
+				}
+				store.EXPECT().
+					ListTags(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(tags, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp []db.ListTagsRow
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Equal(t, tags, resp)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "?page_id=1&page_size=100",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListTags(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListTags(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ListTagsRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil, nil)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/tags"+tc.query, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func requireBodyArticleResponse(t *testing.T, body *bytes.Buffer) articleResponse {
+	var resp articleResponse
+	err := json.NewDecoder(body).Decode(&resp)
+	require.NoError(t, err)
+	return resp
+}
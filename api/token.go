@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kamilwrzyszcz/go_example/token"
 )
 
 type renewAccessTokenRequest struct {
@@ -41,6 +42,10 @@ func (server *Server) renewAccessToken(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
+	if err := refreshPayload.CheckPurpose(token.PurposeSession); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
 
 	session, err := server.sessionClient.Get(ctx, refreshPayload.ID.String())
 	if err != nil {
@@ -2,7 +2,10 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,11 +17,20 @@ import (
 	"github.com/lib/pq"
 )
 
+var (
+	errRegistrationClosed    = errors.New("registration is closed")
+	errInvitationRequired    = errors.New("registration requires an invitation code")
+	errAccountDisabled       = errors.New("account is disabled")
+	errPasswordResetRequired = errors.New("password has to be reset using the link sent by email")
+)
+
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" bindign:"required"`
 	Email    string `json:"email" binding:"required,email"`
+	// Required when registration is invite only
+	InviteCode string `json:"invite_code"`
 }
 
 type userResponse struct {
@@ -27,16 +39,23 @@ type userResponse struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	TOTPEnabled       bool      `json:"totp_enabled"`
+	Role              string    `json:"role"`
+	// Avatar URLs keyed by the size of the square in pixels. Empty when the user has no avatar
+	AvatarURLs map[string]string `json:"avatar_urls,omitempty"`
 }
 
 // func to cover some fields that shouldn't be leaked in response
-func newUserResponse(user db.User) userResponse {
+func (server *Server) newUserResponse(user db.User) userResponse {
 	return userResponse{
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
+		TOTPEnabled:       user.TotpEnabled,
+		Role:              user.Role,
+		AvatarURLs:        server.avatarURLs(user.AvatarID),
 	}
 }
 
@@ -48,7 +67,7 @@ func newUserResponse(user db.User) userResponse {
 // @Produce      json
 // @Param   payload   body    api.createUserRequest    true  "User payload"
 // @Success      201  {object}  api.userResponse
-// @Failure      400  {object} object{error=string}
+// @Failure      400  {object}  api.passwordPolicyResponse
 // @Failure      403  {object} object{error=string}
 // @Failure      500  {object} object{error=string}
 // @Router       /users [post]
@@ -59,8 +78,30 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := util.HashPassword(req.Password)
+	// Emails are stored lowercase, usernames keep their case but are unique regardless of it
+	req.Email = strings.ToLower(req.Email)
+
+	switch server.config.RegistrationMode {
+	case util.RegistrationClosed:
+		ctx.JSON(http.StatusForbidden, errorResponse(errRegistrationClosed))
+		return
+	case util.RegistrationInviteOnly:
+		if req.InviteCode == "" {
+			ctx.JSON(http.StatusForbidden, errorResponse(errInvitationRequired))
+			return
+		}
+	}
+
+	if !server.validateNewPassword(ctx, req.Password, req.Username, req.Email) {
+		return
+	}
+
+	hashedPassword, err := server.hasher.Hash(req.Password)
 	if err != nil {
+		if errors.Is(err, util.ErrPasswordTooLong) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -72,8 +113,20 @@ func (server *Server) createUser(ctx *gin.Context) {
 		Email:          req.Email,
 	}
 
-	user, err := server.store.CreateUser(ctx, arg)
+	var user db.User
+	if server.config.RegistrationMode == util.RegistrationInviteOnly {
+		user, err = server.store.CreateUserTx(ctx, db.CreateUserTxParams{
+			CreateUserParams:     arg,
+			HashedInvitationCode: util.HashInvitationCode(req.InviteCode),
+		})
+	} else {
+		user, err = server.store.CreateUser(ctx, arg)
+	}
 	if err != nil {
+		if errors.Is(err, db.ErrInvalidInvitation) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
 			case "unique_violation":
@@ -85,13 +138,25 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	resp := newUserResponse(user)
+	resp := server.newUserResponse(user)
 	ctx.JSON(http.StatusCreated, resp)
 }
 
 type loginUserRequest struct {
-	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	// Username or email, both are case insensitive
+	Login string `json:"login" binding:"required_without=Username,max=254"`
+	// Deprecated: use login
+	Username string `json:"username" binding:"max=254"`
+	Password string `json:"password" binding:"required"`
+}
+
+// identifier returns the normalized login identifier, so that all spellings of the same login share one throttle counter
+func (req loginUserRequest) identifier() string {
+	login := req.Login
+	if login == "" {
+		login = req.Username
+	}
+	return strings.ToLower(strings.TrimSpace(login))
 }
 
 type loginUserResponse struct {
@@ -105,15 +170,17 @@ type loginUserResponse struct {
 
 // LoginUser godoc
 // @Summary      Login user
-// @Description  Login a user
+// @Description  Login a user with username or email, both case insensitive
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param   payload   body    api.loginUserRequest    true  "Login payload"
 // @Success      200  {object}  api.loginUserResponse
+// @Success      202  {object}  api.mfaChallengeResponse
 // @Failure      400  {object} object{error=string}
 // @Failure      401  {object} object{error=string}
-// @Failure      404  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      429  {object} object{error=string}
 // @Failure      500  {object} object{error=string}
 // @Router       /users/login [post]
 func (server *Server) loginUser(ctx *gin.Context) {
@@ -123,36 +190,123 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
-	user, err := server.store.GetUser(ctx, req.Username)
+	login := req.identifier()
+	if !server.checkLoginThrottle(ctx, loginUserKey(login), loginIPKey(ctx.ClientIP())) {
+		return
+	}
+
+	user, err := server.store.GetUserByLogin(ctx, login)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			server.hasher.Check(req.Password, server.dummyHashedPassword())
+			server.recordLoginEvent(ctx, login, "", util.LoginFailed)
+			server.rejectLogin(ctx, login)
 			return
 		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	err = util.CheckPassword(req.Password, user.HashedPassword)
+	err = server.hasher.Check(req.Password, user.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		server.recordLoginEvent(ctx, login, user.Username, util.LoginFailed)
+		server.rejectLogin(ctx, login)
 		return
 	}
 
-	sessionID, err := uuid.NewRandom()
+	if server.hasher.NeedsRehash(user.HashedPassword) {
+		server.rehashPassword(ctx, user, req.Password)
+	}
+
+	// Client IP counter is not reset on purpose, otherwise one valid account
+	// would be enough to keep guessing passwords of the others
+	err = server.loginLimiter.Reset(ctx, loginUserKey(login))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	if !server.checkUserCanLogin(ctx, user) {
+		server.recordLoginEvent(ctx, login, user.Username, util.LoginBlocked)
+		return
+	}
+
+	// Checked before this login is recorded, otherwise every device would be known already
+	server.notifyNewDevice(ctx, user)
+
+	if user.TotpEnabled {
+		server.recordLoginEvent(ctx, login, user.Username, util.LoginMFARequired)
+		server.startMFAChallenge(ctx, user)
+		return
+	}
+
+	resp, err := server.newUserSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.recordLoginEvent(ctx, login, user.Username, util.LoginSucceeded)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// checkUserCanLogin responds with 403 and returns false if the account is disabled or waiting for a password reset.
+// It's checked by every login flow before a session is created
+func (server *Server) checkUserCanLogin(ctx *gin.Context, user db.User) bool {
+	if user.DisabledAt.Valid {
+		ctx.JSON(http.StatusForbidden, errorResponse(errAccountDisabled))
+		return false
+	}
+	if user.PasswordResetRequired {
+		ctx.JSON(http.StatusForbidden, errorResponse(errPasswordResetRequired))
+		return false
+	}
+	return true
+}
+
+// rehashPassword upgrades the stored hash to the current algorithm and parameters.
+// It's done while the plain password is known, that is after a successful login.
+// Failure is not fatal, the old hash still works and it will be retried on the next login.
+func (server *Server) rehashPassword(ctx *gin.Context, user db.User, password string) {
+	hashedPassword, err := server.hasher.Hash(password)
+	if err != nil {
+		log.Printf("cannot rehash password of %s: %v", user.Username, err)
+		return
+	}
+
+	arg := db.UpdateUserHashedPasswordParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	}
+	err = server.store.UpdateUserHashedPassword(ctx, arg)
+	if err != nil {
+		log.Printf("cannot store rehashed password of %s: %v", user.Username, err)
+	}
+}
+
+// dummyHashedPassword returns a hash made with the current hasher. It's checked against when the user
+// doesn't exist, so that response time doesn't reveal which usernames are registered
+func (server *Server) dummyHashedPassword() string {
+	server.dummyPasswordOnce.Do(func() {
+		server.dummyPassword, _ = server.hasher.Hash(util.RandomString(16))
+	})
+	return server.dummyPassword
+}
+
+// newUserSession creates a new session together with access and refresh tokens for the user
+func (server *Server) newUserSession(ctx *gin.Context, user db.User) (loginUserResponse, error) {
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+
 	accessToken, accessPayload, err := server.tokenMaker.CreateToken(
 		sessionID,
 		user.Username,
 		server.config.AccessTokenDuration,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return loginUserResponse{}, err
 	}
 	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(
 		sessionID,
@@ -160,8 +314,7 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		server.config.RefreshTokenDuration,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return loginUserResponse{}, err
 	}
 
 	session := &session.Session{
@@ -176,8 +329,7 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	}
 	err = server.sessionClient.Set(ctx, session.ID, session)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		return loginUserResponse{}, err
 	}
 
 	resp := loginUserResponse{
@@ -186,7 +338,79 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
 		RefreshToken:          refreshToken,
 		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
-		User:                  newUserResponse(user),
+		User:                  server.newUserResponse(user),
+	}
+
+	return resp, nil
+}
+
+type changeUsernameRequest struct {
+	NewUsername string `json:"new_username" binding:"required,alphanum"`
+}
+
+// ChangeUsername godoc
+// @Summary      Change username
+// @Description  Rename the logged in user. Articles follow the user and the old profile URL redirects to the new one.
+// @Description  All sessions of the user are ended, a new session is returned for the caller
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param   payload   body    api.changeUsernameRequest    true  "Change username payload"
+// @Success      200  {object}  api.loginUserResponse
+// @Failure      400  {object} object{error=string}
+// @Failure      403  {object} object{error=string}
+// @Failure      404  {object} object{error=string}
+// @Failure      500  {object} object{error=string}
+// @Security BearerAuth
+// @Router       /users/me/username [post]
+func (server *Server) changeUsername(ctx *gin.Context) {
+	var req changeUsernameRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if req.NewUsername == authPayload.Username {
+		err := errors.New("new username is the same as the current one")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := db.ChangeUsernameTxParams{
+		Username:    authPayload.Username,
+		NewUsername: req.NewUsername,
+	}
+
+	user, err := server.store.ChangeUsernameTx(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Existing tokens carry the old username, so the sessions can't be moved over.
+	// Every device has to log in again, the caller gets a fresh session right away
+	err = server.sessionClient.DelByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := server.newUserSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
 	ctx.JSON(http.StatusOK, resp)
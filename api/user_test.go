@@ -9,12 +9,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
 	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	mockLimiter "github.com/kamilwrzyszcz/go_example/limiter/mock"
+	"github.com/kamilwrzyszcz/go_example/session"
+	mockSession "github.com/kamilwrzyszcz/go_example/session/mock"
 	"github.com/kamilwrzyszcz/go_example/util"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
@@ -54,6 +60,30 @@ func TestUserAPI(t *testing.T) {
 				requireBodyMatchUser(t, recorder.Body, user)
 			},
 		},
+		{
+			name: "EmailNormalized",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  password,
+				"full_name": user.FullName,
+				"email":     strings.ToUpper(user.Email),
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateUserParams{
+					Username: user.Username,
+					FullName: user.FullName,
+					Email:    user.Email,
+				}
+
+				store.EXPECT().
+					CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
 		{
 			name: "InternalError",
 			body: gin.H{
@@ -107,6 +137,24 @@ func TestUserAPI(t *testing.T) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
 			},
 		},
+		{
+			name: "PasswordContainsUsername",
+			body: gin.H{
+				"username":  user.Username,
+				"password":  "my" + user.Username,
+				"full_name": user.FullName,
+				"email":     user.Email,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireBodyMatchViolations(t, recorder.Body, util.PasswordRuleContainsUsername)
+			},
+		},
 		{
 			name: "InvalidEmail",
 			body: gin.H{
@@ -136,7 +184,7 @@ func TestUserAPI(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
 
-			server := newTestServer(t, store, nil)
+			server := newTestServer(t, store, nil, nil)
 			recorder := httptest.NewRecorder()
 
 			// Marshal body data to JSON
@@ -153,6 +201,596 @@ func TestUserAPI(t *testing.T) {
 	}
 }
 
+func TestCreateUserRegistrationModeAPI(t *testing.T) {
+	user, password := randomUser(t)
+	inviteCode := "invite-code"
+
+	testCases := []struct {
+		name          string
+		mode          string
+		inviteCode    string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "InviteOnlyCreated",
+			mode:       util.RegistrationInviteOnly,
+			inviteCode: inviteCode,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateUserTxParams) (db.User, error) {
+						require.Equal(t, util.HashInvitationCode(inviteCode), arg.HashedInvitationCode)
+						require.Equal(t, user.Username, arg.Username)
+						return user, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				requireBodyMatchUser(t, recorder.Body, user)
+			},
+		},
+		{
+			name: "InviteOnlyMissingCode",
+			mode: util.RegistrationInviteOnly,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvitationRequired)
+			},
+		},
+		{
+			name:       "InviteOnlyInvalidCode",
+			mode:       util.RegistrationInviteOnly,
+			inviteCode: inviteCode,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, db.ErrInvalidInvitation)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, db.ErrInvalidInvitation)
+			},
+		},
+		{
+			name:       "OpenIgnoresCode",
+			mode:       util.RegistrationOpen,
+			inviteCode: inviteCode,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:       "Closed",
+			mode:       util.RegistrationClosed,
+			inviteCode: inviteCode,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errRegistrationClosed)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store, nil, nil)
+			server.config.RegistrationMode = tc.mode
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(gin.H{
+				"username":    user.Username,
+				"password":    password,
+				"full_name":   user.FullName,
+				"email":       user.Email,
+				"invite_code": tc.inviteCode,
+			})
+			require.NoError(t, err)
+
+			url := "/users"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser(t)
+
+	bcryptHasher, err := util.NewPasswordHasher(util.PasswordAlgorithmBcrypt, util.Argon2idParams{}, 0)
+	require.NoError(t, err)
+	legacyUser := user
+	legacyUser.HashedPassword, err = bcryptHasher.Hash(password)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"login":    user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), loginUserKey(user.Username), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), loginUserKey(user.Username)).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginSucceeded, arg.Outcome)
+						require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Disabled",
+			body: gin.H{
+				"login":    user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				disabled := user
+				disabled.DisabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(disabled, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginBlocked, arg.Outcome)
+						require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errAccountDisabled)
+			},
+		},
+		{
+			name: "PasswordResetRequired",
+			body: gin.H{
+				"login":    user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				resetRequired := user
+				resetRequired.PasswordResetRequired = true
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(resetRequired, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errPasswordResetRequired)
+			},
+		},
+		{
+			name: "OKCaseInsensitive",
+			body: gin.H{
+				"login":    strings.ToUpper(user.Username),
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), loginUserKey(user.Username), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), loginUserKey(user.Username)).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OKWithEmail",
+			body: gin.H{
+				"login":    " " + strings.ToUpper(user.Email),
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), loginUserKey(user.Email), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), loginUserKey(user.Email)).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MissingLogin",
+			body: gin.H{
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RehashOutdatedHash",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(legacyUser, nil)
+				store.EXPECT().
+					UpdateUserHashedPassword(gomock.Any(), EqUpdateUserHashedPasswordParams(user.Username, password)).
+					Times(1).
+					Return(nil)
+				loginLimiter.EXPECT().
+					Reset(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{
+				"username": "notfound",
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(2).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginFailed, arg.Outcome)
+						require.Equal(t, sql.NullString{}, arg.Username)
+						return nil
+					})
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvalidCredentials)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": "incorrect",
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), loginUserKey(user.Username), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginFailed, arg.Outcome)
+						require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvalidCredentials)
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: gin.H{
+				"username": user.Username,
+				// Checked against the hash, the policy of the time the password was set decided its length
+				"password": "abc",
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), loginUserKey(user.Username), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateLoginEventParams) error {
+						require.Equal(t, util.LoginFailed, arg.Outcome)
+						require.Equal(t, sql.NullString{String: user.Username, Valid: true}, arg.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				requireBodyMatchError(t, recorder.Body, errInvalidCredentials)
+			},
+		},
+		{
+			name: "LockedAfterFailure",
+			body: gin.H{
+				"username": user.Username,
+				"password": "incorrect",
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), loginUserKey(user.Username), gomock.Any()).
+					Times(1).
+					Return(4*time.Second, nil)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, "4", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "Throttled",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(1500*time.Millisecond, nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CreateLoginEvent(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "2", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient, loginLimiter *mockLimiter.MockLimiter) {
+				loginLimiter.EXPECT().
+					Check(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(time.Duration(0), nil)
+				store.EXPECT().
+					GetUserByLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+				loginLimiter.EXPECT().
+					Fail(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			loginLimiter := mockLimiter.NewMockLimiter(ctrl)
+			tc.buildStubs(store, sessionClient, loginLimiter)
+			// Login history isn't the point of most cases, the device is always known
+			store.EXPECT().
+				CreateLoginEvent(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(nil)
+			store.EXPECT().
+				GetLoginDeviceHistory(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(db.GetLoginDeviceHistoryRow{HasLogins: true, KnownDevice: true}, nil)
+
+			server := newTestServer(t, store, sessionClient, loginLimiter)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/users/login"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
 // Helper functions
 
 func randomUser(t *testing.T) (user db.User, password string) {
@@ -183,6 +821,18 @@ func requireBodyMatchUser(t *testing.T, body *bytes.Buffer, user db.User) {
 	require.Empty(t, gotUser.HashedPassword)
 }
 
+func requireBodyMatchError(t *testing.T, body *bytes.Buffer, expected error) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotError struct {
+		Error string `json:"error"`
+	}
+	err = json.Unmarshal(data, &gotError)
+	require.NoError(t, err)
+	require.Equal(t, expected.Error(), gotError.Error)
+}
+
 type eqCreateUserParamsMatcher struct {
 	arg      db.CreateUserParams
 	password string
@@ -214,3 +864,158 @@ func (e eqCreateUserParamsMatcher) String() string {
 func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher {
 	return eqCreateUserParamsMatcher{arg, password}
 }
+
+type eqUpdateUserHashedPasswordParamsMatcher struct {
+	username string
+	password string
+}
+
+// Rehashed password has to be made with the current algorithm, which is argon2id by default
+func (e eqUpdateUserHashedPasswordParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.UpdateUserHashedPasswordParams)
+	if !ok {
+		return false
+	}
+
+	if !strings.HasPrefix(arg.HashedPassword, "$argon2id$") {
+		return false
+	}
+
+	err := util.CheckPassword(e.password, arg.HashedPassword)
+	if err != nil {
+		return false
+	}
+
+	return e.username == arg.Username
+}
+
+func (e eqUpdateUserHashedPasswordParamsMatcher) String() string {
+	return fmt.Sprintf("matches username %v and password %v", e.username, e.password)
+}
+
+func EqUpdateUserHashedPasswordParams(username, password string) gomock.Matcher {
+	return eqUpdateUserHashedPasswordParamsMatcher{username, password}
+}
+
+func TestChangeUsernameAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	renamed := user
+	renamed.Username = util.RandomAuthor()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"new_username": renamed.Username},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				arg := db.ChangeUsernameTxParams{
+					Username:    user.Username,
+					NewUsername: renamed.Username,
+				}
+				store.EXPECT().
+					ChangeUsernameTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(renamed, nil)
+				sessionClient.EXPECT().
+					DelByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+				sessionClient.EXPECT().
+					Set(gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, _ string, newSession *session.Session) error {
+						require.Equal(t, renamed.Username, newSession.Username)
+						return nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var resp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
+				require.NoError(t, err)
+				require.Equal(t, renamed.Username, resp.User.Username)
+				require.NotEmpty(t, resp.AccessToken)
+			},
+		},
+		{
+			name: "SameUsername",
+			body: gin.H{"new_username": user.Username},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ChangeUsernameTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			body: gin.H{"new_username": "not valid"},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ChangeUsernameTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UsernameTaken",
+			body: gin.H{"new_username": renamed.Username},
+			buildStubs: func(store *mockdb.MockStore, sessionClient *mockSession.MockSessionClient) {
+				store.EXPECT().
+					ChangeUsernameTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505"})
+				sessionClient.EXPECT().
+					DelByUsername(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			sessionID, err := uuid.NewRandom()
+			require.NoError(t, err)
+
+			store := mockdb.NewMockStore(ctrl)
+			sessionClient := mockSession.NewMockSessionClient(ctrl)
+			sessionClient.EXPECT().
+				Get(gomock.Any(), gomock.Eq(sessionID.String())).
+				Times(1).
+				Return(&session.Session{ID: sessionID.String(), Username: user.Username}, nil)
+			expectAccountAccess(store, user.Username, util.RoleUser)
+			tc.buildStubs(store, sessionClient)
+
+			server := newTestServer(t, store, sessionClient, nil)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/users/me/username"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, sessionID, authorizationTypeBearer, user.Username, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package blob
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir, "http://localhost:8080/blobs/")
+	require.NoError(t, err)
+
+	key := "avatars/abc/64.jpg"
+	err = store.Put(context.Background(), key, strings.NewReader("image"), "image/jpeg")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "avatars", "abc", "64.jpg"))
+	require.NoError(t, err)
+	require.Equal(t, "image", string(data))
+	require.Equal(t, "http://localhost:8080/blobs/avatars/abc/64.jpg", store.URL(key))
+
+	// Overwrite
+	err = store.Put(context.Background(), key, strings.NewReader("image2"), "image/jpeg")
+	require.NoError(t, err)
+	data, err = os.ReadFile(filepath.Join(dir, "avatars", "abc", "64.jpg"))
+	require.NoError(t, err)
+	require.Equal(t, "image2", string(data))
+
+	err = store.Delete(context.Background(), key)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "avatars", "abc", "64.jpg"))
+	require.True(t, os.IsNotExist(err))
+
+	// Deleting a missing blob is not an error
+	err = store.Delete(context.Background(), key)
+	require.NoError(t, err)
+}
+
+func TestLocalStoreInvalidKey(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "http://localhost:8080/blobs")
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../outside", "a//b", `a\b`} {
+		err = store.Put(context.Background(), key, strings.NewReader("x"), "text/plain")
+		require.ErrorIs(t, err, ErrInvalidKey, key)
+		err = store.Delete(context.Background(), key)
+		require.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,117 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// S3Config contains the connection details of S3-compatible storage
+type S3Config struct {
+	// Endpoint is the base URL of the storage, e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PublicURL is where objects are served from. Defaults to the bucket URL
+	PublicURL string
+}
+
+// S3Store keeps blobs in a bucket of S3-compatible storage using path-style requests
+type S3Store struct {
+	config     S3Config
+	bucketURL  string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewS3Store creates a new S3Store
+func NewS3Store(config S3Config) (*S3Store, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %q", config.Endpoint)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is not set")
+	}
+
+	bucketURL := joinURL(config.Endpoint, uriEncode(config.Bucket))
+	if config.PublicURL == "" {
+		config.PublicURL = bucketURL
+	}
+
+	return &S3Store{
+		config:     config,
+		bucketURL:  bucketURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		now:        time.Now,
+	}, nil
+}
+
+// Put uploads the blob. The body is read into memory, because its hash has to be signed
+func (store *S3Store) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("cannot read blob: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, store.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return store.do(req, hashHex(body), http.StatusOK)
+}
+
+// Delete removes the blob. Missing blobs are not an error
+func (store *S3Store) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, store.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+
+	return store.do(req, emptyPayloadHash, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+}
+
+// URL returns the public URL of the blob
+func (store *S3Store) URL(key string) string {
+	return joinURL(store.config.PublicURL, key)
+}
+
+func (store *S3Store) objectURL(key string) string {
+	return joinURL(store.bucketURL, canonicalURI(key))
+}
+
+func (store *S3Store) do(req *http.Request, payloadHash string, expectedStatuses ...int) error {
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signV4(req, payloadHash, store.config.AccessKeyID, store.config.SecretAccessKey, store.config.Region, "s3", store.now())
+
+	resp, err := store.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	for _, status := range expectedStatuses {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("s3 %s %s failed with status %d: %s", req.Method, req.URL.Path, resp.StatusCode, msg)
+}
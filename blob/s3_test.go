@@ -0,0 +1,136 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAccessKeyID     = "access-key"
+	testSecretAccessKey = "secret-key"
+	testRegion          = "us-east-1"
+)
+
+// fakeS3 is a local stand-in for S3-compatible storage. It checks request signatures
+// and keeps objects in memory
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !s.validSignature(r, body) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		s.objects[r.URL.Path] = fakeObject{data: body, contentType: r.Header.Get("Content-Type")}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		delete(s.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *fakeS3) validSignature(r *http.Request, body []byte) bool {
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != hashHex(body) {
+		return false
+	}
+	signedAt, err := time.Parse(sigV4TimeFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	expected := r.Clone(context.Background())
+	expected.Header.Del("Authorization")
+	signV4(expected, payloadHash, testAccessKeyID, testSecretAccessKey, testRegion, "s3", signedAt)
+
+	return expected.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func newTestS3Store(t *testing.T, secretAccessKey string) (*S3Store, *fakeS3) {
+	fake := &fakeS3{objects: make(map[string]fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:        server.URL,
+		Region:          testRegion,
+		Bucket:          "avatars-bucket",
+		AccessKeyID:     testAccessKeyID,
+		SecretAccessKey: secretAccessKey,
+	})
+	require.NoError(t, err)
+
+	return store, fake
+}
+
+func TestS3Store(t *testing.T) {
+	store, fake := newTestS3Store(t, testSecretAccessKey)
+
+	key := "avatars/abc/64.jpg"
+	err := store.Put(context.Background(), key, strings.NewReader("image"), "image/jpeg")
+	require.NoError(t, err)
+
+	object, ok := fake.objects["/avatars-bucket/avatars/abc/64.jpg"]
+	require.True(t, ok)
+	require.Equal(t, "image", string(object.data))
+	require.Equal(t, "image/jpeg", object.contentType)
+	require.True(t, strings.HasSuffix(store.URL(key), "/avatars-bucket/avatars/abc/64.jpg"))
+
+	err = store.Delete(context.Background(), key)
+	require.NoError(t, err)
+	require.Empty(t, fake.objects)
+
+	err = store.Put(context.Background(), "../outside", strings.NewReader("image"), "image/jpeg")
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestS3StoreWrongCredentials(t *testing.T) {
+	store, fake := newTestS3Store(t, "wrong-secret")
+
+	err := store.Put(context.Background(), "avatars/abc/64.jpg", strings.NewReader("image"), "image/jpeg")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "403")
+	require.Empty(t, fake.objects)
+}
+
+func TestS3StorePublicURL(t *testing.T) {
+	store, err := NewS3Store(S3Config{
+		Endpoint:  "http://localhost:9000",
+		Bucket:    "bucket",
+		PublicURL: "https://cdn.example.com/",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://cdn.example.com/avatars/abc/64.jpg", store.URL("avatars/abc/64.jpg"))
+
+	_, err = NewS3Store(S3Config{Endpoint: "localhost", Bucket: "bucket"})
+	require.Error(t, err)
+}
@@ -0,0 +1,32 @@
+package blob
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// get-vanilla case of the AWS Signature Version 4 test suite
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+
+	signedAt := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	signV4(req, emptyPayloadHash, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "us-east-1", "service", signedAt)
+
+	require.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	require.Equal(t,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+			"SignedHeaders=host;x-amz-date, "+
+			"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"),
+	)
+}
+
+func TestURIEncode(t *testing.T) {
+	require.Equal(t, "avatars", uriEncode("avatars"))
+	require.Equal(t, "a%20b%2Fc~d", uriEncode("a b/c~d"))
+	require.Equal(t, "/avatars/a%2Bb/64.jpg", canonicalURI("/avatars/a+b/64.jpg"))
+}
@@ -0,0 +1,18 @@
+-- First admin has to be promoted manually:
+-- UPDATE users SET role = 'admin' WHERE username = '...';
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'user';
+
+CREATE TABLE IF NOT EXISTS "invitations" (
+  "id" bigserial PRIMARY KEY,
+  "hashed_code" varchar UNIQUE NOT NULL,
+  "email" varchar,
+  "created_by" varchar NOT NULL,
+  "used_by" varchar,
+  "used_at" timestamptz,
+  "revoked_at" timestamptz,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "invitations" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+ALTER TABLE "invitations" ADD FOREIGN KEY ("used_by") REFERENCES "users" ("username");
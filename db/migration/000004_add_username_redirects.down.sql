@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS "username_redirects";
+
+ALTER TABLE "invitations" DROP CONSTRAINT "invitations_used_by_fkey";
+ALTER TABLE "invitations" ADD FOREIGN KEY ("used_by") REFERENCES "users" ("username");
+
+ALTER TABLE "invitations" DROP CONSTRAINT "invitations_created_by_fkey";
+ALTER TABLE "invitations" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+
+ALTER TABLE "recovery_codes" DROP CONSTRAINT "recovery_codes_username_fkey";
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON DELETE CASCADE;
+
+ALTER TABLE "articles" DROP CONSTRAINT "articles_author_fkey";
+ALTER TABLE "articles" ADD FOREIGN KEY ("author") REFERENCES "users" ("username");
@@ -0,0 +1,22 @@
+-- Usernames are referenced as foreign keys, so renaming a user has to cascade
+ALTER TABLE "articles" DROP CONSTRAINT "articles_author_fkey";
+ALTER TABLE "articles" ADD FOREIGN KEY ("author") REFERENCES "users" ("username") ON UPDATE CASCADE;
+
+ALTER TABLE "recovery_codes" DROP CONSTRAINT "recovery_codes_username_fkey";
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE "invitations" DROP CONSTRAINT "invitations_created_by_fkey";
+ALTER TABLE "invitations" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username") ON UPDATE CASCADE;
+
+ALTER TABLE "invitations" DROP CONSTRAINT "invitations_used_by_fkey";
+ALTER TABLE "invitations" ADD FOREIGN KEY ("used_by") REFERENCES "users" ("username") ON UPDATE CASCADE;
+
+CREATE TABLE IF NOT EXISTS "username_redirects" (
+  "old_username" varchar PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Cascading keeps older redirects pointing at the current username after consecutive renames
+ALTER TABLE "username_redirects" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+CREATE INDEX ON "username_redirects" ("username");
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "articles_author_created_at_id_idx";
+
+DROP TABLE IF EXISTS "follows";
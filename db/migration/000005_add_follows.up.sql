@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "follows" (
+  "follower" varchar NOT NULL,
+  "followee" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("follower", "followee"),
+  CHECK ("follower" <> "followee")
+);
+
+ALTER TABLE "follows" ADD FOREIGN KEY ("follower") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE "follows" ADD FOREIGN KEY ("followee") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+CREATE INDEX ON "follows" ("followee");
+
+-- Feed is read newest first with keyset pagination
+CREATE INDEX ON "articles" ("author", "created_at" DESC, "id" DESC);
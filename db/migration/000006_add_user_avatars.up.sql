@@ -0,0 +1,2 @@
+-- Avatar variants are stored under a key derived from avatar_id, empty when the user has no avatar
+ALTER TABLE "users" ADD COLUMN "avatar_id" varchar NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_email_lowercase";
+
+DROP INDEX IF EXISTS "users_lower_username_idx";
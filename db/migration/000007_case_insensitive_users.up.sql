@@ -0,0 +1,17 @@
+-- Existing accounts differing only in case can't be merged automatically
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM users GROUP BY lower(username) HAVING count(*) > 1) THEN
+    RAISE EXCEPTION 'usernames differing only in case have to be resolved before this migration';
+  END IF;
+  IF EXISTS (SELECT 1 FROM users GROUP BY lower(email) HAVING count(*) > 1) THEN
+    RAISE EXCEPTION 'emails differing only in case have to be resolved before this migration';
+  END IF;
+END $$;
+
+-- Usernames keep the case they were registered with, only their uniqueness ignores it
+CREATE UNIQUE INDEX "users_lower_username_idx" ON "users" (lower("username"));
+
+-- Emails are stored lowercase, so the existing unique constraint covers them
+UPDATE "users" SET "email" = lower("email") WHERE "email" <> lower("email");
+ALTER TABLE "users" ADD CONSTRAINT "users_email_lowercase" CHECK ("email" = lower("email"));
@@ -0,0 +1,9 @@
+-- Single-use tokens are recorded when used, so that they can't be replayed before they expire
+CREATE TABLE IF NOT EXISTS "consumed_tokens" (
+  "id" uuid PRIMARY KEY,
+  "purpose" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "consumed_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "consumed_tokens" ("expires_at");
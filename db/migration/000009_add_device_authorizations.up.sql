@@ -0,0 +1,15 @@
+-- Device authorization grant (RFC 8628) for clients that can't show a login form
+CREATE TABLE IF NOT EXISTS "device_authorizations" (
+  "id" bigserial PRIMARY KEY,
+  "hashed_device_code" varchar UNIQUE NOT NULL,
+  "user_code" varchar UNIQUE NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "username" varchar,
+  "poll_interval" integer NOT NULL,
+  "last_polled_at" timestamptz,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "device_authorizations" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+CREATE INDEX ON "device_authorizations" ("expires_at");
@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "disabled_at" timestamptz;
+-- Set by admins, the user can't log in until the password is reset through the emailed link
+ALTER TABLE "users" ADD COLUMN "password_reset_required" boolean NOT NULL DEFAULT false;
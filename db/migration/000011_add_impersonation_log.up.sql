@@ -0,0 +1,15 @@
+-- There are no foreign keys on purpose, entries have to outlive renamed and deleted users
+CREATE TABLE IF NOT EXISTS "impersonation_log" (
+  "id" bigserial PRIMARY KEY,
+  "session_id" uuid NOT NULL,
+  "impersonator" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "method" varchar NOT NULL,
+  "path" varchar NOT NULL,
+  "status" integer NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "impersonation_log" ("impersonator");
+CREATE INDEX ON "impersonation_log" ("username");
@@ -0,0 +1,13 @@
+-- Attempts for unknown logins are recorded too, without the username
+CREATE TABLE IF NOT EXISTS "login_events" (
+  "id" bigserial PRIMARY KEY,
+  "login" varchar NOT NULL,
+  "username" varchar,
+  "outcome" varchar NOT NULL,
+  "ip" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "login_events" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+CREATE INDEX ON "login_events" ("username", "created_at");
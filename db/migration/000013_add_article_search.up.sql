@@ -0,0 +1,6 @@
+-- The text search configuration is replaced by a per article one in 000020_add_article_search_language
+ALTER TABLE "articles" ADD COLUMN "search_vector" tsvector NOT NULL GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', "headline"), 'A') || setweight(to_tsvector('english', "content"), 'B')
+) STORED;
+
+CREATE INDEX ON "articles" USING GIN ("search_vector");
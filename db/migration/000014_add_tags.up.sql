@@ -0,0 +1,16 @@
+-- Names are normalized slugs, e.g. "Go Modules" is stored as "go-modules"
+CREATE TABLE IF NOT EXISTS "tags" (
+  "id" bigserial PRIMARY KEY,
+  "name" varchar UNIQUE NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE IF NOT EXISTS "article_tags" (
+  "article_id" bigint NOT NULL,
+  "tag_id" bigint NOT NULL,
+  PRIMARY KEY ("article_id", "tag_id")
+);
+
+ALTER TABLE "article_tags" ADD FOREIGN KEY ("article_id") REFERENCES "articles" ("id") ON DELETE CASCADE;
+ALTER TABLE "article_tags" ADD FOREIGN KEY ("tag_id") REFERENCES "tags" ("id") ON DELETE CASCADE;
+CREATE INDEX ON "article_tags" ("tag_id");
@@ -0,0 +1,17 @@
+-- Replies point to their parent comment. Deleted comments keep their row, so that the replies stay in the thread
+CREATE TABLE IF NOT EXISTS "comments" (
+  "id" bigserial PRIMARY KEY,
+  "article_id" bigint NOT NULL,
+  "parent_id" bigint,
+  "author" varchar NOT NULL,
+  "content" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "edited_at" timestamptz,
+  "deleted_at" timestamptz
+);
+
+ALTER TABLE "comments" ADD FOREIGN KEY ("article_id") REFERENCES "articles" ("id") ON DELETE CASCADE;
+ALTER TABLE "comments" ADD FOREIGN KEY ("parent_id") REFERENCES "comments" ("id");
+ALTER TABLE "comments" ADD FOREIGN KEY ("author") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+CREATE INDEX ON "comments" ("article_id", "created_at");
+CREATE INDEX ON "comments" ("parent_id");
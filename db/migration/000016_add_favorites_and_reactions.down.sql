@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "article_reactions";
+DROP TABLE IF EXISTS "favorites";
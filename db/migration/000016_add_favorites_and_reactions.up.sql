@@ -0,0 +1,24 @@
+-- Counts are computed from these rows. A reader can favorite an article and leave each reaction only once,
+-- so repeated or concurrent clicks can't change the counts by more than one
+CREATE TABLE IF NOT EXISTS "favorites" (
+  "username" varchar NOT NULL,
+  "article_id" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("username", "article_id")
+);
+
+ALTER TABLE "favorites" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE "favorites" ADD FOREIGN KEY ("article_id") REFERENCES "articles" ("id") ON DELETE CASCADE;
+CREATE INDEX ON "favorites" ("article_id");
+CREATE INDEX ON "favorites" ("username", "created_at");
+
+CREATE TABLE IF NOT EXISTS "article_reactions" (
+  "username" varchar NOT NULL,
+  "article_id" bigint NOT NULL,
+  "reaction" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("article_id", "username", "reaction")
+);
+
+ALTER TABLE "article_reactions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+ALTER TABLE "article_reactions" ADD FOREIGN KEY ("article_id") REFERENCES "articles" ("id") ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+ALTER TABLE "articles" DROP COLUMN IF EXISTS "publish_at";
+ALTER TABLE "articles" DROP COLUMN IF EXISTS "status";
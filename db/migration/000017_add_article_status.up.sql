@@ -0,0 +1,10 @@
+-- Articles written so far stay published, new ones start as drafts
+ALTER TABLE "articles" ADD COLUMN "status" varchar NOT NULL DEFAULT 'published'
+  CHECK ("status" IN ('draft', 'published', 'scheduled', 'archived'));
+ALTER TABLE "articles" ALTER COLUMN "status" SET DEFAULT 'draft';
+-- When a scheduled article is due, or when a published or archived one was published.
+-- Existing articles were published when they were created
+ALTER TABLE "articles" ADD COLUMN "publish_at" timestamptz;
+UPDATE "articles" SET "publish_at" = "created_at";
+
+CREATE INDEX ON "articles" ("publish_at") WHERE "status" = 'scheduled';
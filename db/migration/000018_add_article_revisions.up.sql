@@ -0,0 +1,19 @@
+-- Every version of the text of an article, the latest one is the current text
+CREATE TABLE IF NOT EXISTS "article_revisions" (
+  "article_id" bigint NOT NULL,
+  "revision" int NOT NULL,
+  "headline" varchar NOT NULL,
+  "content" varchar NOT NULL,
+  "editor" varchar NOT NULL,
+  -- The revision that was restored, if this one is a restore
+  "restored_from" int,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("article_id", "revision")
+);
+
+ALTER TABLE "article_revisions" ADD FOREIGN KEY ("article_id") REFERENCES "articles" ("id") ON DELETE CASCADE;
+ALTER TABLE "article_revisions" ADD FOREIGN KEY ("editor") REFERENCES "users" ("username") ON UPDATE CASCADE ON DELETE CASCADE;
+
+-- History of the articles written so far starts with their current text
+INSERT INTO "article_revisions" ("article_id", "revision", "headline", "content", "editor", "created_at")
+SELECT "id", 1, "headline", "content", "author", COALESCE("edited_at", "created_at") FROM "articles";
@@ -0,0 +1,2 @@
+-- Incremented by every update, so that clients can tell whether the article changed since they read it
+ALTER TABLE "articles" ADD COLUMN "version" int NOT NULL DEFAULT 1;
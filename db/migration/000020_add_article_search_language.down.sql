@@ -0,0 +1,8 @@
+ALTER TABLE "articles" DROP COLUMN IF EXISTS "search_vector";
+ALTER TABLE "articles" ADD COLUMN "search_vector" tsvector NOT NULL GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', "headline"), 'A') || setweight(to_tsvector('english', "content"), 'B')
+) STORED;
+
+CREATE INDEX ON "articles" USING GIN ("search_vector");
+
+ALTER TABLE "articles" DROP COLUMN IF EXISTS "search_language";
@@ -0,0 +1,10 @@
+-- Articles are indexed with the configuration they were written under, which has to be immutable
+-- for a generated column. Existing ones were indexed with english
+ALTER TABLE "articles" ADD COLUMN "search_language" regconfig NOT NULL DEFAULT 'english';
+
+ALTER TABLE "articles" DROP COLUMN "search_vector";
+ALTER TABLE "articles" ADD COLUMN "search_vector" tsvector NOT NULL GENERATED ALWAYS AS (
+  setweight(to_tsvector("search_language", "headline"), 'A') || setweight(to_tsvector("search_language", "content"), 'B')
+) STORED;
+
+CREATE INDEX ON "articles" USING GIN ("search_vector");
@@ -35,6 +35,78 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// AddArticleTags mocks base method.
+func (m *MockStore) AddArticleTags(arg0 context.Context, arg1 db.AddArticleTagsParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddArticleTags", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddArticleTags indicates an expected call of AddArticleTags.
+func (mr *MockStoreMockRecorder) AddArticleTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddArticleTags", reflect.TypeOf((*MockStore)(nil).AddArticleTags), arg0, arg1)
+}
+
+// AddFavorite mocks base method.
+func (m *MockStore) AddFavorite(arg0 context.Context, arg1 db.AddFavoriteParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddFavorite", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddFavorite indicates an expected call of AddFavorite.
+func (mr *MockStoreMockRecorder) AddFavorite(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFavorite", reflect.TypeOf((*MockStore)(nil).AddFavorite), arg0, arg1)
+}
+
+// AddReaction mocks base method.
+func (m *MockStore) AddReaction(arg0 context.Context, arg1 db.AddReactionParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReaction", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddReaction indicates an expected call of AddReaction.
+func (mr *MockStoreMockRecorder) AddReaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReaction", reflect.TypeOf((*MockStore)(nil).AddReaction), arg0, arg1)
+}
+
+// ChangeArticleStatus mocks base method.
+func (m *MockStore) ChangeArticleStatus(arg0 context.Context, arg1 db.ChangeArticleStatusParams) (db.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeArticleStatus", arg0, arg1)
+	ret0, _ := ret[0].(db.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeArticleStatus indicates an expected call of ChangeArticleStatus.
+func (mr *MockStoreMockRecorder) ChangeArticleStatus(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeArticleStatus", reflect.TypeOf((*MockStore)(nil).ChangeArticleStatus), arg0, arg1)
+}
+
+// ChangeUsernameTx mocks base method.
+func (m *MockStore) ChangeUsernameTx(arg0 context.Context, arg1 db.ChangeUsernameTxParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeUsernameTx", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeUsernameTx indicates an expected call of ChangeUsernameTx.
+func (mr *MockStoreMockRecorder) ChangeUsernameTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUsernameTx", reflect.TypeOf((*MockStore)(nil).ChangeUsernameTx), arg0, arg1)
+}
+
 // Close mocks base method.
 func (m *MockStore) Close() error {
 	m.ctrl.T.Helper()
@@ -49,6 +121,81 @@ func (mr *MockStoreMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStore)(nil).Close))
 }
 
+// ConsumeToken mocks base method.
+func (m *MockStore) ConsumeToken(arg0 context.Context, arg1 db.ConsumeTokenParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeToken", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeToken indicates an expected call of ConsumeToken.
+func (mr *MockStoreMockRecorder) ConsumeToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeToken", reflect.TypeOf((*MockStore)(nil).ConsumeToken), arg0, arg1)
+}
+
+// CountArticles mocks base method.
+func (m *MockStore) CountArticles(arg0 context.Context, arg1 db.ListArticlesParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountArticles", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountArticles indicates an expected call of CountArticles.
+func (mr *MockStoreMockRecorder) CountArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountArticles", reflect.TypeOf((*MockStore)(nil).CountArticles), arg0, arg1)
+}
+
+// CountCommentsOfArticles mocks base method.
+func (m *MockStore) CountCommentsOfArticles(arg0 context.Context, arg1 []int64) ([]db.CountCommentsOfArticlesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountCommentsOfArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.CountCommentsOfArticlesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountCommentsOfArticles indicates an expected call of CountCommentsOfArticles.
+func (mr *MockStoreMockRecorder) CountCommentsOfArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountCommentsOfArticles", reflect.TypeOf((*MockStore)(nil).CountCommentsOfArticles), arg0, arg1)
+}
+
+// CountFavoritesOfArticles mocks base method.
+func (m *MockStore) CountFavoritesOfArticles(arg0 context.Context, arg1 db.CountFavoritesOfArticlesParams) ([]db.CountFavoritesOfArticlesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountFavoritesOfArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.CountFavoritesOfArticlesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountFavoritesOfArticles indicates an expected call of CountFavoritesOfArticles.
+func (mr *MockStoreMockRecorder) CountFavoritesOfArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountFavoritesOfArticles", reflect.TypeOf((*MockStore)(nil).CountFavoritesOfArticles), arg0, arg1)
+}
+
+// CountReactionsOfArticles mocks base method.
+func (m *MockStore) CountReactionsOfArticles(arg0 context.Context, arg1 db.CountReactionsOfArticlesParams) ([]db.CountReactionsOfArticlesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountReactionsOfArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.CountReactionsOfArticlesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountReactionsOfArticles indicates an expected call of CountReactionsOfArticles.
+func (mr *MockStoreMockRecorder) CountReactionsOfArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountReactionsOfArticles", reflect.TypeOf((*MockStore)(nil).CountReactionsOfArticles), arg0, arg1)
+}
+
 // CreateArticle mocks base method.
 func (m *MockStore) CreateArticle(arg0 context.Context, arg1 db.CreateArticleParams) (db.Article, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +211,138 @@ func (mr *MockStoreMockRecorder) CreateArticle(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateArticle", reflect.TypeOf((*MockStore)(nil).CreateArticle), arg0, arg1)
 }
 
+// CreateArticleRevision mocks base method.
+func (m *MockStore) CreateArticleRevision(arg0 context.Context, arg1 db.CreateArticleRevisionParams) (db.ArticleRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateArticleRevision", arg0, arg1)
+	ret0, _ := ret[0].(db.ArticleRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateArticleRevision indicates an expected call of CreateArticleRevision.
+func (mr *MockStoreMockRecorder) CreateArticleRevision(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateArticleRevision", reflect.TypeOf((*MockStore)(nil).CreateArticleRevision), arg0, arg1)
+}
+
+// CreateArticleTx mocks base method.
+func (m *MockStore) CreateArticleTx(arg0 context.Context, arg1 db.CreateArticleTxParams) (db.ArticleTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateArticleTx", arg0, arg1)
+	ret0, _ := ret[0].(db.ArticleTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateArticleTx indicates an expected call of CreateArticleTx.
+func (mr *MockStoreMockRecorder) CreateArticleTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateArticleTx", reflect.TypeOf((*MockStore)(nil).CreateArticleTx), arg0, arg1)
+}
+
+// CreateComment mocks base method.
+func (m *MockStore) CreateComment(arg0 context.Context, arg1 db.CreateCommentParams) (db.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateComment", arg0, arg1)
+	ret0, _ := ret[0].(db.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateComment indicates an expected call of CreateComment.
+func (mr *MockStoreMockRecorder) CreateComment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateComment", reflect.TypeOf((*MockStore)(nil).CreateComment), arg0, arg1)
+}
+
+// CreateDeviceAuthorization mocks base method.
+func (m *MockStore) CreateDeviceAuthorization(arg0 context.Context, arg1 db.CreateDeviceAuthorizationParams) (db.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeviceAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(db.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDeviceAuthorization indicates an expected call of CreateDeviceAuthorization.
+func (mr *MockStoreMockRecorder) CreateDeviceAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeviceAuthorization", reflect.TypeOf((*MockStore)(nil).CreateDeviceAuthorization), arg0, arg1)
+}
+
+// CreateFollow mocks base method.
+func (m *MockStore) CreateFollow(arg0 context.Context, arg1 db.CreateFollowParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFollow", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateFollow indicates an expected call of CreateFollow.
+func (mr *MockStoreMockRecorder) CreateFollow(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFollow", reflect.TypeOf((*MockStore)(nil).CreateFollow), arg0, arg1)
+}
+
+// CreateImpersonationLogEntry mocks base method.
+func (m *MockStore) CreateImpersonationLogEntry(arg0 context.Context, arg1 db.CreateImpersonationLogEntryParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImpersonationLogEntry", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateImpersonationLogEntry indicates an expected call of CreateImpersonationLogEntry.
+func (mr *MockStoreMockRecorder) CreateImpersonationLogEntry(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImpersonationLogEntry", reflect.TypeOf((*MockStore)(nil).CreateImpersonationLogEntry), arg0, arg1)
+}
+
+// CreateInvitation mocks base method.
+func (m *MockStore) CreateInvitation(arg0 context.Context, arg1 db.CreateInvitationParams) (db.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInvitation", arg0, arg1)
+	ret0, _ := ret[0].(db.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateInvitation indicates an expected call of CreateInvitation.
+func (mr *MockStoreMockRecorder) CreateInvitation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvitation", reflect.TypeOf((*MockStore)(nil).CreateInvitation), arg0, arg1)
+}
+
+// CreateLoginEvent mocks base method.
+func (m *MockStore) CreateLoginEvent(arg0 context.Context, arg1 db.CreateLoginEventParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginEvent", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLoginEvent indicates an expected call of CreateLoginEvent.
+func (mr *MockStoreMockRecorder) CreateLoginEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginEvent", reflect.TypeOf((*MockStore)(nil).CreateLoginEvent), arg0, arg1)
+}
+
+// CreateRecoveryCode mocks base method.
+func (m *MockStore) CreateRecoveryCode(arg0 context.Context, arg1 db.CreateRecoveryCodeParams) (db.RecoveryCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecoveryCode", arg0, arg1)
+	ret0, _ := ret[0].(db.RecoveryCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRecoveryCode indicates an expected call of CreateRecoveryCode.
+func (mr *MockStoreMockRecorder) CreateRecoveryCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecoveryCode", reflect.TypeOf((*MockStore)(nil).CreateRecoveryCode), arg0, arg1)
+}
+
 // CreateUser mocks base method.
 func (m *MockStore) CreateUser(arg0 context.Context, arg1 db.CreateUserParams) (db.User, error) {
 	m.ctrl.T.Helper()
@@ -79,76 +358,968 @@ func (mr *MockStoreMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStore)(nil).CreateUser), arg0, arg1)
 }
 
-// DeleteArticle mocks base method.
-func (m *MockStore) DeleteArticle(arg0 context.Context, arg1 int64) error {
+// CreateUserTx mocks base method.
+func (m *MockStore) CreateUserTx(arg0 context.Context, arg1 db.CreateUserTxParams) (db.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteArticle", arg0, arg1)
+	ret := m.ctrl.Call(m, "CreateUserTx", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUserTx indicates an expected call of CreateUserTx.
+func (mr *MockStoreMockRecorder) CreateUserTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserTx", reflect.TypeOf((*MockStore)(nil).CreateUserTx), arg0, arg1)
+}
+
+// CreateUsernameRedirect mocks base method.
+func (m *MockStore) CreateUsernameRedirect(arg0 context.Context, arg1 db.CreateUsernameRedirectParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUsernameRedirect", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// CreateUsernameRedirect indicates an expected call of CreateUsernameRedirect.
+func (mr *MockStoreMockRecorder) CreateUsernameRedirect(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUsernameRedirect", reflect.TypeOf((*MockStore)(nil).CreateUsernameRedirect), arg0, arg1)
+}
+
+// DecideDeviceAuthorization mocks base method.
+func (m *MockStore) DecideDeviceAuthorization(arg0 context.Context, arg1 db.DecideDeviceAuthorizationParams) (db.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecideDeviceAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(db.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecideDeviceAuthorization indicates an expected call of DecideDeviceAuthorization.
+func (mr *MockStoreMockRecorder) DecideDeviceAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecideDeviceAuthorization", reflect.TypeOf((*MockStore)(nil).DecideDeviceAuthorization), arg0, arg1)
+}
+
+// DeleteArticle mocks base method.
+func (m *MockStore) DeleteArticle(arg0 context.Context, arg1 db.DeleteArticleParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteArticle", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // DeleteArticle indicates an expected call of DeleteArticle.
 func (mr *MockStoreMockRecorder) DeleteArticle(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteArticle", reflect.TypeOf((*MockStore)(nil).DeleteArticle), arg0, arg1)
 }
 
-// GetArticle mocks base method.
-func (m *MockStore) GetArticle(arg0 context.Context, arg1 int64) (db.Article, error) {
+// DeleteArticleTags mocks base method.
+func (m *MockStore) DeleteArticleTags(arg0 context.Context, arg1 int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetArticle", arg0, arg1)
-	ret0, _ := ret[0].(db.Article)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "DeleteArticleTags", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetArticle indicates an expected call of GetArticle.
-func (mr *MockStoreMockRecorder) GetArticle(arg0, arg1 interface{}) *gomock.Call {
+// DeleteArticleTags indicates an expected call of DeleteArticleTags.
+func (mr *MockStoreMockRecorder) DeleteArticleTags(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArticle", reflect.TypeOf((*MockStore)(nil).GetArticle), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteArticleTags", reflect.TypeOf((*MockStore)(nil).DeleteArticleTags), arg0, arg1)
 }
 
-// GetUser mocks base method.
-func (m *MockStore) GetUser(arg0 context.Context, arg1 string) (db.User, error) {
+// DeleteComment mocks base method.
+func (m *MockStore) DeleteComment(arg0 context.Context, arg1 int64) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
-	ret0, _ := ret[0].(db.User)
+	ret := m.ctrl.Call(m, "DeleteComment", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUser indicates an expected call of GetUser.
-func (mr *MockStoreMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
+// DeleteComment indicates an expected call of DeleteComment.
+func (mr *MockStoreMockRecorder) DeleteComment(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockStore)(nil).GetUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteComment", reflect.TypeOf((*MockStore)(nil).DeleteComment), arg0, arg1)
 }
 
-// ListArticles mocks base method.
-func (m *MockStore) ListArticles(arg0 context.Context, arg1 db.ListArticlesParams) ([]db.Article, error) {
+// DeleteDeviceAuthorization mocks base method.
+func (m *MockStore) DeleteDeviceAuthorization(arg0 context.Context, arg1 int64) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListArticles", arg0, arg1)
-	ret0, _ := ret[0].([]db.Article)
+	ret := m.ctrl.Call(m, "DeleteDeviceAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListArticles indicates an expected call of ListArticles.
-func (mr *MockStoreMockRecorder) ListArticles(arg0, arg1 interface{}) *gomock.Call {
+// DeleteDeviceAuthorization indicates an expected call of DeleteDeviceAuthorization.
+func (mr *MockStoreMockRecorder) DeleteDeviceAuthorization(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListArticles", reflect.TypeOf((*MockStore)(nil).ListArticles), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDeviceAuthorization", reflect.TypeOf((*MockStore)(nil).DeleteDeviceAuthorization), arg0, arg1)
 }
 
-// UpdateArticle mocks base method.
-func (m *MockStore) UpdateArticle(arg0 context.Context, arg1 db.UpdateArticleParams) (db.Article, error) {
+// DeleteFavorite mocks base method.
+func (m *MockStore) DeleteFavorite(arg0 context.Context, arg1 db.DeleteFavoriteParams) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateArticle", arg0, arg1)
-	ret0, _ := ret[0].(db.Article)
+	ret := m.ctrl.Call(m, "DeleteFavorite", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFavorite indicates an expected call of DeleteFavorite.
+func (mr *MockStoreMockRecorder) DeleteFavorite(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFavorite", reflect.TypeOf((*MockStore)(nil).DeleteFavorite), arg0, arg1)
+}
+
+// DeleteFollow mocks base method.
+func (m *MockStore) DeleteFollow(arg0 context.Context, arg1 db.DeleteFollowParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFollow", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFollow indicates an expected call of DeleteFollow.
+func (mr *MockStoreMockRecorder) DeleteFollow(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFollow", reflect.TypeOf((*MockStore)(nil).DeleteFollow), arg0, arg1)
+}
+
+// DeleteReaction mocks base method.
+func (m *MockStore) DeleteReaction(arg0 context.Context, arg1 db.DeleteReactionParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteReaction", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteReaction indicates an expected call of DeleteReaction.
+func (mr *MockStoreMockRecorder) DeleteReaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteReaction", reflect.TypeOf((*MockStore)(nil).DeleteReaction), arg0, arg1)
+}
+
+// DeleteRecoveryCodes mocks base method.
+func (m *MockStore) DeleteRecoveryCodes(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecoveryCodes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecoveryCodes indicates an expected call of DeleteRecoveryCodes.
+func (mr *MockStoreMockRecorder) DeleteRecoveryCodes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecoveryCodes", reflect.TypeOf((*MockStore)(nil).DeleteRecoveryCodes), arg0, arg1)
+}
+
+// DeleteUsernameRedirect mocks base method.
+func (m *MockStore) DeleteUsernameRedirect(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUsernameRedirect", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUsernameRedirect indicates an expected call of DeleteUsernameRedirect.
+func (mr *MockStoreMockRecorder) DeleteUsernameRedirect(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUsernameRedirect", reflect.TypeOf((*MockStore)(nil).DeleteUsernameRedirect), arg0, arg1)
+}
+
+// DisableTOTPTx mocks base method.
+func (m *MockStore) DisableTOTPTx(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTPTx", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// UpdateArticle indicates an expected call of UpdateArticle.
-func (mr *MockStoreMockRecorder) UpdateArticle(arg0, arg1 interface{}) *gomock.Call {
+// DisableTOTPTx indicates an expected call of DisableTOTPTx.
+func (mr *MockStoreMockRecorder) DisableTOTPTx(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateArticle", reflect.TypeOf((*MockStore)(nil).UpdateArticle), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTPTx", reflect.TypeOf((*MockStore)(nil).DisableTOTPTx), arg0, arg1)
+}
+
+// DisableUser mocks base method.
+func (m *MockStore) DisableUser(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUser", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisableUser indicates an expected call of DisableUser.
+func (mr *MockStoreMockRecorder) DisableUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUser", reflect.TypeOf((*MockStore)(nil).DisableUser), arg0, arg1)
+}
+
+// DisableUserTOTP mocks base method.
+func (m *MockStore) DisableUserTOTP(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUserTOTP", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisableUserTOTP indicates an expected call of DisableUserTOTP.
+func (mr *MockStoreMockRecorder) DisableUserTOTP(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUserTOTP", reflect.TypeOf((*MockStore)(nil).DisableUserTOTP), arg0, arg1)
+}
+
+// EnableTOTPTx mocks base method.
+func (m *MockStore) EnableTOTPTx(arg0 context.Context, arg1 db.EnableTOTPTxParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTPTx", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableTOTPTx indicates an expected call of EnableTOTPTx.
+func (mr *MockStoreMockRecorder) EnableTOTPTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTPTx", reflect.TypeOf((*MockStore)(nil).EnableTOTPTx), arg0, arg1)
+}
+
+// EnableUser mocks base method.
+func (m *MockStore) EnableUser(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableUser", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableUser indicates an expected call of EnableUser.
+func (mr *MockStoreMockRecorder) EnableUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableUser", reflect.TypeOf((*MockStore)(nil).EnableUser), arg0, arg1)
+}
+
+// EnableUserTOTP mocks base method.
+func (m *MockStore) EnableUserTOTP(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableUserTOTP", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableUserTOTP indicates an expected call of EnableUserTOTP.
+func (mr *MockStoreMockRecorder) EnableUserTOTP(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableUserTOTP", reflect.TypeOf((*MockStore)(nil).EnableUserTOTP), arg0, arg1)
+}
+
+// GetArticle mocks base method.
+func (m *MockStore) GetArticle(arg0 context.Context, arg1 int64) (db.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArticle", arg0, arg1)
+	ret0, _ := ret[0].(db.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArticle indicates an expected call of GetArticle.
+func (mr *MockStoreMockRecorder) GetArticle(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArticle", reflect.TypeOf((*MockStore)(nil).GetArticle), arg0, arg1)
+}
+
+// GetArticleRevision mocks base method.
+func (m *MockStore) GetArticleRevision(arg0 context.Context, arg1 db.GetArticleRevisionParams) (db.ArticleRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetArticleRevision", arg0, arg1)
+	ret0, _ := ret[0].(db.ArticleRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetArticleRevision indicates an expected call of GetArticleRevision.
+func (mr *MockStoreMockRecorder) GetArticleRevision(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetArticleRevision", reflect.TypeOf((*MockStore)(nil).GetArticleRevision), arg0, arg1)
+}
+
+// GetAuthor mocks base method.
+func (m *MockStore) GetAuthor(arg0 context.Context, arg1 string) (db.GetAuthorRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuthor", arg0, arg1)
+	ret0, _ := ret[0].(db.GetAuthorRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthor indicates an expected call of GetAuthor.
+func (mr *MockStoreMockRecorder) GetAuthor(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthor", reflect.TypeOf((*MockStore)(nil).GetAuthor), arg0, arg1)
+}
+
+// GetComment mocks base method.
+func (m *MockStore) GetComment(arg0 context.Context, arg1 int64) (db.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetComment", arg0, arg1)
+	ret0, _ := ret[0].(db.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetComment indicates an expected call of GetComment.
+func (mr *MockStoreMockRecorder) GetComment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetComment", reflect.TypeOf((*MockStore)(nil).GetComment), arg0, arg1)
+}
+
+// GetDeviceAuthorization mocks base method.
+func (m *MockStore) GetDeviceAuthorization(arg0 context.Context, arg1 string) (db.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(db.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceAuthorization indicates an expected call of GetDeviceAuthorization.
+func (mr *MockStoreMockRecorder) GetDeviceAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceAuthorization", reflect.TypeOf((*MockStore)(nil).GetDeviceAuthorization), arg0, arg1)
+}
+
+// GetLoginDeviceHistory mocks base method.
+func (m *MockStore) GetLoginDeviceHistory(arg0 context.Context, arg1 db.GetLoginDeviceHistoryParams) (db.GetLoginDeviceHistoryRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginDeviceHistory", arg0, arg1)
+	ret0, _ := ret[0].(db.GetLoginDeviceHistoryRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLoginDeviceHistory indicates an expected call of GetLoginDeviceHistory.
+func (mr *MockStoreMockRecorder) GetLoginDeviceHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginDeviceHistory", reflect.TypeOf((*MockStore)(nil).GetLoginDeviceHistory), arg0, arg1)
+}
+
+// GetUser mocks base method.
+func (m *MockStore) GetUser(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockStoreMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockStore)(nil).GetUser), arg0, arg1)
+}
+
+// GetUserByLogin mocks base method.
+func (m *MockStore) GetUserByLogin(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByLogin", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByLogin indicates an expected call of GetUserByLogin.
+func (mr *MockStoreMockRecorder) GetUserByLogin(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLogin", reflect.TypeOf((*MockStore)(nil).GetUserByLogin), arg0, arg1)
+}
+
+// GetUsernameRedirect mocks base method.
+func (m *MockStore) GetUsernameRedirect(arg0 context.Context, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsernameRedirect", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsernameRedirect indicates an expected call of GetUsernameRedirect.
+func (mr *MockStoreMockRecorder) GetUsernameRedirect(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsernameRedirect", reflect.TypeOf((*MockStore)(nil).GetUsernameRedirect), arg0, arg1)
+}
+
+// ListArticleRevisions mocks base method.
+func (m *MockStore) ListArticleRevisions(arg0 context.Context, arg1 db.ListArticleRevisionsParams) ([]db.ListArticleRevisionsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListArticleRevisions", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListArticleRevisionsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListArticleRevisions indicates an expected call of ListArticleRevisions.
+func (mr *MockStoreMockRecorder) ListArticleRevisions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListArticleRevisions", reflect.TypeOf((*MockStore)(nil).ListArticleRevisions), arg0, arg1)
+}
+
+// ListArticles mocks base method.
+func (m *MockStore) ListArticles(arg0 context.Context, arg1 db.ListArticlesParams) ([]db.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListArticles indicates an expected call of ListArticles.
+func (mr *MockStoreMockRecorder) ListArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListArticles", reflect.TypeOf((*MockStore)(nil).ListArticles), arg0, arg1)
+}
+
+// ListAuthors mocks base method.
+func (m *MockStore) ListAuthors(arg0 context.Context, arg1 db.ListAuthorsParams) ([]db.ListAuthorsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuthors", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListAuthorsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuthors indicates an expected call of ListAuthors.
+func (mr *MockStoreMockRecorder) ListAuthors(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuthors", reflect.TypeOf((*MockStore)(nil).ListAuthors), arg0, arg1)
+}
+
+// ListCommentReplies mocks base method.
+func (m *MockStore) ListCommentReplies(arg0 context.Context, arg1 []int64) ([]db.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCommentReplies", arg0, arg1)
+	ret0, _ := ret[0].([]db.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCommentReplies indicates an expected call of ListCommentReplies.
+func (mr *MockStoreMockRecorder) ListCommentReplies(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCommentReplies", reflect.TypeOf((*MockStore)(nil).ListCommentReplies), arg0, arg1)
+}
+
+// ListComments mocks base method.
+func (m *MockStore) ListComments(arg0 context.Context, arg1 db.ListCommentsParams) ([]db.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListComments", arg0, arg1)
+	ret0, _ := ret[0].([]db.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListComments indicates an expected call of ListComments.
+func (mr *MockStoreMockRecorder) ListComments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListComments", reflect.TypeOf((*MockStore)(nil).ListComments), arg0, arg1)
+}
+
+// ListFavoriteArticles mocks base method.
+func (m *MockStore) ListFavoriteArticles(arg0 context.Context, arg1 db.ListFavoriteArticlesParams) ([]db.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFavoriteArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFavoriteArticles indicates an expected call of ListFavoriteArticles.
+func (mr *MockStoreMockRecorder) ListFavoriteArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFavoriteArticles", reflect.TypeOf((*MockStore)(nil).ListFavoriteArticles), arg0, arg1)
+}
+
+// ListFeedArticles mocks base method.
+func (m *MockStore) ListFeedArticles(arg0 context.Context, arg1 db.ListFeedArticlesParams) ([]db.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFeedArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFeedArticles indicates an expected call of ListFeedArticles.
+func (mr *MockStoreMockRecorder) ListFeedArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFeedArticles", reflect.TypeOf((*MockStore)(nil).ListFeedArticles), arg0, arg1)
+}
+
+// ListFollowers mocks base method.
+func (m *MockStore) ListFollowers(arg0 context.Context, arg1 db.ListFollowersParams) ([]db.ListFollowersRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFollowers", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListFollowersRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFollowers indicates an expected call of ListFollowers.
+func (mr *MockStoreMockRecorder) ListFollowers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFollowers", reflect.TypeOf((*MockStore)(nil).ListFollowers), arg0, arg1)
+}
+
+// ListFollowing mocks base method.
+func (m *MockStore) ListFollowing(arg0 context.Context, arg1 db.ListFollowingParams) ([]db.ListFollowingRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFollowing", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListFollowingRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFollowing indicates an expected call of ListFollowing.
+func (mr *MockStoreMockRecorder) ListFollowing(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFollowing", reflect.TypeOf((*MockStore)(nil).ListFollowing), arg0, arg1)
+}
+
+// ListImpersonationLog mocks base method.
+func (m *MockStore) ListImpersonationLog(arg0 context.Context, arg1 db.ListImpersonationLogParams) ([]db.ImpersonationLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImpersonationLog", arg0, arg1)
+	ret0, _ := ret[0].([]db.ImpersonationLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImpersonationLog indicates an expected call of ListImpersonationLog.
+func (mr *MockStoreMockRecorder) ListImpersonationLog(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImpersonationLog", reflect.TypeOf((*MockStore)(nil).ListImpersonationLog), arg0, arg1)
+}
+
+// ListInvitations mocks base method.
+func (m *MockStore) ListInvitations(arg0 context.Context, arg1 db.ListInvitationsParams) ([]db.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListInvitations", arg0, arg1)
+	ret0, _ := ret[0].([]db.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListInvitations indicates an expected call of ListInvitations.
+func (mr *MockStoreMockRecorder) ListInvitations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInvitations", reflect.TypeOf((*MockStore)(nil).ListInvitations), arg0, arg1)
+}
+
+// ListLoginEvents mocks base method.
+func (m *MockStore) ListLoginEvents(arg0 context.Context, arg1 db.ListLoginEventsParams) ([]db.LoginEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoginEvents", arg0, arg1)
+	ret0, _ := ret[0].([]db.LoginEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLoginEvents indicates an expected call of ListLoginEvents.
+func (mr *MockStoreMockRecorder) ListLoginEvents(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoginEvents", reflect.TypeOf((*MockStore)(nil).ListLoginEvents), arg0, arg1)
+}
+
+// ListPublicArticles mocks base method.
+func (m *MockStore) ListPublicArticles(arg0 context.Context, arg1 db.ListPublicArticlesParams) ([]db.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPublicArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPublicArticles indicates an expected call of ListPublicArticles.
+func (mr *MockStoreMockRecorder) ListPublicArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPublicArticles", reflect.TypeOf((*MockStore)(nil).ListPublicArticles), arg0, arg1)
+}
+
+// ListRootComments mocks base method.
+func (m *MockStore) ListRootComments(arg0 context.Context, arg1 db.ListRootCommentsParams) ([]db.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRootComments", arg0, arg1)
+	ret0, _ := ret[0].([]db.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRootComments indicates an expected call of ListRootComments.
+func (mr *MockStoreMockRecorder) ListRootComments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRootComments", reflect.TypeOf((*MockStore)(nil).ListRootComments), arg0, arg1)
+}
+
+// ListTags mocks base method.
+func (m *MockStore) ListTags(arg0 context.Context, arg1 db.ListTagsParams) ([]db.ListTagsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTags", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListTagsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTags indicates an expected call of ListTags.
+func (mr *MockStoreMockRecorder) ListTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTags", reflect.TypeOf((*MockStore)(nil).ListTags), arg0, arg1)
+}
+
+// ListTagsOfArticles mocks base method.
+func (m *MockStore) ListTagsOfArticles(arg0 context.Context, arg1 []int64) ([]db.ListTagsOfArticlesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTagsOfArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListTagsOfArticlesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTagsOfArticles indicates an expected call of ListTagsOfArticles.
+func (mr *MockStoreMockRecorder) ListTagsOfArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTagsOfArticles", reflect.TypeOf((*MockStore)(nil).ListTagsOfArticles), arg0, arg1)
+}
+
+// ListUserAccess mocks base method.
+func (m *MockStore) ListUserAccess(arg0 context.Context, arg1 []string) ([]db.ListUserAccessRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserAccess", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListUserAccessRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserAccess indicates an expected call of ListUserAccess.
+func (mr *MockStoreMockRecorder) ListUserAccess(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserAccess", reflect.TypeOf((*MockStore)(nil).ListUserAccess), arg0, arg1)
+}
+
+// ListUsers mocks base method.
+func (m *MockStore) ListUsers(arg0 context.Context, arg1 db.ListUsersParams) ([]db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", arg0, arg1)
+	ret0, _ := ret[0].([]db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockStoreMockRecorder) ListUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockStore)(nil).ListUsers), arg0, arg1)
+}
+
+// MarkCommentDeleted mocks base method.
+func (m *MockStore) MarkCommentDeleted(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkCommentDeleted", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkCommentDeleted indicates an expected call of MarkCommentDeleted.
+func (mr *MockStoreMockRecorder) MarkCommentDeleted(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCommentDeleted", reflect.TypeOf((*MockStore)(nil).MarkCommentDeleted), arg0, arg1)
+}
+
+// PollDeviceAuthorization mocks base method.
+func (m *MockStore) PollDeviceAuthorization(arg0 context.Context, arg1 db.PollDeviceAuthorizationParams) (db.DeviceAuthorization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PollDeviceAuthorization", arg0, arg1)
+	ret0, _ := ret[0].(db.DeviceAuthorization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PollDeviceAuthorization indicates an expected call of PollDeviceAuthorization.
+func (mr *MockStoreMockRecorder) PollDeviceAuthorization(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PollDeviceAuthorization", reflect.TypeOf((*MockStore)(nil).PollDeviceAuthorization), arg0, arg1)
+}
+
+// PublishScheduledArticles mocks base method.
+func (m *MockStore) PublishScheduledArticles(arg0 context.Context, arg1 int32) ([]db.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishScheduledArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PublishScheduledArticles indicates an expected call of PublishScheduledArticles.
+func (mr *MockStoreMockRecorder) PublishScheduledArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishScheduledArticles", reflect.TypeOf((*MockStore)(nil).PublishScheduledArticles), arg0, arg1)
+}
+
+// ReindexArticleSearch mocks base method.
+func (m *MockStore) ReindexArticleSearch(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReindexArticleSearch", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReindexArticleSearch indicates an expected call of ReindexArticleSearch.
+func (mr *MockStoreMockRecorder) ReindexArticleSearch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReindexArticleSearch", reflect.TypeOf((*MockStore)(nil).ReindexArticleSearch), arg0, arg1)
+}
+
+// RequirePasswordReset mocks base method.
+func (m *MockStore) RequirePasswordReset(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequirePasswordReset", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequirePasswordReset indicates an expected call of RequirePasswordReset.
+func (mr *MockStoreMockRecorder) RequirePasswordReset(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequirePasswordReset", reflect.TypeOf((*MockStore)(nil).RequirePasswordReset), arg0, arg1)
+}
+
+// RevokeInvitation mocks base method.
+func (m *MockStore) RevokeInvitation(arg0 context.Context, arg1 int64) (db.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeInvitation", arg0, arg1)
+	ret0, _ := ret[0].(db.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeInvitation indicates an expected call of RevokeInvitation.
+func (mr *MockStoreMockRecorder) RevokeInvitation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeInvitation", reflect.TypeOf((*MockStore)(nil).RevokeInvitation), arg0, arg1)
+}
+
+// SearchArticles mocks base method.
+func (m *MockStore) SearchArticles(arg0 context.Context, arg1 db.SearchArticlesParams) ([]db.SearchArticlesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchArticles", arg0, arg1)
+	ret0, _ := ret[0].([]db.SearchArticlesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchArticles indicates an expected call of SearchArticles.
+func (mr *MockStoreMockRecorder) SearchArticles(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchArticles", reflect.TypeOf((*MockStore)(nil).SearchArticles), arg0, arg1)
+}
+
+// SetUserAvatar mocks base method.
+func (m *MockStore) SetUserAvatar(arg0 context.Context, arg1 db.SetUserAvatarParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserAvatar", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetUserAvatar indicates an expected call of SetUserAvatar.
+func (mr *MockStoreMockRecorder) SetUserAvatar(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserAvatar", reflect.TypeOf((*MockStore)(nil).SetUserAvatar), arg0, arg1)
+}
+
+// SetUserRole mocks base method.
+func (m *MockStore) SetUserRole(arg0 context.Context, arg1 db.SetUserRoleParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockStoreMockRecorder) SetUserRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockStore)(nil).SetUserRole), arg0, arg1)
+}
+
+// SetUserTOTPSecret mocks base method.
+func (m *MockStore) SetUserTOTPSecret(arg0 context.Context, arg1 db.SetUserTOTPSecretParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserTOTPSecret", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetUserTOTPSecret indicates an expected call of SetUserTOTPSecret.
+func (mr *MockStoreMockRecorder) SetUserTOTPSecret(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserTOTPSecret", reflect.TypeOf((*MockStore)(nil).SetUserTOTPSecret), arg0, arg1)
+}
+
+// UpdateArticle mocks base method.
+func (m *MockStore) UpdateArticle(arg0 context.Context, arg1 db.UpdateArticleParams) (db.Article, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateArticle", arg0, arg1)
+	ret0, _ := ret[0].(db.Article)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateArticle indicates an expected call of UpdateArticle.
+func (mr *MockStoreMockRecorder) UpdateArticle(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateArticle", reflect.TypeOf((*MockStore)(nil).UpdateArticle), arg0, arg1)
+}
+
+// UpdateArticleTx mocks base method.
+func (m *MockStore) UpdateArticleTx(arg0 context.Context, arg1 db.UpdateArticleTxParams) (db.ArticleTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateArticleTx", arg0, arg1)
+	ret0, _ := ret[0].(db.ArticleTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateArticleTx indicates an expected call of UpdateArticleTx.
+func (mr *MockStoreMockRecorder) UpdateArticleTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateArticleTx", reflect.TypeOf((*MockStore)(nil).UpdateArticleTx), arg0, arg1)
+}
+
+// UpdateComment mocks base method.
+func (m *MockStore) UpdateComment(arg0 context.Context, arg1 db.UpdateCommentParams) (db.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateComment", arg0, arg1)
+	ret0, _ := ret[0].(db.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateComment indicates an expected call of UpdateComment.
+func (mr *MockStoreMockRecorder) UpdateComment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateComment", reflect.TypeOf((*MockStore)(nil).UpdateComment), arg0, arg1)
+}
+
+// UpdateUserHashedPassword mocks base method.
+func (m *MockStore) UpdateUserHashedPassword(arg0 context.Context, arg1 db.UpdateUserHashedPasswordParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserHashedPassword", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserHashedPassword indicates an expected call of UpdateUserHashedPassword.
+func (mr *MockStoreMockRecorder) UpdateUserHashedPassword(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserHashedPassword", reflect.TypeOf((*MockStore)(nil).UpdateUserHashedPassword), arg0, arg1)
+}
+
+// UpdateUserPassword mocks base method.
+func (m *MockStore) UpdateUserPassword(arg0 context.Context, arg1 db.UpdateUserPasswordParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockStoreMockRecorder) UpdateUserPassword(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockStore)(nil).UpdateUserPassword), arg0, arg1)
+}
+
+// UpdateUsername mocks base method.
+func (m *MockStore) UpdateUsername(arg0 context.Context, arg1 db.UpdateUsernameParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUsername", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUsername indicates an expected call of UpdateUsername.
+func (mr *MockStoreMockRecorder) UpdateUsername(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUsername", reflect.TypeOf((*MockStore)(nil).UpdateUsername), arg0, arg1)
+}
+
+// UpsertTags mocks base method.
+func (m *MockStore) UpsertTags(arg0 context.Context, arg1 []string) ([]db.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertTags", arg0, arg1)
+	ret0, _ := ret[0].([]db.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertTags indicates an expected call of UpsertTags.
+func (mr *MockStoreMockRecorder) UpsertTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertTags", reflect.TypeOf((*MockStore)(nil).UpsertTags), arg0, arg1)
+}
+
+// UseInvitation mocks base method.
+func (m *MockStore) UseInvitation(arg0 context.Context, arg1 db.UseInvitationParams) (db.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseInvitation", arg0, arg1)
+	ret0, _ := ret[0].(db.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseInvitation indicates an expected call of UseInvitation.
+func (mr *MockStoreMockRecorder) UseInvitation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseInvitation", reflect.TypeOf((*MockStore)(nil).UseInvitation), arg0, arg1)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockStore) UseRecoveryCode(arg0 context.Context, arg1 db.UseRecoveryCodeParams) (db.RecoveryCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", arg0, arg1)
+	ret0, _ := ret[0].(db.RecoveryCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockStoreMockRecorder) UseRecoveryCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockStore)(nil).UseRecoveryCode), arg0, arg1)
+}
+
+// UseUserTOTPStep mocks base method.
+func (m *MockStore) UseUserTOTPStep(arg0 context.Context, arg1 db.UseUserTOTPStepParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseUserTOTPStep", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseUserTOTPStep indicates an expected call of UseUserTOTPStep.
+func (mr *MockStoreMockRecorder) UseUserTOTPStep(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseUserTOTPStep", reflect.TypeOf((*MockStore)(nil).UseUserTOTPStep), arg0, arg1)
 }
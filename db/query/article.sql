@@ -2,9 +2,13 @@
 INSERT INTO articles (
     author,
     headline,
-    content
+    content,
+    status,
+    publish_at,
+    search_language
 ) VALUES (
-    $1, $2, $3
+    sqlc.arg('author'), sqlc.arg('headline'), sqlc.arg('content'), sqlc.arg('status'), sqlc.narg('publish_at'),
+    sqlc.arg('search_language')::regconfig
 ) RETURNING *;
 
 -- name: GetArticle :one
@@ -12,21 +16,101 @@ SELECT * FROM articles
 WHERE id = $1 LIMIT 1;
 
 -- name: UpdateArticle :one
+-- Nothing is updated when version is given and the article has another one
 UPDATE articles
 SET
     headline = coalesce(sqlc.narg('headline'), headline),
     content = coalesce(sqlc.narg('content'), content),
-    edited_at = NOW()
+    edited_at = NOW(),
+    version = version + 1
 WHERE id = sqlc.arg('id')
+    AND (sqlc.narg('version')::int IS NULL OR version = sqlc.narg('version')::int)
 RETURNING *;
 
--- name: ListArticles :many
+-- name: ListPublicArticles :many
 SELECT * FROM articles
-WHERE author = $1
-ORDER BY id
-LIMIT $2
-OFFSET $3;
+WHERE status = 'published'
+    AND (sqlc.narg('author')::varchar IS NULL OR author = sqlc.narg('author')::varchar)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
 
--- name: DeleteArticle :exec
+-- name: ListFeedArticles :many
+SELECT a.* FROM articles a
+JOIN follows f ON f.followee = a.author
+WHERE f.follower = sqlc.arg('follower')
+    AND a.status = 'published'
+    AND (
+        sqlc.narg('cursor_created_at')::timestamptz IS NULL
+        OR (a.created_at, a.id) < (sqlc.narg('cursor_created_at')::timestamptz, sqlc.narg('cursor_id')::bigint)
+    )
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteArticle :execrows
+-- Nothing is deleted when version is given and the article has another one
 DELETE FROM articles
-WHERE id = $1;
\ No newline at end of file
+WHERE id = sqlc.arg('id')
+    AND (sqlc.narg('version')::int IS NULL OR version = sqlc.narg('version')::int);
+
+-- name: ChangeArticleStatus :one
+-- Nothing is updated when the article isn't in one of from_statuses anymore, e.g. the publisher got to it first
+UPDATE articles
+SET
+    status = sqlc.arg('status'),
+    publish_at = sqlc.narg('publish_at'),
+    version = version + 1
+WHERE id = sqlc.arg('id') AND status = ANY(sqlc.arg('from_statuses')::varchar[])
+RETURNING *;
+
+-- name: PublishScheduledArticles :many
+-- Rows locked by another instance running the same query are skipped, so every article is published once
+UPDATE articles
+SET
+    status = 'published',
+    version = version + 1
+WHERE id IN (
+    SELECT id FROM articles
+    WHERE status = 'scheduled' AND publish_at <= NOW()
+    ORDER BY publish_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReindexArticleSearch :execrows
+-- Articles indexed with another configuration are rebuilt, so that all of them can be searched with the current one
+UPDATE articles
+SET search_language = sqlc.arg('language')::regconfig
+WHERE search_language <> sqlc.arg('language')::regconfig;
+
+-- name: SearchArticles :many
+-- Headline matches weigh more than content ones. Snippets are HTML: the text is escaped first
+-- and only the matched words are wrapped in <b></b>, so that stored markup can't get into them
+SELECT
+    id,
+    author,
+    headline,
+    content,
+    created_at,
+    edited_at,
+    ts_rank(search_vector, websearch_to_tsquery(sqlc.arg('language')::regconfig, sqlc.arg('query')::text))::real AS rank,
+    ts_headline(
+        sqlc.arg('language')::regconfig,
+        replace(replace(replace(replace(headline, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+        websearch_to_tsquery(sqlc.arg('language')::regconfig, sqlc.arg('query')::text),
+        'HighlightAll=true'
+    )::text AS headline_snippet,
+    ts_headline(
+        sqlc.arg('language')::regconfig,
+        replace(replace(replace(replace(content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+        websearch_to_tsquery(sqlc.arg('language')::regconfig, sqlc.arg('query')::text),
+        'MaxFragments=2, MaxWords=30, MinWords=10'
+    )::text AS content_snippet
+FROM articles
+WHERE status = 'published'
+    AND search_language = sqlc.arg('language')::regconfig
+    AND search_vector @@ websearch_to_tsquery(sqlc.arg('language')::regconfig, sqlc.arg('query')::text)
+ORDER BY rank DESC, id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
@@ -0,0 +1,44 @@
+-- name: GetAuthor :one
+-- Only published articles are counted, the others aren't public
+SELECT
+    u.username,
+    u.full_name,
+    u.created_at AS joined_at,
+    count(a.id) AS article_count,
+    latest.publish_at AS last_published_at
+FROM users u
+LEFT JOIN articles a ON a.author = u.username AND a.status = 'published'
+LEFT JOIN articles latest ON latest.id = (
+    SELECT id FROM articles
+    WHERE author = u.username AND status = 'published'
+    ORDER BY publish_at DESC, id DESC
+    LIMIT 1
+)
+WHERE u.username = $1
+GROUP BY u.username, latest.id;
+
+-- name: ListAuthors :many
+SELECT
+    u.username,
+    u.full_name,
+    u.created_at AS joined_at,
+    count(a.id) AS article_count,
+    latest.publish_at AS last_published_at
+FROM users u
+LEFT JOIN articles a ON a.author = u.username AND a.status = 'published'
+LEFT JOIN articles latest ON latest.id = (
+    SELECT id FROM articles
+    WHERE author = u.username AND status = 'published'
+    ORDER BY publish_at DESC, id DESC
+    LIMIT 1
+)
+GROUP BY u.username, latest.id
+ORDER BY
+    CASE WHEN sqlc.arg('sort_by')::text = 'article_count' AND NOT sqlc.arg('descending')::bool THEN count(a.id) END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'article_count' AND sqlc.arg('descending')::bool THEN count(a.id) END DESC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'joined_at' AND NOT sqlc.arg('descending')::bool THEN u.created_at END ASC,
+    CASE WHEN sqlc.arg('sort_by')::text = 'joined_at' AND sqlc.arg('descending')::bool THEN u.created_at END DESC,
+    CASE WHEN NOT sqlc.arg('descending')::bool THEN u.username END ASC,
+    CASE WHEN sqlc.arg('descending')::bool THEN u.username END DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
@@ -0,0 +1,70 @@
+-- name: CreateComment :one
+INSERT INTO comments (
+    article_id,
+    parent_id,
+    author,
+    content
+) VALUES (
+    sqlc.arg('article_id'), sqlc.narg('parent_id'), sqlc.arg('author'), sqlc.arg('content')
+) RETURNING *;
+
+-- name: GetComment :one
+SELECT * FROM comments
+WHERE id = $1 LIMIT 1;
+
+-- name: UpdateComment :one
+UPDATE comments
+SET
+    content = sqlc.arg('content'),
+    edited_at = NOW()
+WHERE id = sqlc.arg('id') AND deleted_at IS NULL
+RETURNING *;
+
+-- name: DeleteComment :execrows
+-- Only comments without replies are deleted, the others have to be marked with MarkCommentDeleted.
+-- A reply inserted concurrently isn't seen by the check, the delete fails with foreign_key_violation then
+DELETE FROM comments c
+WHERE c.id = $1 AND NOT EXISTS (
+    SELECT 1 FROM comments r WHERE r.parent_id = c.id
+);
+
+-- name: MarkCommentDeleted :exec
+UPDATE comments
+SET
+    content = '',
+    deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: ListComments :many
+SELECT * FROM comments
+WHERE article_id = $1
+ORDER BY created_at, id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListRootComments :many
+SELECT * FROM comments
+WHERE article_id = $1 AND parent_id IS NULL
+ORDER BY created_at, id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListCommentReplies :many
+-- All replies in the threads of the given comments, however deep they are
+WITH RECURSIVE replies AS (
+    SELECT c.id FROM comments c
+    WHERE c.parent_id = ANY(sqlc.arg('parent_ids')::bigint[])
+    UNION ALL
+    SELECT c.id FROM comments c
+    JOIN replies r ON c.parent_id = r.id
+)
+SELECT * FROM comments
+WHERE id IN (SELECT id FROM replies)
+ORDER BY created_at, id;
+
+-- name: CountCommentsOfArticles :many
+-- Deleted comments aren't counted
+SELECT article_id, count(*) AS comment_count
+FROM comments
+WHERE article_id = ANY(sqlc.arg('article_ids')::bigint[]) AND deleted_at IS NULL
+GROUP BY article_id;
@@ -0,0 +1,14 @@
+-- name: ConsumeToken :execrows
+-- Returns 0 if the token has been consumed already. Expired tokens are cleaned up on the way,
+-- they are rejected by signature verification anyway
+WITH cleanup AS (
+    DELETE FROM consumed_tokens
+    WHERE expires_at < NOW()
+)
+INSERT INTO consumed_tokens (
+    id,
+    purpose,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,41 @@
+-- name: CreateDeviceAuthorization :one
+-- Expired authorizations are cleaned up on the way. They are kept for a day after expiring,
+-- so that clients still polling get expired_token rather than invalid_grant
+WITH cleanup AS (
+    DELETE FROM device_authorizations
+    WHERE expires_at < NOW() - interval '1 day'
+)
+INSERT INTO device_authorizations (
+    hashed_device_code,
+    user_code,
+    poll_interval,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetDeviceAuthorization :one
+SELECT * FROM device_authorizations
+WHERE hashed_device_code = $1 LIMIT 1;
+
+-- name: PollDeviceAuthorization :one
+UPDATE device_authorizations
+SET
+    last_polled_at = NOW(),
+    poll_interval = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: DecideDeviceAuthorization :one
+UPDATE device_authorizations
+SET
+    status = sqlc.arg('status'),
+    username = sqlc.arg('username')
+WHERE user_code = sqlc.arg('user_code')
+    AND status = 'pending'
+    AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteDeviceAuthorization :execrows
+DELETE FROM device_authorizations
+WHERE id = $1;
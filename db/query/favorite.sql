@@ -0,0 +1,56 @@
+-- name: AddFavorite :exec
+INSERT INTO favorites (
+    username,
+    article_id
+) VALUES (
+    $1, $2
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteFavorite :exec
+DELETE FROM favorites
+WHERE username = $1 AND article_id = $2;
+
+-- name: ListFavoriteArticles :many
+-- Most recently favorited first. Articles that aren't published anymore are only listed to their author
+SELECT a.* FROM articles a
+JOIN favorites f ON f.article_id = a.id
+WHERE f.username = sqlc.arg('username')
+    AND (a.status = 'published' OR a.author = sqlc.arg('username'))
+ORDER BY f.created_at DESC, a.id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: CountFavoritesOfArticles :many
+-- Favorited tells if the given user is one of those who favorited the article
+SELECT
+    article_id,
+    count(*) AS favorite_count,
+    bool_or(username = sqlc.arg('username')::varchar)::boolean AS favorited
+FROM favorites
+WHERE article_id = ANY(sqlc.arg('article_ids')::bigint[])
+GROUP BY article_id;
+
+-- name: AddReaction :exec
+INSERT INTO article_reactions (
+    username,
+    article_id,
+    reaction
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteReaction :exec
+DELETE FROM article_reactions
+WHERE username = $1 AND article_id = $2 AND reaction = $3;
+
+-- name: CountReactionsOfArticles :many
+-- Reacted tells if the given user left the reaction
+SELECT
+    article_id,
+    reaction,
+    count(*) AS reaction_count,
+    bool_or(username = sqlc.arg('username')::varchar)::boolean AS reacted
+FROM article_reactions
+WHERE article_id = ANY(sqlc.arg('article_ids')::bigint[])
+GROUP BY article_id, reaction
+ORDER BY article_id, reaction;
@@ -0,0 +1,29 @@
+-- name: CreateFollow :exec
+INSERT INTO follows (
+    follower,
+    followee
+) VALUES (
+    $1, $2
+) ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower = $1 AND followee = $2;
+
+-- name: ListFollowers :many
+SELECT u.username, u.full_name, f.created_at AS followed_at
+FROM follows f
+JOIN users u ON u.username = f.follower
+WHERE f.followee = $1
+ORDER BY f.created_at DESC, u.username
+LIMIT $2
+OFFSET $3;
+
+-- name: ListFollowing :many
+SELECT u.username, u.full_name, f.created_at AS followed_at
+FROM follows f
+JOIN users u ON u.username = f.followee
+WHERE f.follower = $1
+ORDER BY f.created_at DESC, u.username
+LIMIT $2
+OFFSET $3;
@@ -0,0 +1,21 @@
+-- name: CreateImpersonationLogEntry :exec
+INSERT INTO impersonation_log (
+    session_id,
+    impersonator,
+    username,
+    method,
+    path,
+    status,
+    client_ip
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: ListImpersonationLog :many
+-- Filters are skipped when null
+SELECT * FROM impersonation_log
+WHERE (sqlc.narg('impersonator')::varchar IS NULL OR impersonator = sqlc.narg('impersonator')::varchar)
+    AND (sqlc.narg('username')::varchar IS NULL OR username = sqlc.narg('username')::varchar)
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
@@ -0,0 +1,33 @@
+-- name: CreateInvitation :one
+INSERT INTO invitations (
+    hashed_code,
+    email,
+    created_by,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListInvitations :many
+SELECT * FROM invitations
+ORDER BY id DESC
+LIMIT $1
+OFFSET $2;
+
+-- name: RevokeInvitation :one
+UPDATE invitations
+SET revoked_at = NOW()
+WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+RETURNING *;
+
+-- name: UseInvitation :one
+UPDATE invitations
+SET
+    used_by = sqlc.arg('used_by'),
+    used_at = NOW()
+WHERE hashed_code = sqlc.arg('hashed_code')
+    AND used_at IS NULL
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+    AND (email IS NULL OR lower(email) = lower(sqlc.arg('email')))
+RETURNING *;
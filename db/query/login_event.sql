@@ -0,0 +1,30 @@
+-- name: CreateLoginEvent :exec
+INSERT INTO login_events (
+    login,
+    username,
+    outcome,
+    ip,
+    user_agent
+) VALUES (
+    $1, $2, $3, $4, $5
+);
+
+-- name: ListLoginEvents :many
+SELECT * FROM login_events
+WHERE username = sqlc.arg('username')::varchar
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: GetLoginDeviceHistory :one
+-- Device is the combination of IP and user agent. Logins with the correct password count, even if MFA wasn't finished
+SELECT
+    EXISTS (
+        SELECT 1 FROM login_events e
+        WHERE e.username = sqlc.arg('username')::varchar AND e.outcome IN ('success', 'mfa_required')
+    ) AS has_logins,
+    EXISTS (
+        SELECT 1 FROM login_events e
+        WHERE e.username = sqlc.arg('username')::varchar AND e.outcome IN ('success', 'mfa_required')
+            AND e.ip = sqlc.arg('ip') AND e.user_agent = sqlc.arg('user_agent')
+    ) AS known_device;
@@ -0,0 +1,17 @@
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING *;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE username = $1 AND hashed_code = $2 AND used_at IS NULL
+RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
@@ -0,0 +1,32 @@
+-- name: CreateArticleRevision :one
+-- Called in the transaction that wrote the article, which keeps the article row locked,
+-- so concurrent updates can't get the same number
+INSERT INTO article_revisions (
+    article_id,
+    revision,
+    headline,
+    content,
+    editor,
+    restored_from
+) VALUES (
+    sqlc.arg(article_id),
+    (SELECT COALESCE(MAX(revision), 0) + 1 FROM article_revisions WHERE article_id = sqlc.arg(article_id)),
+    sqlc.arg(headline),
+    sqlc.arg(content),
+    sqlc.arg(editor),
+    sqlc.narg(restored_from)
+)
+RETURNING *;
+
+-- name: GetArticleRevision :one
+SELECT * FROM article_revisions
+WHERE article_id = $1 AND revision = $2
+LIMIT 1;
+
+-- name: ListArticleRevisions :many
+-- Without the content, which can be fetched revision by revision
+SELECT article_id, revision, headline, editor, restored_from, created_at FROM article_revisions
+WHERE article_id = $1
+ORDER BY revision DESC
+LIMIT $2
+OFFSET $3;
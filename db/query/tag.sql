@@ -0,0 +1,38 @@
+-- name: UpsertTags :many
+-- Existing tags are returned too, the no-op update makes RETURNING include them
+INSERT INTO tags (
+    name
+) SELECT unnest(sqlc.arg('names')::varchar[])
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: AddArticleTags :exec
+INSERT INTO article_tags (
+    article_id,
+    tag_id
+) SELECT sqlc.arg('article_id'), unnest(sqlc.arg('tag_ids')::bigint[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteArticleTags :exec
+DELETE FROM article_tags
+WHERE article_id = $1;
+
+-- name: ListTagsOfArticles :many
+-- Tags of many articles at once, so that lists don't need a query per article
+SELECT at.article_id, t.name
+FROM article_tags at
+JOIN tags t ON t.id = at.tag_id
+WHERE at.article_id = ANY(sqlc.arg('article_ids')::bigint[])
+ORDER BY at.article_id, t.name;
+
+-- name: ListTags :many
+-- Only tags used by some published article are listed, the other articles aren't public
+SELECT t.name, count(*) AS article_count
+FROM tags t
+JOIN article_tags at ON at.tag_id = t.id
+JOIN articles a ON a.id = at.article_id
+WHERE a.status = 'published'
+GROUP BY t.id
+ORDER BY article_count DESC, t.name
+LIMIT $1
+OFFSET $2;
@@ -10,4 +10,107 @@ INSERT INTO users (
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
\ No newline at end of file
+WHERE username = $1 LIMIT 1;
+
+-- name: GetUserByLogin :one
+SELECT * FROM users
+WHERE lower(username) = lower(sqlc.arg('login')::varchar)
+    OR email = lower(sqlc.arg('login')::varchar)
+LIMIT 1;
+
+-- name: SetUserTOTPSecret :one
+UPDATE users
+SET
+    totp_secret = sqlc.arg('totp_secret'),
+    totp_enabled = false,
+    totp_last_step = 0
+WHERE username = sqlc.arg('username')
+RETURNING *;
+
+-- name: EnableUserTOTP :one
+UPDATE users
+SET totp_enabled = true
+WHERE username = $1
+RETURNING *;
+
+-- name: DisableUserTOTP :one
+UPDATE users
+SET
+    totp_secret = '',
+    totp_enabled = false,
+    totp_last_step = 0
+WHERE username = $1
+RETURNING *;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = sqlc.arg('step')
+WHERE username = sqlc.arg('username') AND totp_last_step < sqlc.arg('step');
+
+-- name: UpdateUserHashedPassword :exec
+UPDATE users
+SET hashed_password = sqlc.arg('hashed_password')
+WHERE username = sqlc.arg('username');
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET
+    hashed_password = sqlc.arg('hashed_password'),
+    password_changed_at = NOW(),
+    password_reset_required = false
+WHERE username = sqlc.arg('username')
+RETURNING *;
+
+-- name: UpdateUsername :one
+UPDATE users
+SET username = sqlc.arg('new_username')
+WHERE username = sqlc.arg('username')
+RETURNING *;
+
+-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_id = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: ListUsers :many
+-- Filters are skipped when null. Search is matched as a substring of username, full name and email
+SELECT * FROM users
+WHERE (sqlc.narg('search')::text IS NULL
+        OR username ILIKE '%' || sqlc.narg('search')::text || '%'
+        OR full_name ILIKE '%' || sqlc.narg('search')::text || '%'
+        OR email ILIKE '%' || sqlc.narg('search')::text || '%')
+    AND (sqlc.narg('role')::varchar IS NULL OR role = sqlc.narg('role')::varchar)
+    AND (sqlc.narg('disabled')::bool IS NULL OR (disabled_at IS NOT NULL) = sqlc.narg('disabled')::bool)
+ORDER BY created_at DESC, username
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: DisableUser :one
+UPDATE users
+SET disabled_at = COALESCE(disabled_at, NOW())
+WHERE username = $1
+RETURNING *;
+
+-- name: EnableUser :one
+UPDATE users
+SET disabled_at = NULL
+WHERE username = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING *;
+
+-- name: RequirePasswordReset :one
+UPDATE users
+SET password_reset_required = true
+WHERE username = $1
+RETURNING *;
+
+-- name: ListUserAccess :many
+-- Read on every authenticated request, so that disabling an account takes effect immediately
+SELECT username, role, disabled_at FROM users
+WHERE username = ANY(sqlc.arg('usernames')::varchar[]);
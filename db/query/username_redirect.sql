@@ -0,0 +1,17 @@
+-- name: CreateUsernameRedirect :exec
+INSERT INTO username_redirects (
+    old_username,
+    username
+) VALUES (
+    $1, $2
+) ON CONFLICT (old_username) DO UPDATE SET
+    username = EXCLUDED.username,
+    created_at = NOW();
+
+-- name: GetUsernameRedirect :one
+SELECT username FROM username_redirects
+WHERE old_username = $1 LIMIT 1;
+
+-- name: DeleteUsernameRedirect :exec
+DELETE FROM username_redirects
+WHERE old_username = $1;
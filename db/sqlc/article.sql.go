@@ -8,26 +8,85 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+const changeArticleStatus = `-- name: ChangeArticleStatus :one
+UPDATE articles
+SET
+    status = $1,
+    publish_at = $2,
+    version = version + 1
+WHERE id = $3 AND status = ANY($4::varchar[])
+RETURNING id, author, headline, content, created_at, edited_at, status, publish_at, version, search_language, search_vector
+`
+
+type ChangeArticleStatusParams struct {
+	Status       string       `json:"status"`
+	PublishAt    sql.NullTime `json:"publish_at"`
+	ID           int64        `json:"id"`
+	FromStatuses []string     `json:"from_statuses"`
+}
+
+// Nothing is updated when the article isn't in one of from_statuses anymore, e.g. the publisher got to it first
+func (q *Queries) ChangeArticleStatus(ctx context.Context, arg ChangeArticleStatusParams) (Article, error) {
+	row := q.db.QueryRowContext(ctx, changeArticleStatus,
+		arg.Status,
+		arg.PublishAt,
+		arg.ID,
+		pq.Array(arg.FromStatuses),
+	)
+	var i Article
+	err := row.Scan(
+		&i.ID,
+		&i.Author,
+		&i.Headline,
+		&i.Content,
+		&i.CreatedAt,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Version,
+		&i.SearchLanguage,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
 const createArticle = `-- name: CreateArticle :one
 INSERT INTO articles (
     author,
     headline,
-    content
+    content,
+    status,
+    publish_at,
+    search_language
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, author, headline, content, created_at, edited_at
+    $1, $2, $3, $4, $5,
+    $6::regconfig
+) RETURNING id, author, headline, content, created_at, edited_at, status, publish_at, version, search_language, search_vector
 `
 
 type CreateArticleParams struct {
-	Author   string `json:"author"`
-	Headline string `json:"headline"`
-	Content  string `json:"content"`
+	Author         string       `json:"author"`
+	Headline       string       `json:"headline"`
+	Content        string       `json:"content"`
+	Status         string       `json:"status"`
+	PublishAt      sql.NullTime `json:"publish_at"`
+	SearchLanguage string       `json:"search_language"`
 }
 
 func (q *Queries) CreateArticle(ctx context.Context, arg CreateArticleParams) (Article, error) {
-	row := q.db.QueryRowContext(ctx, createArticle, arg.Author, arg.Headline, arg.Content)
+	row := q.db.QueryRowContext(ctx, createArticle,
+		arg.Author,
+		arg.Headline,
+		arg.Content,
+		arg.Status,
+		arg.PublishAt,
+		arg.SearchLanguage,
+	)
 	var i Article
 	err := row.Scan(
 		&i.ID,
@@ -36,22 +95,37 @@ func (q *Queries) CreateArticle(ctx context.Context, arg CreateArticleParams) (A
 		&i.Content,
 		&i.CreatedAt,
 		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Version,
+		&i.SearchLanguage,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
-const deleteArticle = `-- name: DeleteArticle :exec
+const deleteArticle = `-- name: DeleteArticle :execrows
 DELETE FROM articles
 WHERE id = $1
+    AND ($2::int IS NULL OR version = $2::int)
 `
 
-func (q *Queries) DeleteArticle(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, deleteArticle, id)
-	return err
+type DeleteArticleParams struct {
+	ID      int64         `json:"id"`
+	Version sql.NullInt32 `json:"version"`
+}
+
+// Nothing is deleted when version is given and the article has another one
+func (q *Queries) DeleteArticle(ctx context.Context, arg DeleteArticleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteArticle, arg.ID, arg.Version)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getArticle = `-- name: GetArticle :one
-SELECT id, author, headline, content, created_at, edited_at FROM articles
+SELECT id, author, headline, content, created_at, edited_at, status, publish_at, version, search_language, search_vector FROM articles
 WHERE id = $1 LIMIT 1
 `
 
@@ -65,26 +139,143 @@ func (q *Queries) GetArticle(ctx context.Context, id int64) (Article, error) {
 		&i.Content,
 		&i.CreatedAt,
 		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Version,
+		&i.SearchLanguage,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
-const listArticles = `-- name: ListArticles :many
-SELECT id, author, headline, content, created_at, edited_at FROM articles
-WHERE author = $1
-ORDER BY id
-LIMIT $2
-OFFSET $3
+const listFeedArticles = `-- name: ListFeedArticles :many
+SELECT a.id, a.author, a.headline, a.content, a.created_at, a.edited_at, a.status, a.publish_at, a.version, a.search_language, a.search_vector FROM articles a
+JOIN follows f ON f.followee = a.author
+WHERE f.follower = $1
+    AND a.status = 'published'
+    AND (
+        $2::timestamptz IS NULL
+        OR (a.created_at, a.id) < ($2::timestamptz, $3::bigint)
+    )
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT $4
+`
+
+type ListFeedArticlesParams struct {
+	Follower        string        `json:"follower"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorID        sql.NullInt64 `json:"cursor_id"`
+	Limit           int32         `json:"limit"`
+}
+
+func (q *Queries) ListFeedArticles(ctx context.Context, arg ListFeedArticlesParams) ([]Article, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedArticles,
+		arg.Follower,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Article{}
+	for rows.Next() {
+		var i Article
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Headline,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Version,
+			&i.SearchLanguage,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPublicArticles = `-- name: ListPublicArticles :many
+SELECT id, author, headline, content, created_at, edited_at, status, publish_at, version, search_language, search_vector FROM articles
+WHERE status = 'published'
+    AND ($1::varchar IS NULL OR author = $1::varchar)
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+OFFSET $2
 `
 
-type ListArticlesParams struct {
-	Author string `json:"author"`
-	Limit  int32  `json:"limit"`
-	Offset int32  `json:"offset"`
+type ListPublicArticlesParams struct {
+	Author sql.NullString `json:"author"`
+	Offset int32          `json:"offset"`
+	Limit  int32          `json:"limit"`
+}
+
+func (q *Queries) ListPublicArticles(ctx context.Context, arg ListPublicArticlesParams) ([]Article, error) {
+	rows, err := q.db.QueryContext(ctx, listPublicArticles, arg.Author, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Article{}
+	for rows.Next() {
+		var i Article
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Headline,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Version,
+			&i.SearchLanguage,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error) {
-	rows, err := q.db.QueryContext(ctx, listArticles, arg.Author, arg.Limit, arg.Offset)
+const publishScheduledArticles = `-- name: PublishScheduledArticles :many
+UPDATE articles
+SET
+    status = 'published',
+    version = version + 1
+WHERE id IN (
+    SELECT id FROM articles
+    WHERE status = 'scheduled' AND publish_at <= NOW()
+    ORDER BY publish_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, author, headline, content, created_at, edited_at, status, publish_at, version, search_language, search_vector
+`
+
+// Rows locked by another instance running the same query are skipped, so every article is published once
+func (q *Queries) PublishScheduledArticles(ctx context.Context, limit int32) ([]Article, error) {
+	rows, err := q.db.QueryContext(ctx, publishScheduledArticles, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +290,115 @@ func (q *Queries) ListArticles(ctx context.Context, arg ListArticlesParams) ([]A
 			&i.Content,
 			&i.CreatedAt,
 			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Version,
+			&i.SearchLanguage,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reindexArticleSearch = `-- name: ReindexArticleSearch :execrows
+UPDATE articles
+SET search_language = $1::regconfig
+WHERE search_language <> $1::regconfig
+`
+
+// Articles indexed with another configuration are rebuilt, so that all of them can be searched with the current one
+func (q *Queries) ReindexArticleSearch(ctx context.Context, language string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reindexArticleSearch, language)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchArticles = `-- name: SearchArticles :many
+SELECT
+    id,
+    author,
+    headline,
+    content,
+    created_at,
+    edited_at,
+    ts_rank(search_vector, websearch_to_tsquery($1::regconfig, $2::text))::real AS rank,
+    ts_headline(
+        $1::regconfig,
+        replace(replace(replace(replace(headline, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+        websearch_to_tsquery($1::regconfig, $2::text),
+        'HighlightAll=true'
+    )::text AS headline_snippet,
+    ts_headline(
+        $1::regconfig,
+        replace(replace(replace(replace(content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+        websearch_to_tsquery($1::regconfig, $2::text),
+        'MaxFragments=2, MaxWords=30, MinWords=10'
+    )::text AS content_snippet
+FROM articles
+WHERE status = 'published'
+    AND search_language = $1::regconfig
+    AND search_vector @@ websearch_to_tsquery($1::regconfig, $2::text)
+ORDER BY rank DESC, id DESC
+LIMIT $4
+OFFSET $3
+`
+
+type SearchArticlesParams struct {
+	Language string `json:"language"`
+	Query    string `json:"query"`
+	Offset   int32  `json:"offset"`
+	Limit    int32  `json:"limit"`
+}
+
+type SearchArticlesRow struct {
+	ID              int64        `json:"id"`
+	Author          string       `json:"author"`
+	Headline        string       `json:"headline"`
+	Content         string       `json:"content"`
+	CreatedAt       time.Time    `json:"created_at"`
+	EditedAt        sql.NullTime `json:"edited_at"`
+	Rank            float32      `json:"rank"`
+	HeadlineSnippet string       `json:"headline_snippet"`
+	ContentSnippet  string       `json:"content_snippet"`
+}
+
+// Headline matches weigh more than content ones. Snippets are HTML: the text is escaped first
+// and only the matched words are wrapped in <b></b>, so that stored markup can't get into them
+func (q *Queries) SearchArticles(ctx context.Context, arg SearchArticlesParams) ([]SearchArticlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchArticles,
+		arg.Language,
+		arg.Query,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchArticlesRow{}
+	for rows.Next() {
+		var i SearchArticlesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Headline,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.Rank,
+			&i.HeadlineSnippet,
+			&i.ContentSnippet,
 		); err != nil {
 			return nil, err
 		}
@@ -118,19 +418,28 @@ UPDATE articles
 SET
     headline = coalesce($1, headline),
     content = coalesce($2, content),
-    edited_at = NOW()
+    edited_at = NOW(),
+    version = version + 1
 WHERE id = $3
-RETURNING id, author, headline, content, created_at, edited_at
+    AND ($4::int IS NULL OR version = $4::int)
+RETURNING id, author, headline, content, created_at, edited_at, status, publish_at, version, search_language, search_vector
 `
 
 type UpdateArticleParams struct {
 	Headline sql.NullString `json:"headline"`
 	Content  sql.NullString `json:"content"`
 	ID       int64          `json:"id"`
+	Version  sql.NullInt32  `json:"version"`
 }
 
+// Nothing is updated when version is given and the article has another one
 func (q *Queries) UpdateArticle(ctx context.Context, arg UpdateArticleParams) (Article, error) {
-	row := q.db.QueryRowContext(ctx, updateArticle, arg.Headline, arg.Content, arg.ID)
+	row := q.db.QueryRowContext(ctx, updateArticle,
+		arg.Headline,
+		arg.Content,
+		arg.ID,
+		arg.Version,
+	)
 	var i Article
 	err := row.Scan(
 		&i.ID,
@@ -139,6 +448,11 @@ func (q *Queries) UpdateArticle(ctx context.Context, arg UpdateArticleParams) (A
 		&i.Content,
 		&i.CreatedAt,
 		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Version,
+		&i.SearchLanguage,
+		&i.SearchVector,
 	)
 	return i, err
 }
@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Columns articles can be sorted by
+const (
+	ArticleSortCreatedAt = "created_at"
+	ArticleSortEditedAt  = "edited_at"
+	ArticleSortHeadline  = "headline"
+)
+
+// Only these expressions ever end up in ORDER BY, user input is never put into the query itself
+var articleSortColumns = map[string]string{
+	ArticleSortCreatedAt: "created_at",
+	ArticleSortEditedAt:  "edited_at",
+	ArticleSortHeadline:  "headline",
+}
+
+// ArticleCursor is the position of an article in a sorted list
+type ArticleCursor struct {
+	// Value of the sort column. Nil for articles that were never edited when sorting by edited_at
+	Value interface{}
+	ID    int64
+	// Articles right before the cursor are listed instead of the ones after it
+	Backward bool
+}
+
+// ListArticlesParams contains the filters of the article list. Filters with zero values are skipped
+type ListArticlesParams struct {
+	// Articles of others are only listed once they're published. Nobody's drafts are listed when it's empty
+	Viewer        string
+	Authors       []string
+	Statuses      []string
+	CreatedAfter  sql.NullTime
+	CreatedBefore sql.NullTime
+	EditedAfter   sql.NullTime
+	EditedBefore  sql.NullTime
+	Edited        sql.NullBool
+	// Normalized tag names. Articles with any of them are listed, or only the ones with all of them
+	Tags    []string
+	AllTags bool
+	// One of the ArticleSort constants, created_at when empty
+	SortBy   string
+	SortDesc bool
+	// Keyset pagination. Offset is ignored when it's set
+	Cursor *ArticleCursor
+	Limit  int32
+	Offset int32
+}
+
+// articleQuery builds the WHERE clause of an article query, keeping placeholders and args in sync
+type articleQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+// where adds a condition. Every %s in cond is replaced with a placeholder for the next arg
+func (query *articleQuery) where(cond string, args ...interface{}) {
+	placeholders := make([]interface{}, len(args))
+	for i, arg := range args {
+		placeholders[i] = query.placeholder(arg)
+	}
+	query.conditions = append(query.conditions, fmt.Sprintf(cond, placeholders...))
+}
+
+// placeholder adds an arg and returns its placeholder
+func (query *articleQuery) placeholder(arg interface{}) string {
+	query.args = append(query.args, arg)
+	return fmt.Sprintf("$%d", len(query.args))
+}
+
+func (query *articleQuery) whereClause() string {
+	if len(query.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(query.conditions, " AND ") + "\n"
+}
+
+// filterArticles adds the filters shared by listing and counting
+func filterArticles(arg ListArticlesParams) *articleQuery {
+	query := &articleQuery{}
+	query.where("(status = 'published' OR author = %s)", arg.Viewer)
+	if len(arg.Authors) > 0 {
+		query.where("author = ANY(%s)", pq.Array(arg.Authors))
+	}
+	if len(arg.Statuses) > 0 {
+		query.where("status = ANY(%s)", pq.Array(arg.Statuses))
+	}
+	if len(arg.Tags) > 0 {
+		tagged := "id IN (SELECT at.article_id FROM article_tags at JOIN tags t ON t.id = at.tag_id WHERE t.name = ANY(%s)"
+		if arg.AllTags {
+			query.where(tagged+" GROUP BY at.article_id HAVING count(*) = %s)", pq.Array(arg.Tags), len(arg.Tags))
+		} else {
+			query.where(tagged+")", pq.Array(arg.Tags))
+		}
+	}
+	if arg.CreatedAfter.Valid {
+		query.where("created_at >= %s", arg.CreatedAfter.Time)
+	}
+	if arg.CreatedBefore.Valid {
+		query.where("created_at < %s", arg.CreatedBefore.Time)
+	}
+	if arg.EditedAfter.Valid {
+		query.where("edited_at >= %s", arg.EditedAfter.Time)
+	}
+	if arg.EditedBefore.Valid {
+		query.where("edited_at < %s", arg.EditedBefore.Time)
+	}
+	if arg.Edited.Valid {
+		if arg.Edited.Bool {
+			query.where("edited_at IS NOT NULL")
+		} else {
+			query.where("edited_at IS NULL")
+		}
+	}
+	return query
+}
+
+// ListArticles lists articles matching the filters. The query is built dynamically,
+// so it isn't generated by sqlc like the others
+func (q *Queries) ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error) {
+	sortBy := arg.SortBy
+	if sortBy == "" {
+		sortBy = ArticleSortCreatedAt
+	}
+	column, ok := articleSortColumns[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort articles by %q", arg.SortBy)
+	}
+
+	// Pages before a cursor are read in the opposite order and reversed afterwards
+	backward := arg.Cursor != nil && arg.Cursor.Backward
+	direction, nulls, op := "ASC", "NULLS LAST", ">"
+	if arg.SortDesc != backward {
+		direction, op = "DESC", "<"
+	}
+	if backward {
+		nulls = "NULLS FIRST"
+	}
+
+	query := filterArticles(arg)
+	if arg.Cursor != nil {
+		// Articles that were never edited are last in the list, whatever the direction
+		switch {
+		case arg.Cursor.Value == nil && backward:
+			query.where(fmt.Sprintf("(%s IS NOT NULL OR id %s %%s)", column, op), arg.Cursor.ID)
+		case arg.Cursor.Value == nil:
+			query.where(fmt.Sprintf("(%s IS NULL AND id %s %%s)", column, op), arg.Cursor.ID)
+		case backward:
+			query.where(fmt.Sprintf("(%s, id) %s (%%s, %%s)", column, op), arg.Cursor.Value, arg.Cursor.ID)
+		default:
+			query.where(fmt.Sprintf("((%s, id) %s (%%s, %%s) OR %s IS NULL)", column, op, column), arg.Cursor.Value, arg.Cursor.ID)
+		}
+	}
+
+	// The id keeps the order stable between pages
+	stmt := "SELECT id, author, headline, content, created_at, edited_at, status, publish_at, version FROM articles\n" +
+		query.whereClause() +
+		fmt.Sprintf("ORDER BY %s %s %s, id %s\n", column, direction, nulls, direction)
+	stmt += fmt.Sprintf("LIMIT %s", query.placeholder(arg.Limit))
+	if arg.Cursor == nil {
+		stmt += fmt.Sprintf(" OFFSET %s", query.placeholder(arg.Offset))
+	}
+
+	rows, err := q.db.QueryContext(ctx, stmt, query.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Article{}
+	for rows.Next() {
+		var i Article
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Headline,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+	return items, nil
+}
+
+// CountArticles counts articles matching the filters of the list. Sorting and paging params are ignored
+func (q *Queries) CountArticles(ctx context.Context, arg ListArticlesParams) (int64, error) {
+	query := filterArticles(arg)
+	stmt := "SELECT count(*) FROM articles\n" + query.whereClause()
+
+	var count int64
+	err := q.db.QueryRowContext(ctx, stmt, query.args...).Scan(&count)
+	return count, err
+}
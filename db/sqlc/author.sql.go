@@ -0,0 +1,129 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: author.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const getAuthor = `-- name: GetAuthor :one
+SELECT
+    u.username,
+    u.full_name,
+    u.created_at AS joined_at,
+    count(a.id) AS article_count,
+    latest.publish_at AS last_published_at
+FROM users u
+LEFT JOIN articles a ON a.author = u.username AND a.status = 'published'
+LEFT JOIN articles latest ON latest.id = (
+    SELECT id FROM articles
+    WHERE author = u.username AND status = 'published'
+    ORDER BY publish_at DESC, id DESC
+    LIMIT 1
+)
+WHERE u.username = $1
+GROUP BY u.username, latest.id
+`
+
+type GetAuthorRow struct {
+	Username        string       `json:"username"`
+	FullName        string       `json:"full_name"`
+	JoinedAt        time.Time    `json:"joined_at"`
+	ArticleCount    int64        `json:"article_count"`
+	LastPublishedAt sql.NullTime `json:"last_published_at"`
+}
+
+// Only published articles are counted, the others aren't public
+func (q *Queries) GetAuthor(ctx context.Context, username string) (GetAuthorRow, error) {
+	row := q.db.QueryRowContext(ctx, getAuthor, username)
+	var i GetAuthorRow
+	err := row.Scan(
+		&i.Username,
+		&i.FullName,
+		&i.JoinedAt,
+		&i.ArticleCount,
+		&i.LastPublishedAt,
+	)
+	return i, err
+}
+
+const listAuthors = `-- name: ListAuthors :many
+SELECT
+    u.username,
+    u.full_name,
+    u.created_at AS joined_at,
+    count(a.id) AS article_count,
+    latest.publish_at AS last_published_at
+FROM users u
+LEFT JOIN articles a ON a.author = u.username AND a.status = 'published'
+LEFT JOIN articles latest ON latest.id = (
+    SELECT id FROM articles
+    WHERE author = u.username AND status = 'published'
+    ORDER BY publish_at DESC, id DESC
+    LIMIT 1
+)
+GROUP BY u.username, latest.id
+ORDER BY
+    CASE WHEN $1::text = 'article_count' AND NOT $2::bool THEN count(a.id) END ASC,
+    CASE WHEN $1::text = 'article_count' AND $2::bool THEN count(a.id) END DESC,
+    CASE WHEN $1::text = 'joined_at' AND NOT $2::bool THEN u.created_at END ASC,
+    CASE WHEN $1::text = 'joined_at' AND $2::bool THEN u.created_at END DESC,
+    CASE WHEN NOT $2::bool THEN u.username END ASC,
+    CASE WHEN $2::bool THEN u.username END DESC
+LIMIT $4
+OFFSET $3
+`
+
+type ListAuthorsParams struct {
+	SortBy     string `json:"sort_by"`
+	Descending bool   `json:"descending"`
+	Offset     int32  `json:"offset"`
+	Limit      int32  `json:"limit"`
+}
+
+type ListAuthorsRow struct {
+	Username        string       `json:"username"`
+	FullName        string       `json:"full_name"`
+	JoinedAt        time.Time    `json:"joined_at"`
+	ArticleCount    int64        `json:"article_count"`
+	LastPublishedAt sql.NullTime `json:"last_published_at"`
+}
+
+func (q *Queries) ListAuthors(ctx context.Context, arg ListAuthorsParams) ([]ListAuthorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAuthors,
+		arg.SortBy,
+		arg.Descending,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAuthorsRow{}
+	for rows.Next() {
+		var i ListAuthorsRow
+		if err := rows.Scan(
+			&i.Username,
+			&i.FullName,
+			&i.JoinedAt,
+			&i.ArticleCount,
+			&i.LastPublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
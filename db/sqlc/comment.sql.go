@@ -0,0 +1,307 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: comment.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const countCommentsOfArticles = `-- name: CountCommentsOfArticles :many
+SELECT article_id, count(*) AS comment_count
+FROM comments
+WHERE article_id = ANY($1::bigint[]) AND deleted_at IS NULL
+GROUP BY article_id
+`
+
+type CountCommentsOfArticlesRow struct {
+	ArticleID    int64 `json:"article_id"`
+	CommentCount int64 `json:"comment_count"`
+}
+
+// Deleted comments aren't counted
+func (q *Queries) CountCommentsOfArticles(ctx context.Context, articleIds []int64) ([]CountCommentsOfArticlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, countCommentsOfArticles, pq.Array(articleIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountCommentsOfArticlesRow{}
+	for rows.Next() {
+		var i CountCommentsOfArticlesRow
+		if err := rows.Scan(&i.ArticleID, &i.CommentCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createComment = `-- name: CreateComment :one
+INSERT INTO comments (
+    article_id,
+    parent_id,
+    author,
+    content
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, article_id, parent_id, author, content, created_at, edited_at, deleted_at
+`
+
+type CreateCommentParams struct {
+	ArticleID int64         `json:"article_id"`
+	ParentID  sql.NullInt64 `json:"parent_id"`
+	Author    string        `json:"author"`
+	Content   string        `json:"content"`
+}
+
+func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, createComment,
+		arg.ArticleID,
+		arg.ParentID,
+		arg.Author,
+		arg.Content,
+	)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.ArticleID,
+		&i.ParentID,
+		&i.Author,
+		&i.Content,
+		&i.CreatedAt,
+		&i.EditedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const deleteComment = `-- name: DeleteComment :execrows
+DELETE FROM comments c
+WHERE c.id = $1 AND NOT EXISTS (
+    SELECT 1 FROM comments r WHERE r.parent_id = c.id
+)
+`
+
+// Only comments without replies are deleted, the others have to be marked with MarkCommentDeleted.
+// A reply inserted concurrently isn't seen by the check, the delete fails with foreign_key_violation then
+func (q *Queries) DeleteComment(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteComment, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getComment = `-- name: GetComment :one
+SELECT id, article_id, parent_id, author, content, created_at, edited_at, deleted_at FROM comments
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetComment(ctx context.Context, id int64) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, getComment, id)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.ArticleID,
+		&i.ParentID,
+		&i.Author,
+		&i.Content,
+		&i.CreatedAt,
+		&i.EditedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const listCommentReplies = `-- name: ListCommentReplies :many
+WITH RECURSIVE replies AS (
+    SELECT c.id FROM comments c
+    WHERE c.parent_id = ANY($1::bigint[])
+    UNION ALL
+    SELECT c.id FROM comments c
+    JOIN replies r ON c.parent_id = r.id
+)
+SELECT id, article_id, parent_id, author, content, created_at, edited_at, deleted_at FROM comments
+WHERE id IN (SELECT id FROM replies)
+ORDER BY created_at, id
+`
+
+// All replies in the threads of the given comments, however deep they are
+func (q *Queries) ListCommentReplies(ctx context.Context, parentIds []int64) ([]Comment, error) {
+	rows, err := q.db.QueryContext(ctx, listCommentReplies, pq.Array(parentIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Comment{}
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.ArticleID,
+			&i.ParentID,
+			&i.Author,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listComments = `-- name: ListComments :many
+SELECT id, article_id, parent_id, author, content, created_at, edited_at, deleted_at FROM comments
+WHERE article_id = $1
+ORDER BY created_at, id
+LIMIT $2
+OFFSET $3
+`
+
+type ListCommentsParams struct {
+	ArticleID int64 `json:"article_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+func (q *Queries) ListComments(ctx context.Context, arg ListCommentsParams) ([]Comment, error) {
+	rows, err := q.db.QueryContext(ctx, listComments, arg.ArticleID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Comment{}
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.ArticleID,
+			&i.ParentID,
+			&i.Author,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRootComments = `-- name: ListRootComments :many
+SELECT id, article_id, parent_id, author, content, created_at, edited_at, deleted_at FROM comments
+WHERE article_id = $1 AND parent_id IS NULL
+ORDER BY created_at, id
+LIMIT $2
+OFFSET $3
+`
+
+type ListRootCommentsParams struct {
+	ArticleID int64 `json:"article_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+func (q *Queries) ListRootComments(ctx context.Context, arg ListRootCommentsParams) ([]Comment, error) {
+	rows, err := q.db.QueryContext(ctx, listRootComments, arg.ArticleID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Comment{}
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.ArticleID,
+			&i.ParentID,
+			&i.Author,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markCommentDeleted = `-- name: MarkCommentDeleted :exec
+UPDATE comments
+SET
+    content = '',
+    deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) MarkCommentDeleted(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, markCommentDeleted, id)
+	return err
+}
+
+const updateComment = `-- name: UpdateComment :one
+UPDATE comments
+SET
+    content = $1,
+    edited_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, article_id, parent_id, author, content, created_at, edited_at, deleted_at
+`
+
+type UpdateCommentParams struct {
+	Content string `json:"content"`
+	ID      int64  `json:"id"`
+}
+
+func (q *Queries) UpdateComment(ctx context.Context, arg UpdateCommentParams) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, updateComment, arg.Content, arg.ID)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.ArticleID,
+		&i.ParentID,
+		&i.Author,
+		&i.Content,
+		&i.CreatedAt,
+		&i.EditedAt,
+		&i.DeletedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,43 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: consumed_token.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const consumeToken = `-- name: ConsumeToken :execrows
+WITH cleanup AS (
+    DELETE FROM consumed_tokens
+    WHERE expires_at < NOW()
+)
+INSERT INTO consumed_tokens (
+    id,
+    purpose,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (id) DO NOTHING
+`
+
+type ConsumeTokenParams struct {
+	ID        uuid.UUID `json:"id"`
+	Purpose   string    `json:"purpose"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Returns 0 if the token has been consumed already. Expired tokens are cleaned up on the way,
+// they are rejected by signature verification anyway
+func (q *Queries) ConsumeToken(ctx context.Context, arg ConsumeTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, consumeToken, arg.ID, arg.Purpose, arg.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
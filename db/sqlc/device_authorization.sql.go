@@ -0,0 +1,158 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: device_authorization.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createDeviceAuthorization = `-- name: CreateDeviceAuthorization :one
+WITH cleanup AS (
+    DELETE FROM device_authorizations
+    WHERE expires_at < NOW() - interval '1 day'
+)
+INSERT INTO device_authorizations (
+    hashed_device_code,
+    user_code,
+    poll_interval,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, hashed_device_code, user_code, status, username, poll_interval, last_polled_at, expires_at, created_at
+`
+
+type CreateDeviceAuthorizationParams struct {
+	HashedDeviceCode string    `json:"hashed_device_code"`
+	UserCode         string    `json:"user_code"`
+	PollInterval     int32     `json:"poll_interval"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// Expired authorizations are cleaned up on the way. They are kept for a day after expiring,
+// so that clients still polling get expired_token rather than invalid_grant
+func (q *Queries) CreateDeviceAuthorization(ctx context.Context, arg CreateDeviceAuthorizationParams) (DeviceAuthorization, error) {
+	row := q.db.QueryRowContext(ctx, createDeviceAuthorization,
+		arg.HashedDeviceCode,
+		arg.UserCode,
+		arg.PollInterval,
+		arg.ExpiresAt,
+	)
+	var i DeviceAuthorization
+	err := row.Scan(
+		&i.ID,
+		&i.HashedDeviceCode,
+		&i.UserCode,
+		&i.Status,
+		&i.Username,
+		&i.PollInterval,
+		&i.LastPolledAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const decideDeviceAuthorization = `-- name: DecideDeviceAuthorization :one
+UPDATE device_authorizations
+SET
+    status = $1,
+    username = $2
+WHERE user_code = $3
+    AND status = 'pending'
+    AND expires_at > NOW()
+RETURNING id, hashed_device_code, user_code, status, username, poll_interval, last_polled_at, expires_at, created_at
+`
+
+type DecideDeviceAuthorizationParams struct {
+	Status   string         `json:"status"`
+	Username sql.NullString `json:"username"`
+	UserCode string         `json:"user_code"`
+}
+
+func (q *Queries) DecideDeviceAuthorization(ctx context.Context, arg DecideDeviceAuthorizationParams) (DeviceAuthorization, error) {
+	row := q.db.QueryRowContext(ctx, decideDeviceAuthorization, arg.Status, arg.Username, arg.UserCode)
+	var i DeviceAuthorization
+	err := row.Scan(
+		&i.ID,
+		&i.HashedDeviceCode,
+		&i.UserCode,
+		&i.Status,
+		&i.Username,
+		&i.PollInterval,
+		&i.LastPolledAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteDeviceAuthorization = `-- name: DeleteDeviceAuthorization :execrows
+DELETE FROM device_authorizations
+WHERE id = $1
+`
+
+func (q *Queries) DeleteDeviceAuthorization(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDeviceAuthorization, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDeviceAuthorization = `-- name: GetDeviceAuthorization :one
+SELECT id, hashed_device_code, user_code, status, username, poll_interval, last_polled_at, expires_at, created_at FROM device_authorizations
+WHERE hashed_device_code = $1 LIMIT 1
+`
+
+func (q *Queries) GetDeviceAuthorization(ctx context.Context, hashedDeviceCode string) (DeviceAuthorization, error) {
+	row := q.db.QueryRowContext(ctx, getDeviceAuthorization, hashedDeviceCode)
+	var i DeviceAuthorization
+	err := row.Scan(
+		&i.ID,
+		&i.HashedDeviceCode,
+		&i.UserCode,
+		&i.Status,
+		&i.Username,
+		&i.PollInterval,
+		&i.LastPolledAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const pollDeviceAuthorization = `-- name: PollDeviceAuthorization :one
+UPDATE device_authorizations
+SET
+    last_polled_at = NOW(),
+    poll_interval = $2
+WHERE id = $1
+RETURNING id, hashed_device_code, user_code, status, username, poll_interval, last_polled_at, expires_at, created_at
+`
+
+type PollDeviceAuthorizationParams struct {
+	ID           int64 `json:"id"`
+	PollInterval int32 `json:"poll_interval"`
+}
+
+func (q *Queries) PollDeviceAuthorization(ctx context.Context, arg PollDeviceAuthorizationParams) (DeviceAuthorization, error) {
+	row := q.db.QueryRowContext(ctx, pollDeviceAuthorization, arg.ID, arg.PollInterval)
+	var i DeviceAuthorization
+	err := row.Scan(
+		&i.ID,
+		&i.HashedDeviceCode,
+		&i.UserCode,
+		&i.Status,
+		&i.Username,
+		&i.PollInterval,
+		&i.LastPolledAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
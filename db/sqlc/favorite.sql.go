@@ -0,0 +1,233 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: favorite.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+const addFavorite = `-- name: AddFavorite :exec
+INSERT INTO favorites (
+    username,
+    article_id
+) VALUES (
+    $1, $2
+) ON CONFLICT DO NOTHING
+`
+
+type AddFavoriteParams struct {
+	Username  string `json:"username"`
+	ArticleID int64  `json:"article_id"`
+}
+
+func (q *Queries) AddFavorite(ctx context.Context, arg AddFavoriteParams) error {
+	_, err := q.db.ExecContext(ctx, addFavorite, arg.Username, arg.ArticleID)
+	return err
+}
+
+const addReaction = `-- name: AddReaction :exec
+INSERT INTO article_reactions (
+    username,
+    article_id,
+    reaction
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT DO NOTHING
+`
+
+type AddReactionParams struct {
+	Username  string `json:"username"`
+	ArticleID int64  `json:"article_id"`
+	Reaction  string `json:"reaction"`
+}
+
+func (q *Queries) AddReaction(ctx context.Context, arg AddReactionParams) error {
+	_, err := q.db.ExecContext(ctx, addReaction, arg.Username, arg.ArticleID, arg.Reaction)
+	return err
+}
+
+const countFavoritesOfArticles = `-- name: CountFavoritesOfArticles :many
+SELECT
+    article_id,
+    count(*) AS favorite_count,
+    bool_or(username = $1::varchar)::boolean AS favorited
+FROM favorites
+WHERE article_id = ANY($2::bigint[])
+GROUP BY article_id
+`
+
+type CountFavoritesOfArticlesParams struct {
+	Username   string  `json:"username"`
+	ArticleIds []int64 `json:"article_ids"`
+}
+
+type CountFavoritesOfArticlesRow struct {
+	ArticleID     int64 `json:"article_id"`
+	FavoriteCount int64 `json:"favorite_count"`
+	Favorited     bool  `json:"favorited"`
+}
+
+// Favorited tells if the given user is one of those who favorited the article
+func (q *Queries) CountFavoritesOfArticles(ctx context.Context, arg CountFavoritesOfArticlesParams) ([]CountFavoritesOfArticlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, countFavoritesOfArticles, arg.Username, pq.Array(arg.ArticleIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountFavoritesOfArticlesRow{}
+	for rows.Next() {
+		var i CountFavoritesOfArticlesRow
+		if err := rows.Scan(&i.ArticleID, &i.FavoriteCount, &i.Favorited); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countReactionsOfArticles = `-- name: CountReactionsOfArticles :many
+SELECT
+    article_id,
+    reaction,
+    count(*) AS reaction_count,
+    bool_or(username = $1::varchar)::boolean AS reacted
+FROM article_reactions
+WHERE article_id = ANY($2::bigint[])
+GROUP BY article_id, reaction
+ORDER BY article_id, reaction
+`
+
+type CountReactionsOfArticlesParams struct {
+	Username   string  `json:"username"`
+	ArticleIds []int64 `json:"article_ids"`
+}
+
+type CountReactionsOfArticlesRow struct {
+	ArticleID     int64  `json:"article_id"`
+	Reaction      string `json:"reaction"`
+	ReactionCount int64  `json:"reaction_count"`
+	Reacted       bool   `json:"reacted"`
+}
+
+// Reacted tells if the given user left the reaction
+func (q *Queries) CountReactionsOfArticles(ctx context.Context, arg CountReactionsOfArticlesParams) ([]CountReactionsOfArticlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, countReactionsOfArticles, arg.Username, pq.Array(arg.ArticleIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountReactionsOfArticlesRow{}
+	for rows.Next() {
+		var i CountReactionsOfArticlesRow
+		if err := rows.Scan(
+			&i.ArticleID,
+			&i.Reaction,
+			&i.ReactionCount,
+			&i.Reacted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteFavorite = `-- name: DeleteFavorite :exec
+DELETE FROM favorites
+WHERE username = $1 AND article_id = $2
+`
+
+type DeleteFavoriteParams struct {
+	Username  string `json:"username"`
+	ArticleID int64  `json:"article_id"`
+}
+
+func (q *Queries) DeleteFavorite(ctx context.Context, arg DeleteFavoriteParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFavorite, arg.Username, arg.ArticleID)
+	return err
+}
+
+const deleteReaction = `-- name: DeleteReaction :exec
+DELETE FROM article_reactions
+WHERE username = $1 AND article_id = $2 AND reaction = $3
+`
+
+type DeleteReactionParams struct {
+	Username  string `json:"username"`
+	ArticleID int64  `json:"article_id"`
+	Reaction  string `json:"reaction"`
+}
+
+func (q *Queries) DeleteReaction(ctx context.Context, arg DeleteReactionParams) error {
+	_, err := q.db.ExecContext(ctx, deleteReaction, arg.Username, arg.ArticleID, arg.Reaction)
+	return err
+}
+
+const listFavoriteArticles = `-- name: ListFavoriteArticles :many
+SELECT a.id, a.author, a.headline, a.content, a.created_at, a.edited_at, a.status, a.publish_at, a.version, a.search_language, a.search_vector FROM articles a
+JOIN favorites f ON f.article_id = a.id
+WHERE f.username = $1
+    AND (a.status = 'published' OR a.author = $1)
+ORDER BY f.created_at DESC, a.id DESC
+LIMIT $3
+OFFSET $2
+`
+
+type ListFavoriteArticlesParams struct {
+	Username string `json:"username"`
+	Offset   int32  `json:"offset"`
+	Limit    int32  `json:"limit"`
+}
+
+// Most recently favorited first. Articles that aren't published anymore are only listed to their author
+func (q *Queries) ListFavoriteArticles(ctx context.Context, arg ListFavoriteArticlesParams) ([]Article, error) {
+	rows, err := q.db.QueryContext(ctx, listFavoriteArticles, arg.Username, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Article{}
+	for rows.Next() {
+		var i Article
+		if err := rows.Scan(
+			&i.ID,
+			&i.Author,
+			&i.Headline,
+			&i.Content,
+			&i.CreatedAt,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Version,
+			&i.SearchLanguage,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
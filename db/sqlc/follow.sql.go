@@ -0,0 +1,135 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: follow.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createFollow = `-- name: CreateFollow :exec
+INSERT INTO follows (
+    follower,
+    followee
+) VALUES (
+    $1, $2
+) ON CONFLICT DO NOTHING
+`
+
+type CreateFollowParams struct {
+	Follower string `json:"follower"`
+	Followee string `json:"followee"`
+}
+
+func (q *Queries) CreateFollow(ctx context.Context, arg CreateFollowParams) error {
+	_, err := q.db.ExecContext(ctx, createFollow, arg.Follower, arg.Followee)
+	return err
+}
+
+const deleteFollow = `-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower = $1 AND followee = $2
+`
+
+type DeleteFollowParams struct {
+	Follower string `json:"follower"`
+	Followee string `json:"followee"`
+}
+
+func (q *Queries) DeleteFollow(ctx context.Context, arg DeleteFollowParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFollow, arg.Follower, arg.Followee)
+	return err
+}
+
+const listFollowers = `-- name: ListFollowers :many
+SELECT u.username, u.full_name, f.created_at AS followed_at
+FROM follows f
+JOIN users u ON u.username = f.follower
+WHERE f.followee = $1
+ORDER BY f.created_at DESC, u.username
+LIMIT $2
+OFFSET $3
+`
+
+type ListFollowersParams struct {
+	Followee string `json:"followee"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
+}
+
+type ListFollowersRow struct {
+	Username   string    `json:"username"`
+	FullName   string    `json:"full_name"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (q *Queries) ListFollowers(ctx context.Context, arg ListFollowersParams) ([]ListFollowersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowers, arg.Followee, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListFollowersRow{}
+	for rows.Next() {
+		var i ListFollowersRow
+		if err := rows.Scan(&i.Username, &i.FullName, &i.FollowedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowing = `-- name: ListFollowing :many
+SELECT u.username, u.full_name, f.created_at AS followed_at
+FROM follows f
+JOIN users u ON u.username = f.followee
+WHERE f.follower = $1
+ORDER BY f.created_at DESC, u.username
+LIMIT $2
+OFFSET $3
+`
+
+type ListFollowingParams struct {
+	Follower string `json:"follower"`
+	Limit    int32  `json:"limit"`
+	Offset   int32  `json:"offset"`
+}
+
+type ListFollowingRow struct {
+	Username   string    `json:"username"`
+	FullName   string    `json:"full_name"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (q *Queries) ListFollowing(ctx context.Context, arg ListFollowingParams) ([]ListFollowingRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowing, arg.Follower, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListFollowingRow{}
+	for rows.Next() {
+		var i ListFollowingRow
+		if err := rows.Scan(&i.Username, &i.FullName, &i.FollowedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,105 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: impersonation_log.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const createImpersonationLogEntry = `-- name: CreateImpersonationLogEntry :exec
+INSERT INTO impersonation_log (
+    session_id,
+    impersonator,
+    username,
+    method,
+    path,
+    status,
+    client_ip
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+`
+
+type CreateImpersonationLogEntryParams struct {
+	SessionID    uuid.UUID `json:"session_id"`
+	Impersonator string    `json:"impersonator"`
+	Username     string    `json:"username"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Status       int32     `json:"status"`
+	ClientIp     string    `json:"client_ip"`
+}
+
+func (q *Queries) CreateImpersonationLogEntry(ctx context.Context, arg CreateImpersonationLogEntryParams) error {
+	_, err := q.db.ExecContext(ctx, createImpersonationLogEntry,
+		arg.SessionID,
+		arg.Impersonator,
+		arg.Username,
+		arg.Method,
+		arg.Path,
+		arg.Status,
+		arg.ClientIp,
+	)
+	return err
+}
+
+const listImpersonationLog = `-- name: ListImpersonationLog :many
+SELECT id, session_id, impersonator, username, method, path, status, client_ip, created_at FROM impersonation_log
+WHERE ($1::varchar IS NULL OR impersonator = $1::varchar)
+    AND ($2::varchar IS NULL OR username = $2::varchar)
+ORDER BY id DESC
+LIMIT $4
+OFFSET $3
+`
+
+type ListImpersonationLogParams struct {
+	Impersonator sql.NullString `json:"impersonator"`
+	Username     sql.NullString `json:"username"`
+	Offset       int32          `json:"offset"`
+	Limit        int32          `json:"limit"`
+}
+
+// Filters are skipped when null
+func (q *Queries) ListImpersonationLog(ctx context.Context, arg ListImpersonationLogParams) ([]ImpersonationLog, error) {
+	rows, err := q.db.QueryContext(ctx, listImpersonationLog,
+		arg.Impersonator,
+		arg.Username,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ImpersonationLog{}
+	for rows.Next() {
+		var i ImpersonationLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Impersonator,
+			&i.Username,
+			&i.Method,
+			&i.Path,
+			&i.Status,
+			&i.ClientIp,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -3,12 +3,14 @@ package db
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang-migrate/migrate"
 	"github.com/golang-migrate/migrate/database/postgres"
 	_ "github.com/golang-migrate/migrate/source/file"
+	"github.com/google/uuid"
 	"github.com/kamilwrzyszcz/go_example/util"
 	_ "github.com/lib/pq"
 	"github.com/stretchr/testify/suite"
@@ -51,9 +53,12 @@ func createRandomArticle(its *DBIntegrationTestSuite) Article {
 	user := createRandomUser(its)
 
 	arg := CreateArticleParams{
-		Author:   user.Username,
-		Headline: util.RandomString(15),
-		Content:  util.RandomString(25),
+		Author:         user.Username,
+		Headline:       util.RandomString(15),
+		Content:        util.RandomString(25),
+		Status:         util.ArticlePublished,
+		SearchLanguage: "english",
+		PublishAt:      sql.NullTime{Time: time.Now(), Valid: true},
 	}
 
 	article, err := its.store.CreateArticle(context.Background(), arg)
@@ -119,6 +124,40 @@ func (its *DBIntegrationTestSuite) TestGetUser() {
 	its.Empty(user3)
 }
 
+func (its *DBIntegrationTestSuite) TestUpdateUserHashedPassword() {
+	user1 := createRandomUser(its)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	its.NoError(err)
+
+	err = its.store.UpdateUserHashedPassword(context.Background(), UpdateUserHashedPasswordParams{
+		Username:       user1.Username,
+		HashedPassword: hashedPassword,
+	})
+	its.NoError(err)
+
+	user2, err := its.store.GetUser(context.Background(), user1.Username)
+	its.NoError(err)
+	its.Equal(hashedPassword, user2.HashedPassword)
+	// Rehashing is not a password change
+	its.WithinDuration(user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
+}
+
+func (its *DBIntegrationTestSuite) TestUpdateUserPassword() {
+	user1 := createRandomUser(its)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	its.NoError(err)
+
+	user2, err := its.store.UpdateUserPassword(context.Background(), UpdateUserPasswordParams{
+		Username:       user1.Username,
+		HashedPassword: hashedPassword,
+	})
+	its.NoError(err)
+	its.Equal(hashedPassword, user2.HashedPassword)
+	its.WithinDuration(time.Now(), user2.PasswordChangedAt, time.Second)
+}
+
 func (its *DBIntegrationTestSuite) TestCreateArticle() {
 	createRandomArticle(its)
 }
@@ -189,8 +228,9 @@ func (its *DBIntegrationTestSuite) TestUpdateArticle() {
 
 func (its *DBIntegrationTestSuite) TestDeleteArticle() {
 	article1 := createRandomArticle(its)
-	err := its.store.DeleteArticle(context.Background(), article1.ID)
+	deleted, err := its.store.DeleteArticle(context.Background(), DeleteArticleParams{ID: article1.ID})
 	its.NoError(err)
+	its.Equal(int64(1), deleted)
 
 	article2, err := its.store.GetArticle(context.Background(), article1.ID)
 	its.Error(err)
@@ -205,9 +245,9 @@ func (its *DBIntegrationTestSuite) TestListArticles() {
 	}
 
 	arg := ListArticlesParams{
-		Author: lastArticle.Author,
-		Limit:  5,
-		Offset: 0,
+		Authors: []string{lastArticle.Author},
+		Limit:   5,
+		Offset:  0,
 	}
 
 	articles, err := its.store.ListArticles(context.Background(), arg)
@@ -220,6 +260,1284 @@ func (its *DBIntegrationTestSuite) TestListArticles() {
 	}
 }
 
+func (its *DBIntegrationTestSuite) TestListArticlesFilters() {
+	first := createRandomArticle(its)
+	second := createRandomArticle(its)
+	edited, err := its.store.UpdateArticle(context.Background(), UpdateArticleParams{
+		ID:       second.ID,
+		Headline: sql.NullString{String: "aaa " + second.Headline, Valid: true},
+	})
+	its.NoError(err)
+
+	arg := ListArticlesParams{
+		Authors:  []string{first.Author, second.Author},
+		SortBy:   ArticleSortHeadline,
+		SortDesc: false,
+		Limit:    5,
+	}
+	articles, err := its.store.ListArticles(context.Background(), arg)
+	its.NoError(err)
+	its.Len(articles, 2)
+	its.Equal(edited.ID, articles[0].ID)
+
+	arg.Edited = sql.NullBool{Bool: false, Valid: true}
+	articles, err = its.store.ListArticles(context.Background(), arg)
+	its.NoError(err)
+	its.Len(articles, 1)
+	its.Equal(first.ID, articles[0].ID)
+
+	arg.Edited = sql.NullBool{}
+	arg.CreatedAfter = sql.NullTime{Time: second.CreatedAt, Valid: true}
+	arg.SortBy = ArticleSortEditedAt
+	articles, err = its.store.ListArticles(context.Background(), arg)
+	its.NoError(err)
+	its.Len(articles, 1)
+	its.Equal(second.ID, articles[0].ID)
+
+	count, err := its.store.CountArticles(context.Background(), ListArticlesParams{
+		Authors: []string{first.Author, second.Author},
+		Limit:   1,
+	})
+	its.NoError(err)
+	its.Equal(int64(2), count)
+
+	// Only whitelisted columns can be used for sorting
+	arg.SortBy = "content; DROP TABLE articles"
+	_, err = its.store.ListArticles(context.Background(), arg)
+	its.Error(err)
+}
+
+func (its *DBIntegrationTestSuite) TestListPublicArticles() {
+	var lastArticle Article
+	for i := 0; i < 3; i++ {
+		lastArticle = createRandomArticle(its)
+	}
+
+	// Newest of all authors first
+	articles, err := its.store.ListPublicArticles(context.Background(), ListPublicArticlesParams{
+		Limit: 5,
+	})
+	its.NoError(err)
+	its.NotEmpty(articles)
+	its.Equal(lastArticle.ID, articles[0].ID)
+
+	articles, err = its.store.ListPublicArticles(context.Background(), ListPublicArticlesParams{
+		Author: sql.NullString{String: lastArticle.Author, Valid: true},
+		Limit:  5,
+	})
+	its.NoError(err)
+	its.NotEmpty(articles)
+	for _, article := range articles {
+		its.Equal(lastArticle.Author, article.Author)
+	}
+}
+
+func (its *DBIntegrationTestSuite) TestEnableAndDisableTOTPTx() {
+	user1 := createRandomUser(its)
+
+	user2, err := its.store.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		Username:   user1.Username,
+		TotpSecret: "SECRET",
+	})
+	its.NoError(err)
+	its.Equal("SECRET", user2.TotpSecret)
+	its.False(user2.TotpEnabled)
+
+	user3, err := its.store.EnableTOTPTx(context.Background(), EnableTOTPTxParams{
+		Username:            user1.Username,
+		HashedRecoveryCodes: []string{"code1", "code2"},
+	})
+	its.NoError(err)
+	its.True(user3.TotpEnabled)
+
+	// Recovery code can be used only once
+	code, err := its.store.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   user1.Username,
+		HashedCode: "code1",
+	})
+	its.NoError(err)
+	its.True(code.UsedAt.Valid)
+
+	_, err = its.store.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   user1.Username,
+		HashedCode: "code1",
+	})
+	its.ErrorIs(err, sql.ErrNoRows)
+
+	user4, err := its.store.DisableTOTPTx(context.Background(), user1.Username)
+	its.NoError(err)
+	its.False(user4.TotpEnabled)
+	its.Empty(user4.TotpSecret)
+
+	_, err = its.store.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username:   user1.Username,
+		HashedCode: "code2",
+	})
+	its.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (its *DBIntegrationTestSuite) TestUseUserTOTPStep() {
+	user := createRandomUser(its)
+
+	rows, err := its.store.UseUserTOTPStep(context.Background(), UseUserTOTPStepParams{
+		Username: user.Username,
+		Step:     100,
+	})
+	its.NoError(err)
+	its.Equal(int64(1), rows)
+
+	// Same or older step can't be used again
+	rows, err = its.store.UseUserTOTPStep(context.Background(), UseUserTOTPStepParams{
+		Username: user.Username,
+		Step:     100,
+	})
+	its.NoError(err)
+	its.Zero(rows)
+}
+
+func (its *DBIntegrationTestSuite) TestGetAuthor() {
+	article := createRandomArticle(its)
+
+	author, err := its.store.GetAuthor(context.Background(), article.Author)
+	its.NoError(err)
+	its.Equal(article.Author, author.Username)
+	its.Equal(int64(1), author.ArticleCount)
+	its.True(author.LastPublishedAt.Valid)
+	its.WithinDuration(article.PublishAt.Time, author.LastPublishedAt.Time, time.Second)
+
+	// Drafts and scheduled articles aren't public, so they don't show up in the stats
+	for _, status := range []string{util.ArticleDraft, util.ArticleScheduled} {
+		arg := CreateArticleParams{
+			Author:         article.Author,
+			Headline:       util.RandomString(10),
+			Content:        util.RandomString(25),
+			Status:         status,
+			SearchLanguage: "english",
+		}
+		if status == util.ArticleScheduled {
+			arg.PublishAt = sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+		}
+		_, err = its.store.CreateArticle(context.Background(), arg)
+		its.NoError(err)
+	}
+	author, err = its.store.GetAuthor(context.Background(), article.Author)
+	its.NoError(err)
+	its.Equal(int64(1), author.ArticleCount)
+	its.WithinDuration(article.PublishAt.Time, author.LastPublishedAt.Time, time.Second)
+
+	// Author without articles
+	user := createRandomUser(its)
+	author, err = its.store.GetAuthor(context.Background(), user.Username)
+	its.NoError(err)
+	its.Zero(author.ArticleCount)
+	its.False(author.LastPublishedAt.Valid)
+
+	_, err = its.store.GetAuthor(context.Background(), "non-existing-user")
+	its.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (its *DBIntegrationTestSuite) TestListAuthors() {
+	for i := 0; i < 3; i++ {
+		createRandomArticle(its)
+	}
+
+	authors, err := its.store.ListAuthors(context.Background(), ListAuthorsParams{
+		SortBy:     "article_count",
+		Descending: true,
+		Limit:      5,
+		Offset:     0,
+	})
+	its.NoError(err)
+	its.NotEmpty(authors)
+
+	for i := 1; i < len(authors); i++ {
+		its.GreaterOrEqual(authors[i-1].ArticleCount, authors[i].ArticleCount)
+	}
+}
+
+func (its *DBIntegrationTestSuite) TestCreateUserTx() {
+	admin := createRandomUser(its)
+	its.Equal("user", admin.Role)
+
+	invitation, err := its.store.CreateInvitation(context.Background(), CreateInvitationParams{
+		HashedCode: util.RandomString(32),
+		Email:      sql.NullString{String: "Invited@Example.com", Valid: true},
+		CreatedBy:  admin.Username,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	})
+	its.NoError(err)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	its.NoError(err)
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomAuthor(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomAuthor(),
+			Email:          util.RandomEmail(),
+		},
+		HashedInvitationCode: invitation.HashedCode,
+	}
+
+	// Invitation is bound to a different email, user isn't created
+	_, err = its.store.CreateUserTx(context.Background(), arg)
+	its.ErrorIs(err, ErrInvalidInvitation)
+	_, err = its.store.GetUser(context.Background(), arg.Username)
+	its.ErrorIs(err, sql.ErrNoRows)
+
+	arg.Email = "invited@example.com"
+	user, err := its.store.CreateUserTx(context.Background(), arg)
+	its.NoError(err)
+	its.Equal(arg.Username, user.Username)
+
+	// Invitation can be used only once
+	arg.Username = util.RandomAuthor()
+	_, err = its.store.CreateUserTx(context.Background(), arg)
+	its.ErrorIs(err, ErrInvalidInvitation)
+
+	_, err = its.store.RevokeInvitation(context.Background(), invitation.ID)
+	its.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (its *DBIntegrationTestSuite) TestChangeUsernameTx() {
+	article := createRandomArticle(its)
+	oldUsername := article.Author
+	newUsername := util.RandomAuthor()
+
+	user, err := its.store.ChangeUsernameTx(context.Background(), ChangeUsernameTxParams{
+		Username:    oldUsername,
+		NewUsername: newUsername,
+	})
+	its.NoError(err)
+	its.Equal(newUsername, user.Username)
+
+	// Articles follow the user
+	article2, err := its.store.GetArticle(context.Background(), article.ID)
+	its.NoError(err)
+	its.Equal(newUsername, article2.Author)
+
+	redirect, err := its.store.GetUsernameRedirect(context.Background(), oldUsername)
+	its.NoError(err)
+	its.Equal(newUsername, redirect)
+
+	// Older redirects point to the current username after another rename
+	newestUsername := util.RandomAuthor()
+	_, err = its.store.ChangeUsernameTx(context.Background(), ChangeUsernameTxParams{
+		Username:    newUsername,
+		NewUsername: newestUsername,
+	})
+	its.NoError(err)
+
+	redirect, err = its.store.GetUsernameRedirect(context.Background(), oldUsername)
+	its.NoError(err)
+	its.Equal(newestUsername, redirect)
+
+	// Taking the old username back removes its redirect
+	_, err = its.store.ChangeUsernameTx(context.Background(), ChangeUsernameTxParams{
+		Username:    newestUsername,
+		NewUsername: oldUsername,
+	})
+	its.NoError(err)
+
+	_, err = its.store.GetUsernameRedirect(context.Background(), oldUsername)
+	its.ErrorIs(err, sql.ErrNoRows)
+
+	// Username taken by another user
+	other := createRandomUser(its)
+	_, err = its.store.ChangeUsernameTx(context.Background(), ChangeUsernameTxParams{
+		Username:    oldUsername,
+		NewUsername: other.Username,
+	})
+	its.Error(err)
+}
+
+func (its *DBIntegrationTestSuite) TestFollowsAndFeed() {
+	reader := createRandomUser(its)
+	article1 := createRandomArticle(its)
+	article2 := createRandomArticle(its)
+	createRandomArticle(its)
+
+	for _, article := range []Article{article1, article2} {
+		err := its.store.CreateFollow(context.Background(), CreateFollowParams{
+			Follower: reader.Username,
+			Followee: article.Author,
+		})
+		its.NoError(err)
+	}
+
+	// Following twice is not an error
+	err := its.store.CreateFollow(context.Background(), CreateFollowParams{
+		Follower: reader.Username,
+		Followee: article1.Author,
+	})
+	its.NoError(err)
+
+	followers, err := its.store.ListFollowers(context.Background(), ListFollowersParams{
+		Followee: article1.Author,
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Len(followers, 1)
+	its.Equal(reader.Username, followers[0].Username)
+
+	following, err := its.store.ListFollowing(context.Background(), ListFollowingParams{
+		Follower: reader.Username,
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Len(following, 2)
+
+	// Feed holds only articles of followed authors, newest first
+	feed, err := its.store.ListFeedArticles(context.Background(), ListFeedArticlesParams{
+		Follower: reader.Username,
+		Limit:    1,
+	})
+	its.NoError(err)
+	its.Len(feed, 1)
+	its.Equal(article2.ID, feed[0].ID)
+
+	feed, err = its.store.ListFeedArticles(context.Background(), ListFeedArticlesParams{
+		Follower:        reader.Username,
+		CursorCreatedAt: sql.NullTime{Time: feed[0].CreatedAt, Valid: true},
+		CursorID:        sql.NullInt64{Int64: feed[0].ID, Valid: true},
+		Limit:           5,
+	})
+	its.NoError(err)
+	its.Len(feed, 1)
+	its.Equal(article1.ID, feed[0].ID)
+
+	err = its.store.DeleteFollow(context.Background(), DeleteFollowParams{
+		Follower: reader.Username,
+		Followee: article1.Author,
+	})
+	its.NoError(err)
+
+	// Users can't follow themselves
+	err = its.store.CreateFollow(context.Background(), CreateFollowParams{
+		Follower: reader.Username,
+		Followee: reader.Username,
+	})
+	its.Error(err)
+}
+
+func (its *DBIntegrationTestSuite) TestGetUserByLogin() {
+	user := createRandomUser(its)
+
+	for _, login := range []string{user.Username, strings.ToUpper(user.Username), user.Email, strings.ToUpper(user.Email)} {
+		got, err := its.store.GetUserByLogin(context.Background(), login)
+		its.NoError(err)
+		its.Equal(user.Username, got.Username)
+	}
+
+	_, err := its.store.GetUserByLogin(context.Background(), "non-existing-user")
+	its.ErrorIs(err, sql.ErrNoRows)
+
+	// Usernames are unique regardless of case
+	_, err = its.store.CreateUser(context.Background(), CreateUserParams{
+		Username:       strings.ToUpper(user.Username),
+		HashedPassword: user.HashedPassword,
+		FullName:       user.FullName,
+		Email:          util.RandomEmail(),
+	})
+	its.Error(err)
+
+	// Emails have to be stored lowercase
+	_, err = its.store.CreateUser(context.Background(), CreateUserParams{
+		Username:       util.RandomAuthor(),
+		HashedPassword: user.HashedPassword,
+		FullName:       user.FullName,
+		Email:          "Mixed@Example.com",
+	})
+	its.Error(err)
+}
+
+func (its *DBIntegrationTestSuite) TestConsumeToken() {
+	id, err := uuid.NewRandom()
+	its.NoError(err)
+
+	arg := ConsumeTokenParams{
+		ID:        id,
+		Purpose:   "magic_link",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	rows, err := its.store.ConsumeToken(context.Background(), arg)
+	its.NoError(err)
+	its.Equal(int64(1), rows)
+
+	// Replay is rejected
+	rows, err = its.store.ConsumeToken(context.Background(), arg)
+	its.NoError(err)
+	its.Equal(int64(0), rows)
+}
+
+func (its *DBIntegrationTestSuite) TestDeviceAuthorization() {
+	user := createRandomUser(its)
+
+	authorization, err := its.store.CreateDeviceAuthorization(context.Background(), CreateDeviceAuthorizationParams{
+		HashedDeviceCode: util.RandomString(64),
+		UserCode:         util.RandomString(8),
+		PollInterval:     5,
+		ExpiresAt:        time.Now().Add(time.Minute),
+	})
+	its.NoError(err)
+	its.Equal("pending", authorization.Status)
+	its.False(authorization.Username.Valid)
+
+	polled, err := its.store.PollDeviceAuthorization(context.Background(), PollDeviceAuthorizationParams{
+		ID:           authorization.ID,
+		PollInterval: 10,
+	})
+	its.NoError(err)
+	its.Equal(int32(10), polled.PollInterval)
+	its.True(polled.LastPolledAt.Valid)
+
+	arg := DecideDeviceAuthorizationParams{
+		Status:   "approved",
+		Username: sql.NullString{String: user.Username, Valid: true},
+		UserCode: authorization.UserCode,
+	}
+	approved, err := its.store.DecideDeviceAuthorization(context.Background(), arg)
+	its.NoError(err)
+	its.Equal("approved", approved.Status)
+	its.Equal(user.Username, approved.Username.String)
+
+	// Can be decided only once
+	_, err = its.store.DecideDeviceAuthorization(context.Background(), arg)
+	its.ErrorIs(err, sql.ErrNoRows)
+
+	got, err := its.store.GetDeviceAuthorization(context.Background(), authorization.HashedDeviceCode)
+	its.NoError(err)
+	its.Equal(approved.Status, got.Status)
+
+	rows, err := its.store.DeleteDeviceAuthorization(context.Background(), authorization.ID)
+	its.NoError(err)
+	its.Equal(int64(1), rows)
+
+	rows, err = its.store.DeleteDeviceAuthorization(context.Background(), authorization.ID)
+	its.NoError(err)
+	its.Equal(int64(0), rows)
+}
+
+func (its *DBIntegrationTestSuite) TestDeviceAuthorizationCleanup() {
+	ctx := context.Background()
+	create := func(expiresAt time.Time) DeviceAuthorization {
+		authorization, err := its.store.CreateDeviceAuthorization(ctx, CreateDeviceAuthorizationParams{
+			HashedDeviceCode: util.RandomString(64),
+			UserCode:         util.RandomString(8),
+			PollInterval:     5,
+			ExpiresAt:        expiresAt,
+		})
+		its.NoError(err)
+		return authorization
+	}
+
+	expired := create(time.Now().Add(-time.Minute))
+	abandoned := create(time.Now().Add(-48 * time.Hour))
+	// Creating another one cleans up the old ones
+	create(time.Now().Add(time.Minute))
+
+	// Recently expired ones are still found, so that polling clients learn they expired
+	got, err := its.store.GetDeviceAuthorization(ctx, expired.HashedDeviceCode)
+	its.NoError(err)
+	its.Equal(expired.ID, got.ID)
+	its.True(got.ExpiresAt.Before(time.Now()))
+
+	_, err = its.store.GetDeviceAuthorization(ctx, abandoned.HashedDeviceCode)
+	its.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (its *DBIntegrationTestSuite) TestUserAdministration() {
+	user := createRandomUser(its)
+
+	disabled, err := its.store.DisableUser(context.Background(), user.Username)
+	its.NoError(err)
+	its.True(disabled.DisabledAt.Valid)
+
+	// Disabling again keeps the original time
+	again, err := its.store.DisableUser(context.Background(), user.Username)
+	its.NoError(err)
+	its.WithinDuration(disabled.DisabledAt.Time, again.DisabledAt.Time, 0)
+
+	users, err := its.store.ListUsers(context.Background(), ListUsersParams{
+		Search:   sql.NullString{String: strings.ToUpper(user.Username), Valid: true},
+		Disabled: sql.NullBool{Bool: true, Valid: true},
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Len(users, 1)
+	its.Equal(user.Username, users[0].Username)
+
+	enabled, err := its.store.EnableUser(context.Background(), user.Username)
+	its.NoError(err)
+	its.False(enabled.DisabledAt.Valid)
+
+	users, err = its.store.ListUsers(context.Background(), ListUsersParams{
+		Search:   sql.NullString{String: user.Username, Valid: true},
+		Disabled: sql.NullBool{Bool: true, Valid: true},
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Empty(users)
+
+	promoted, err := its.store.SetUserRole(context.Background(), SetUserRoleParams{
+		Username: user.Username,
+		Role:     "admin",
+	})
+	its.NoError(err)
+	its.Equal("admin", promoted.Role)
+
+	resetRequired, err := its.store.RequirePasswordReset(context.Background(), user.Username)
+	its.NoError(err)
+	its.True(resetRequired.PasswordResetRequired)
+
+	// Setting a new password clears the requirement
+	updated, err := its.store.UpdateUserPassword(context.Background(), UpdateUserPasswordParams{
+		Username:       user.Username,
+		HashedPassword: user.HashedPassword,
+	})
+	its.NoError(err)
+	its.False(updated.PasswordResetRequired)
+}
+
+func (its *DBIntegrationTestSuite) TestImpersonationLog() {
+	admin := createRandomUser(its)
+	user := createRandomUser(its)
+	sessionID, err := uuid.NewRandom()
+	its.NoError(err)
+
+	for _, path := range []string{"/feed", "/articles"} {
+		err = its.store.CreateImpersonationLogEntry(context.Background(), CreateImpersonationLogEntryParams{
+			SessionID:    sessionID,
+			Impersonator: admin.Username,
+			Username:     user.Username,
+			Method:       "GET",
+			Path:         path,
+			Status:       200,
+			ClientIp:     "127.0.0.1",
+		})
+		its.NoError(err)
+	}
+
+	entries, err := its.store.ListImpersonationLog(context.Background(), ListImpersonationLogParams{
+		Impersonator: sql.NullString{String: admin.Username, Valid: true},
+		Limit:        5,
+	})
+	its.NoError(err)
+	its.Len(entries, 2)
+	// Newest first
+	its.Equal("/articles", entries[0].Path)
+	its.Equal(user.Username, entries[0].Username)
+
+	entries, err = its.store.ListImpersonationLog(context.Background(), ListImpersonationLogParams{
+		Username: sql.NullString{String: admin.Username, Valid: true},
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Empty(entries)
+}
+
+func (its *DBIntegrationTestSuite) TestLoginEvents() {
+	user := createRandomUser(its)
+	ctx := context.Background()
+	device := GetLoginDeviceHistoryParams{
+		Username:  user.Username,
+		Ip:        "192.0.2.1",
+		UserAgent: "curl/8.0",
+	}
+
+	history, err := its.store.GetLoginDeviceHistory(ctx, device)
+	its.NoError(err)
+	its.False(history.HasLogins)
+	its.False(history.KnownDevice)
+
+	// Failed attempts don't make the device known
+	for _, outcome := range []string{"failed", "success"} {
+		err = its.store.CreateLoginEvent(ctx, CreateLoginEventParams{
+			Login:     user.Username,
+			Username:  sql.NullString{String: user.Username, Valid: true},
+			Outcome:   outcome,
+			Ip:        "192.0.2.1",
+			UserAgent: "Firefox",
+		})
+		its.NoError(err)
+	}
+	err = its.store.CreateLoginEvent(ctx, CreateLoginEventParams{
+		Login:     user.Username,
+		Username:  sql.NullString{String: user.Username, Valid: true},
+		Outcome:   "failed",
+		Ip:        device.Ip,
+		UserAgent: device.UserAgent,
+	})
+	its.NoError(err)
+
+	history, err = its.store.GetLoginDeviceHistory(ctx, device)
+	its.NoError(err)
+	its.True(history.HasLogins)
+	its.False(history.KnownDevice)
+
+	device.UserAgent = "Firefox"
+	history, err = its.store.GetLoginDeviceHistory(ctx, device)
+	its.NoError(err)
+	its.True(history.KnownDevice)
+
+	events, err := its.store.ListLoginEvents(ctx, ListLoginEventsParams{
+		Username: user.Username,
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Len(events, 3)
+	// Newest first
+	its.Equal("curl/8.0", events[0].UserAgent)
+	its.Equal("success", events[1].Outcome)
+}
+
+func (its *DBIntegrationTestSuite) TestListArticlesCursor() {
+	user := createRandomUser(its)
+	ctx := context.Background()
+
+	var ids []int64
+	for i := 0; i < 5; i++ {
+		article, err := its.store.CreateArticle(ctx, CreateArticleParams{
+			Author:         user.Username,
+			Headline:       util.RandomString(10),
+			Content:        util.RandomString(20),
+			Status:         util.ArticlePublished,
+			SearchLanguage: "english",
+		})
+		its.NoError(err)
+		ids = append(ids, article.ID)
+	}
+	// Edited articles come first when sorting by edited_at, the rest by id
+	for _, id := range []int64{ids[3], ids[1]} {
+		_, err := its.store.UpdateArticle(ctx, UpdateArticleParams{
+			ID:      id,
+			Content: sql.NullString{String: util.RandomString(20), Valid: true},
+		})
+		its.NoError(err)
+	}
+	order := []int64{ids[3], ids[1], ids[0], ids[2], ids[4]}
+
+	// Walk the whole list forward two at a time, then back from the end
+	arg := ListArticlesParams{
+		Authors: []string{user.Username},
+		SortBy:  ArticleSortEditedAt,
+		Limit:   2,
+	}
+	var forward []Article
+	for {
+		articles, err := its.store.ListArticles(ctx, arg)
+		its.NoError(err)
+		if len(articles) == 0 {
+			break
+		}
+		forward = append(forward, articles...)
+		last := articles[len(articles)-1]
+		arg.Cursor = &ArticleCursor{ID: last.ID}
+		if last.EditedAt.Valid {
+			arg.Cursor.Value = last.EditedAt.Time
+		}
+	}
+	its.Len(forward, len(order))
+	for i, article := range forward {
+		its.Equal(order[i], article.ID)
+	}
+
+	arg.Cursor = &ArticleCursor{ID: ids[4], Backward: true}
+	articles, err := its.store.ListArticles(ctx, arg)
+	its.NoError(err)
+	its.Len(articles, 2)
+	its.Equal(order[2], articles[0].ID)
+	its.Equal(order[3], articles[1].ID)
+
+	arg.Cursor = &ArticleCursor{Value: forward[2].CreatedAt, ID: ids[0], Backward: true}
+	arg.SortBy = ArticleSortCreatedAt
+	arg.SortDesc = true
+	articles, err = its.store.ListArticles(ctx, arg)
+	its.NoError(err)
+	its.Len(articles, 2)
+	its.Equal(ids[2], articles[0].ID)
+	its.Equal(ids[1], articles[1].ID)
+}
+
+func (its *DBIntegrationTestSuite) TestSearchArticles() {
+	user := createRandomUser(its)
+	ctx := context.Background()
+	marker := util.RandomString(12)
+
+	inHeadline, err := its.store.CreateArticle(ctx, CreateArticleParams{
+		Author:         user.Username,
+		Headline:       "Running " + marker,
+		Content:        util.RandomString(30),
+		Status:         util.ArticlePublished,
+		SearchLanguage: "english",
+	})
+	its.NoError(err)
+	inContent, err := its.store.CreateArticle(ctx, CreateArticleParams{
+		Author:         user.Username,
+		Headline:       util.RandomString(10),
+		Content:        "Some <script>alert(1)</script> words before. They kept running " + marker + " yesterday",
+		Status:         util.ArticlePublished,
+		SearchLanguage: "english",
+	})
+	its.NoError(err)
+
+	results, err := its.store.SearchArticles(ctx, SearchArticlesParams{
+		Language: "english",
+		Query:    "run " + marker,
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Len(results, 2)
+	// Headline matches rank higher
+	its.Equal(inHeadline.ID, results[0].ID)
+	its.Equal(inContent.ID, results[1].ID)
+	its.Contains(results[0].HeadlineSnippet, "<b>"+marker+"</b>")
+	its.Contains(results[1].ContentSnippet, "<b>"+marker+"</b>")
+	// Markup of the article is escaped, only the highlighting is HTML
+	its.Contains(results[1].ContentSnippet, "&lt;script&gt;")
+	its.NotContains(results[1].ContentSnippet, "<script>")
+
+	results, err = its.store.SearchArticles(ctx, SearchArticlesParams{
+		Language: "english",
+		Query:    marker + " -yesterday",
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Len(results, 1)
+	its.Equal(inHeadline.ID, results[0].ID)
+}
+
+func (its *DBIntegrationTestSuite) TestReindexArticleSearch() {
+	user := createRandomUser(its)
+	ctx := context.Background()
+	marker := util.RandomString(12)
+
+	article, err := its.store.CreateArticle(ctx, CreateArticleParams{
+		Author:         user.Username,
+		Headline:       "Running " + marker,
+		Content:        util.RandomString(30),
+		Status:         util.ArticlePublished,
+		SearchLanguage: "simple",
+	})
+	its.NoError(err)
+
+	arg := SearchArticlesParams{
+		Language: "english",
+		Query:    "run " + marker,
+		Limit:    5,
+	}
+	// Indexed with another configuration, so it's not searched
+	results, err := its.store.SearchArticles(ctx, arg)
+	its.NoError(err)
+	its.Empty(results)
+
+	n, err := its.store.ReindexArticleSearch(ctx, "english")
+	its.NoError(err)
+	its.GreaterOrEqual(n, int64(1))
+
+	results, err = its.store.SearchArticles(ctx, arg)
+	its.NoError(err)
+	its.Len(results, 1)
+	its.Equal(article.ID, results[0].ID)
+
+	updated, err := its.store.GetArticle(ctx, article.ID)
+	its.NoError(err)
+	its.Equal("english", updated.SearchLanguage)
+	its.Equal(article.Version, updated.Version)
+}
+
+func (its *DBIntegrationTestSuite) TestArticleTags() {
+	user := createRandomUser(its)
+	ctx := context.Background()
+	// Random tags keep the test independent of the articles of the other tests
+	tagA, tagB, tagC := util.RandomString(8), util.RandomString(8), util.RandomString(8)
+
+	both, err := its.store.CreateArticleTx(ctx, CreateArticleTxParams{
+		CreateArticleParams: CreateArticleParams{
+			Author:         user.Username,
+			Headline:       util.RandomString(10),
+			Content:        util.RandomString(25),
+			Status:         util.ArticlePublished,
+			SearchLanguage: "english",
+		},
+		Tags: []string{tagA, tagB},
+	})
+	its.NoError(err)
+	its.ElementsMatch([]string{tagA, tagB}, both.Tags)
+
+	onlyA, err := its.store.CreateArticleTx(ctx, CreateArticleTxParams{
+		CreateArticleParams: CreateArticleParams{
+			Author:         user.Username,
+			Headline:       util.RandomString(10),
+			Content:        util.RandomString(25),
+			Status:         util.ArticlePublished,
+			SearchLanguage: "english",
+		},
+		Tags: []string{tagA},
+	})
+	its.NoError(err)
+	its.Equal([]string{tagA}, onlyA.Tags)
+
+	arg := ListArticlesParams{
+		Tags:  []string{tagA, tagB},
+		Limit: 5,
+	}
+	articles, err := its.store.ListArticles(ctx, arg)
+	its.NoError(err)
+	its.Len(articles, 2)
+
+	arg.AllTags = true
+	articles, err = its.store.ListArticles(ctx, arg)
+	its.NoError(err)
+	its.Len(articles, 1)
+	its.Equal(both.Article.ID, articles[0].ID)
+
+	count, err := its.store.CountArticles(ctx, arg)
+	its.NoError(err)
+	its.Equal(int64(1), count)
+
+	// Nil tags leave them as they are
+	updated, err := its.store.UpdateArticleTx(ctx, UpdateArticleTxParams{
+		UpdateArticleParams: UpdateArticleParams{
+			ID:       onlyA.Article.ID,
+			Headline: sql.NullString{String: util.RandomString(10), Valid: true},
+		},
+		Editor: user.Username,
+	})
+	its.NoError(err)
+	its.Equal([]string{tagA}, updated.Tags)
+
+	updated, err = its.store.UpdateArticleTx(ctx, UpdateArticleTxParams{
+		UpdateArticleParams: UpdateArticleParams{ID: onlyA.Article.ID},
+		Tags:                []string{tagC},
+		Editor:              user.Username,
+	})
+	its.NoError(err)
+	its.Equal([]string{tagC}, updated.Tags)
+
+	updated, err = its.store.UpdateArticleTx(ctx, UpdateArticleTxParams{
+		UpdateArticleParams: UpdateArticleParams{ID: both.Article.ID},
+		Tags:                []string{},
+		Editor:              user.Username,
+	})
+	its.NoError(err)
+	its.Empty(updated.Tags)
+
+	rows, err := its.store.ListTagsOfArticles(ctx, []int64{both.Article.ID, onlyA.Article.ID})
+	its.NoError(err)
+	its.Equal([]ListTagsOfArticlesRow{{ArticleID: onlyA.Article.ID, Name: tagC}}, rows)
+
+	// Tags no article uses anymore aren't listed
+	tags, err := its.store.ListTags(ctx, ListTagsParams{Limit: 1000})
+	its.NoError(err)
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	its.Contains(names, tagC)
+	its.NotContains(names, tagA)
+	its.NotContains(names, tagB)
+
+	// Tags only drafts use aren't public either
+	tagD := util.RandomString(8)
+	_, err = its.store.CreateArticleTx(ctx, CreateArticleTxParams{
+		CreateArticleParams: CreateArticleParams{
+			Author:         user.Username,
+			Headline:       util.RandomString(10),
+			Content:        util.RandomString(25),
+			Status:         util.ArticleDraft,
+			SearchLanguage: "english",
+		},
+		Tags: []string{tagC, tagD},
+	})
+	its.NoError(err)
+
+	tags, err = its.store.ListTags(ctx, ListTagsParams{Limit: 1000})
+	its.NoError(err)
+	counts := make(map[string]int64, len(tags))
+	for _, tag := range tags {
+		counts[tag.Name] = tag.ArticleCount
+	}
+	its.Equal(int64(1), counts[tagC])
+	its.NotContains(counts, tagD)
+}
+
+func (its *DBIntegrationTestSuite) TestComments() {
+	article := createRandomArticle(its)
+	user := createRandomUser(its)
+	ctx := context.Background()
+
+	root, err := its.store.CreateComment(ctx, CreateCommentParams{
+		ArticleID: article.ID,
+		Author:    user.Username,
+		Content:   util.RandomString(20),
+	})
+	its.NoError(err)
+	its.False(root.ParentID.Valid)
+
+	reply, err := its.store.CreateComment(ctx, CreateCommentParams{
+		ArticleID: article.ID,
+		ParentID:  sql.NullInt64{Int64: root.ID, Valid: true},
+		Author:    article.Author,
+		Content:   util.RandomString(20),
+	})
+	its.NoError(err)
+	nested, err := its.store.CreateComment(ctx, CreateCommentParams{
+		ArticleID: article.ID,
+		ParentID:  sql.NullInt64{Int64: reply.ID, Valid: true},
+		Author:    user.Username,
+		Content:   util.RandomString(20),
+	})
+	its.NoError(err)
+
+	roots, err := its.store.ListRootComments(ctx, ListRootCommentsParams{ArticleID: article.ID, Limit: 5})
+	its.NoError(err)
+	its.Len(roots, 1)
+	its.Equal(root.ID, roots[0].ID)
+
+	replies, err := its.store.ListCommentReplies(ctx, []int64{root.ID})
+	its.NoError(err)
+	its.Len(replies, 2)
+	its.Equal(reply.ID, replies[0].ID)
+	its.Equal(nested.ID, replies[1].ID)
+
+	comments, err := its.store.ListComments(ctx, ListCommentsParams{ArticleID: article.ID, Limit: 5})
+	its.NoError(err)
+	its.Len(comments, 3)
+
+	edited, err := its.store.UpdateComment(ctx, UpdateCommentParams{ID: nested.ID, Content: "edited"})
+	its.NoError(err)
+	its.Equal("edited", edited.Content)
+	its.True(edited.EditedAt.Valid)
+
+	// Comments with replies can't be deleted, only emptied
+	deleted, err := its.store.DeleteComment(ctx, root.ID)
+	its.NoError(err)
+	its.Zero(deleted)
+	err = its.store.MarkCommentDeleted(ctx, root.ID)
+	its.NoError(err)
+
+	root, err = its.store.GetComment(ctx, root.ID)
+	its.NoError(err)
+	its.True(root.DeletedAt.Valid)
+	its.Empty(root.Content)
+
+	_, err = its.store.UpdateComment(ctx, UpdateCommentParams{ID: root.ID, Content: "edited"})
+	its.ErrorIs(err, sql.ErrNoRows)
+
+	deleted, err = its.store.DeleteComment(ctx, nested.ID)
+	its.NoError(err)
+	its.Equal(int64(1), deleted)
+
+	counts, err := its.store.CountCommentsOfArticles(ctx, []int64{article.ID})
+	its.NoError(err)
+	its.Equal([]CountCommentsOfArticlesRow{{ArticleID: article.ID, CommentCount: 1}}, counts)
+
+	// Deleting the article removes the whole thread
+	_, err = its.store.DeleteArticle(ctx, DeleteArticleParams{ID: article.ID})
+	its.NoError(err)
+	_, err = its.store.GetComment(ctx, reply.ID)
+	its.ErrorIs(err, sql.ErrNoRows)
+}
+
+func (its *DBIntegrationTestSuite) TestFavoritesAndReactions() {
+	article := createRandomArticle(its)
+	ctx := context.Background()
+
+	// Every reader clicks many times at once, each of them is still counted once
+	readers := make([]User, 3)
+	for i := range readers {
+		readers[i] = createRandomUser(its)
+	}
+	clicks := 5
+	errs := make(chan error, len(readers)*clicks*2)
+	for _, reader := range readers {
+		for i := 0; i < clicks; i++ {
+			go func(username string) {
+				errs <- its.store.AddFavorite(ctx, AddFavoriteParams{Username: username, ArticleID: article.ID})
+			}(reader.Username)
+			go func(username string) {
+				errs <- its.store.AddReaction(ctx, AddReactionParams{
+					Username:  username,
+					ArticleID: article.ID,
+					Reaction:  util.ReactionHeart,
+				})
+			}(reader.Username)
+		}
+	}
+	for i := 0; i < len(readers)*clicks*2; i++ {
+		its.NoError(<-errs)
+	}
+
+	err := its.store.AddReaction(ctx, AddReactionParams{
+		Username:  readers[0].Username,
+		ArticleID: article.ID,
+		Reaction:  util.ReactionEyes,
+	})
+	its.NoError(err)
+
+	favorites, err := its.store.CountFavoritesOfArticles(ctx, CountFavoritesOfArticlesParams{
+		Username:   readers[0].Username,
+		ArticleIds: []int64{article.ID},
+	})
+	its.NoError(err)
+	its.Equal([]CountFavoritesOfArticlesRow{{ArticleID: article.ID, FavoriteCount: 3, Favorited: true}}, favorites)
+
+	reactions, err := its.store.CountReactionsOfArticles(ctx, CountReactionsOfArticlesParams{
+		Username:   readers[1].Username,
+		ArticleIds: []int64{article.ID},
+	})
+	its.NoError(err)
+	its.Equal([]CountReactionsOfArticlesRow{
+		{ArticleID: article.ID, Reaction: util.ReactionEyes, ReactionCount: 1, Reacted: false},
+		{ArticleID: article.ID, Reaction: util.ReactionHeart, ReactionCount: 3, Reacted: true},
+	}, reactions)
+
+	other := createRandomArticle(its)
+	err = its.store.AddFavorite(ctx, AddFavoriteParams{Username: readers[0].Username, ArticleID: other.ID})
+	its.NoError(err)
+
+	articles, err := its.store.ListFavoriteArticles(ctx, ListFavoriteArticlesParams{
+		Username: readers[0].Username,
+		Limit:    5,
+	})
+	its.NoError(err)
+	its.Len(articles, 2)
+	its.Equal(other.ID, articles[0].ID)
+	its.Equal(article.ID, articles[1].ID)
+
+	err = its.store.DeleteFavorite(ctx, DeleteFavoriteParams{Username: readers[0].Username, ArticleID: article.ID})
+	its.NoError(err)
+	err = its.store.DeleteReaction(ctx, DeleteReactionParams{
+		Username:  readers[0].Username,
+		ArticleID: article.ID,
+		Reaction:  util.ReactionEyes,
+	})
+	its.NoError(err)
+
+	favorites, err = its.store.CountFavoritesOfArticles(ctx, CountFavoritesOfArticlesParams{
+		Username:   readers[0].Username,
+		ArticleIds: []int64{article.ID},
+	})
+	its.NoError(err)
+	its.Equal([]CountFavoritesOfArticlesRow{{ArticleID: article.ID, FavoriteCount: 2, Favorited: false}}, favorites)
+
+	reactions, err = its.store.CountReactionsOfArticles(ctx, CountReactionsOfArticlesParams{
+		Username:   readers[0].Username,
+		ArticleIds: []int64{article.ID},
+	})
+	its.NoError(err)
+	its.Len(reactions, 1)
+}
+
+func (its *DBIntegrationTestSuite) TestArticleStatus() {
+	user := createRandomUser(its)
+	other := createRandomUser(its)
+	ctx := context.Background()
+
+	draft, err := its.store.CreateArticle(ctx, CreateArticleParams{
+		Author:         user.Username,
+		Headline:       util.RandomString(10),
+		Content:        util.RandomString(25),
+		Status:         util.ArticleDraft,
+		SearchLanguage: "english",
+	})
+	its.NoError(err)
+	its.False(draft.PublishAt.Valid)
+
+	// Drafts are listed only for their author
+	arg := ListArticlesParams{
+		Authors: []string{user.Username},
+		Viewer:  other.Username,
+		Limit:   5,
+	}
+	articles, err := its.store.ListArticles(ctx, arg)
+	its.NoError(err)
+	its.Empty(articles)
+
+	arg.Viewer = user.Username
+	arg.Statuses = []string{util.ArticleDraft}
+	articles, err = its.store.ListArticles(ctx, arg)
+	its.NoError(err)
+	its.Len(articles, 1)
+	its.Equal(draft.ID, articles[0].ID)
+
+	// The update is guarded by the status it was decided on
+	_, err = its.store.ChangeArticleStatus(ctx, ChangeArticleStatusParams{
+		ID:           draft.ID,
+		Status:       util.ArticleArchived,
+		FromStatuses: []string{util.ArticlePublished},
+	})
+	its.ErrorIs(err, sql.ErrNoRows)
+
+	due, err := its.store.ChangeArticleStatus(ctx, ChangeArticleStatusParams{
+		ID:           draft.ID,
+		Status:       util.ArticleScheduled,
+		PublishAt:    sql.NullTime{Time: time.Now().Add(-time.Second), Valid: true},
+		FromStatuses: []string{util.ArticleDraft},
+	})
+	its.NoError(err)
+	its.Equal(util.ArticleScheduled, due.Status)
+	// Status changes are updates too, so clients holding the old version can tell
+	its.Equal(draft.Version+1, due.Version)
+
+	later, err := its.store.CreateArticle(ctx, CreateArticleParams{
+		Author:         user.Username,
+		Headline:       util.RandomString(10),
+		Content:        util.RandomString(25),
+		Status:         util.ArticleScheduled,
+		SearchLanguage: "english",
+		PublishAt:      sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+	its.NoError(err)
+
+	// Other tests don't schedule articles, so everything published here is ours
+	published, err := its.store.PublishScheduledArticles(ctx, 10)
+	its.NoError(err)
+	its.Len(published, 1)
+	its.Equal(due.ID, published[0].ID)
+	its.Equal(util.ArticlePublished, published[0].Status)
+	its.Equal(due.Version+1, published[0].Version)
+
+	published, err = its.store.PublishScheduledArticles(ctx, 10)
+	its.NoError(err)
+	its.Empty(published)
+
+	later, err = its.store.GetArticle(ctx, later.ID)
+	its.NoError(err)
+	its.Equal(util.ArticleScheduled, later.Status)
+
+	arg.Viewer = other.Username
+	arg.Statuses = nil
+	articles, err = its.store.ListArticles(ctx, arg)
+	its.NoError(err)
+	its.Len(articles, 1)
+	its.Equal(due.ID, articles[0].ID)
+}
+
+func (its *DBIntegrationTestSuite) TestArticleRevisions() {
+	user := createRandomUser(its)
+	ctx := context.Background()
+
+	created, err := its.store.CreateArticleTx(ctx, CreateArticleTxParams{
+		CreateArticleParams: CreateArticleParams{
+			Author:         user.Username,
+			Headline:       util.RandomString(10),
+			Content:        util.RandomString(25),
+			Status:         util.ArticleDraft,
+			SearchLanguage: "english",
+		},
+	})
+	its.NoError(err)
+	its.Equal(int32(1), created.Revision)
+
+	// Concurrent updates are numbered one after another
+	n := 5
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := its.store.UpdateArticleTx(ctx, UpdateArticleTxParams{
+				UpdateArticleParams: UpdateArticleParams{
+					ID:      created.Article.ID,
+					Content: sql.NullString{String: util.RandomString(25), Valid: true},
+				},
+				Editor: user.Username,
+			})
+			errs <- err
+		}()
+	}
+	for i := 0; i < n; i++ {
+		its.NoError(<-errs)
+	}
+
+	revisions, err := its.store.ListArticleRevisions(ctx, ListArticleRevisionsParams{
+		ArticleID: created.Article.ID,
+		Limit:     10,
+	})
+	its.NoError(err)
+	its.Len(revisions, n+1)
+	for i, revision := range revisions {
+		its.Equal(int32(n+1-i), revision.Revision)
+		its.Equal(user.Username, revision.Editor)
+	}
+
+	// The newest revision is the current text
+	article, err := its.store.GetArticle(ctx, created.Article.ID)
+	its.NoError(err)
+	latest, err := its.store.GetArticleRevision(ctx, GetArticleRevisionParams{
+		ArticleID: article.ID,
+		Revision:  int32(n + 1),
+	})
+	its.NoError(err)
+	its.Equal(article.Content, latest.Content)
+
+	restored, err := its.store.UpdateArticleTx(ctx, UpdateArticleTxParams{
+		UpdateArticleParams: UpdateArticleParams{
+			ID:       article.ID,
+			Headline: sql.NullString{String: created.Article.Headline, Valid: true},
+			Content:  sql.NullString{String: created.Article.Content, Valid: true},
+		},
+		Editor:       user.Username,
+		RestoredFrom: sql.NullInt32{Int32: 1, Valid: true},
+	})
+	its.NoError(err)
+	its.Equal(int32(n+2), restored.Revision)
+	its.Equal(created.Article.Content, restored.Article.Content)
+
+	revision, err := its.store.GetArticleRevision(ctx, GetArticleRevisionParams{
+		ArticleID: article.ID,
+		Revision:  restored.Revision,
+	})
+	its.NoError(err)
+	its.Equal(sql.NullInt32{Int32: 1, Valid: true}, revision.RestoredFrom)
+	its.Equal(created.Article.Content, revision.Content)
+}
+
+func (its *DBIntegrationTestSuite) TestArticleVersion() {
+	article := createRandomArticle(its)
+	ctx := context.Background()
+	its.Equal(int32(1), article.Version)
+
+	updated, err := its.store.UpdateArticle(ctx, UpdateArticleParams{
+		ID:       article.ID,
+		Headline: sql.NullString{String: util.RandomString(10), Valid: true},
+		Version:  sql.NullInt32{Int32: article.Version, Valid: true},
+	})
+	its.NoError(err)
+	its.Equal(article.Version+1, updated.Version)
+
+	// The version read before the first update is stale now
+	_, err = its.store.UpdateArticle(ctx, UpdateArticleParams{
+		ID:       article.ID,
+		Headline: sql.NullString{String: util.RandomString(10), Valid: true},
+		Version:  sql.NullInt32{Int32: article.Version, Valid: true},
+	})
+	its.ErrorIs(err, sql.ErrNoRows)
+
+	deleted, err := its.store.DeleteArticle(ctx, DeleteArticleParams{
+		ID:      article.ID,
+		Version: sql.NullInt32{Int32: article.Version, Valid: true},
+	})
+	its.NoError(err)
+	its.Zero(deleted)
+
+	// Without a version the update always goes through
+	updated, err = its.store.UpdateArticle(ctx, UpdateArticleParams{
+		ID:      article.ID,
+		Content: sql.NullString{String: util.RandomString(25), Valid: true},
+	})
+	its.NoError(err)
+	its.Equal(article.Version+2, updated.Version)
+
+	deleted, err = its.store.DeleteArticle(ctx, DeleteArticleParams{
+		ID:      article.ID,
+		Version: sql.NullInt32{Int32: updated.Version, Valid: true},
+	})
+	its.NoError(err)
+	its.Equal(int64(1), deleted)
+}
+
 // Setup helper functions
 
 func setupDatabase(its *DBIntegrationTestSuite, db *sql.DB) {
@@ -0,0 +1,157 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: invitation.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createInvitation = `-- name: CreateInvitation :one
+INSERT INTO invitations (
+    hashed_code,
+    email,
+    created_by,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, hashed_code, email, created_by, used_by, used_at, revoked_at, expires_at, created_at
+`
+
+type CreateInvitationParams struct {
+	HashedCode string         `json:"hashed_code"`
+	Email      sql.NullString `json:"email"`
+	CreatedBy  string         `json:"created_by"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+}
+
+func (q *Queries) CreateInvitation(ctx context.Context, arg CreateInvitationParams) (Invitation, error) {
+	row := q.db.QueryRowContext(ctx, createInvitation,
+		arg.HashedCode,
+		arg.Email,
+		arg.CreatedBy,
+		arg.ExpiresAt,
+	)
+	var i Invitation
+	err := row.Scan(
+		&i.ID,
+		&i.HashedCode,
+		&i.Email,
+		&i.CreatedBy,
+		&i.UsedBy,
+		&i.UsedAt,
+		&i.RevokedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listInvitations = `-- name: ListInvitations :many
+SELECT id, hashed_code, email, created_by, used_by, used_at, revoked_at, expires_at, created_at FROM invitations
+ORDER BY id DESC
+LIMIT $1
+OFFSET $2
+`
+
+type ListInvitationsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListInvitations(ctx context.Context, arg ListInvitationsParams) ([]Invitation, error) {
+	rows, err := q.db.QueryContext(ctx, listInvitations, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Invitation{}
+	for rows.Next() {
+		var i Invitation
+		if err := rows.Scan(
+			&i.ID,
+			&i.HashedCode,
+			&i.Email,
+			&i.CreatedBy,
+			&i.UsedBy,
+			&i.UsedAt,
+			&i.RevokedAt,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeInvitation = `-- name: RevokeInvitation :one
+UPDATE invitations
+SET revoked_at = NOW()
+WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+RETURNING id, hashed_code, email, created_by, used_by, used_at, revoked_at, expires_at, created_at
+`
+
+func (q *Queries) RevokeInvitation(ctx context.Context, id int64) (Invitation, error) {
+	row := q.db.QueryRowContext(ctx, revokeInvitation, id)
+	var i Invitation
+	err := row.Scan(
+		&i.ID,
+		&i.HashedCode,
+		&i.Email,
+		&i.CreatedBy,
+		&i.UsedBy,
+		&i.UsedAt,
+		&i.RevokedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useInvitation = `-- name: UseInvitation :one
+UPDATE invitations
+SET
+    used_by = $1,
+    used_at = NOW()
+WHERE hashed_code = $2
+    AND used_at IS NULL
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+    AND (email IS NULL OR lower(email) = lower($3))
+RETURNING id, hashed_code, email, created_by, used_by, used_at, revoked_at, expires_at, created_at
+`
+
+type UseInvitationParams struct {
+	UsedBy     sql.NullString `json:"used_by"`
+	HashedCode string         `json:"hashed_code"`
+	Email      string         `json:"email"`
+}
+
+func (q *Queries) UseInvitation(ctx context.Context, arg UseInvitationParams) (Invitation, error) {
+	row := q.db.QueryRowContext(ctx, useInvitation, arg.UsedBy, arg.HashedCode, arg.Email)
+	var i Invitation
+	err := row.Scan(
+		&i.ID,
+		&i.HashedCode,
+		&i.Email,
+		&i.CreatedBy,
+		&i.UsedBy,
+		&i.UsedAt,
+		&i.RevokedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
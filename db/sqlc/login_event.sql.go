@@ -0,0 +1,119 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: login_event.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createLoginEvent = `-- name: CreateLoginEvent :exec
+INSERT INTO login_events (
+    login,
+    username,
+    outcome,
+    ip,
+    user_agent
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+`
+
+type CreateLoginEventParams struct {
+	Login     string         `json:"login"`
+	Username  sql.NullString `json:"username"`
+	Outcome   string         `json:"outcome"`
+	Ip        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+}
+
+func (q *Queries) CreateLoginEvent(ctx context.Context, arg CreateLoginEventParams) error {
+	_, err := q.db.ExecContext(ctx, createLoginEvent,
+		arg.Login,
+		arg.Username,
+		arg.Outcome,
+		arg.Ip,
+		arg.UserAgent,
+	)
+	return err
+}
+
+const getLoginDeviceHistory = `-- name: GetLoginDeviceHistory :one
+SELECT
+    EXISTS (
+        SELECT 1 FROM login_events e
+        WHERE e.username = $1::varchar AND e.outcome IN ('success', 'mfa_required')
+    ) AS has_logins,
+    EXISTS (
+        SELECT 1 FROM login_events e
+        WHERE e.username = $1::varchar AND e.outcome IN ('success', 'mfa_required')
+            AND e.ip = $2 AND e.user_agent = $3
+    ) AS known_device
+`
+
+type GetLoginDeviceHistoryParams struct {
+	Username  string `json:"username"`
+	Ip        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+type GetLoginDeviceHistoryRow struct {
+	HasLogins   bool `json:"has_logins"`
+	KnownDevice bool `json:"known_device"`
+}
+
+// Device is the combination of IP and user agent. Logins with the correct password count, even if MFA wasn't finished
+func (q *Queries) GetLoginDeviceHistory(ctx context.Context, arg GetLoginDeviceHistoryParams) (GetLoginDeviceHistoryRow, error) {
+	row := q.db.QueryRowContext(ctx, getLoginDeviceHistory, arg.Username, arg.Ip, arg.UserAgent)
+	var i GetLoginDeviceHistoryRow
+	err := row.Scan(&i.HasLogins, &i.KnownDevice)
+	return i, err
+}
+
+const listLoginEvents = `-- name: ListLoginEvents :many
+SELECT id, login, username, outcome, ip, user_agent, created_at FROM login_events
+WHERE username = $1::varchar
+ORDER BY id DESC
+LIMIT $3
+OFFSET $2
+`
+
+type ListLoginEventsParams struct {
+	Username string `json:"username"`
+	Offset   int32  `json:"offset"`
+	Limit    int32  `json:"limit"`
+}
+
+func (q *Queries) ListLoginEvents(ctx context.Context, arg ListLoginEventsParams) ([]LoginEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listLoginEvents, arg.Username, arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LoginEvent{}
+	for rows.Next() {
+		var i LoginEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Login,
+			&i.Username,
+			&i.Outcome,
+			&i.Ip,
+			&i.UserAgent,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,22 +7,154 @@ package db
 import (
 	"database/sql"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Article struct {
-	ID        int64        `json:"id"`
-	Author    string       `json:"author"`
-	Headline  string       `json:"headline"`
-	Content   string       `json:"content"`
-	CreatedAt time.Time    `json:"created_at"`
-	EditedAt  sql.NullTime `json:"edited_at"`
+	ID             int64        `json:"id"`
+	Author         string       `json:"author"`
+	Headline       string       `json:"headline"`
+	Content        string       `json:"content"`
+	CreatedAt      time.Time    `json:"created_at"`
+	EditedAt       sql.NullTime `json:"edited_at"`
+	Status         string       `json:"status"`
+	PublishAt      sql.NullTime `json:"publish_at"`
+	Version        int32        `json:"version"`
+	SearchLanguage string       `json:"-"`
+	SearchVector   string       `json:"-"`
+}
+
+type ArticleReaction struct {
+	Username  string    `json:"username"`
+	ArticleID int64     `json:"article_id"`
+	Reaction  string    `json:"reaction"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ArticleRevision struct {
+	ArticleID    int64         `json:"article_id"`
+	Revision     int32         `json:"revision"`
+	Headline     string        `json:"headline"`
+	Content      string        `json:"content"`
+	Editor       string        `json:"editor"`
+	RestoredFrom sql.NullInt32 `json:"restored_from"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+type ArticleTag struct {
+	ArticleID int64 `json:"article_id"`
+	TagID     int64 `json:"tag_id"`
+}
+
+type Comment struct {
+	ID        int64         `json:"id"`
+	ArticleID int64         `json:"article_id"`
+	ParentID  sql.NullInt64 `json:"parent_id"`
+	Author    string        `json:"author"`
+	Content   string        `json:"content"`
+	CreatedAt time.Time     `json:"created_at"`
+	EditedAt  sql.NullTime  `json:"edited_at"`
+	DeletedAt sql.NullTime  `json:"deleted_at"`
+}
+
+type ConsumedToken struct {
+	ID         uuid.UUID `json:"id"`
+	Purpose    string    `json:"purpose"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ConsumedAt time.Time `json:"consumed_at"`
+}
+
+type DeviceAuthorization struct {
+	ID               int64          `json:"id"`
+	HashedDeviceCode string         `json:"hashed_device_code"`
+	UserCode         string         `json:"user_code"`
+	Status           string         `json:"status"`
+	Username         sql.NullString `json:"username"`
+	PollInterval     int32          `json:"poll_interval"`
+	LastPolledAt     sql.NullTime   `json:"last_polled_at"`
+	ExpiresAt        time.Time      `json:"expires_at"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+type Favorite struct {
+	Username  string    `json:"username"`
+	ArticleID int64     `json:"article_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Follow struct {
+	Follower  string    `json:"follower"`
+	Followee  string    `json:"followee"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ImpersonationLog struct {
+	ID           int64     `json:"id"`
+	SessionID    uuid.UUID `json:"session_id"`
+	Impersonator string    `json:"impersonator"`
+	Username     string    `json:"username"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Status       int32     `json:"status"`
+	ClientIp     string    `json:"client_ip"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Invitation struct {
+	ID         int64          `json:"id"`
+	HashedCode string         `json:"hashed_code"`
+	Email      sql.NullString `json:"email"`
+	CreatedBy  string         `json:"created_by"`
+	UsedBy     sql.NullString `json:"used_by"`
+	UsedAt     sql.NullTime   `json:"used_at"`
+	RevokedAt  sql.NullTime   `json:"revoked_at"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+type LoginEvent struct {
+	ID        int64          `json:"id"`
+	Login     string         `json:"login"`
+	Username  sql.NullString `json:"username"`
+	Outcome   string         `json:"outcome"`
+	Ip        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID         int64        `json:"id"`
+	Username   string       `json:"username"`
+	HashedCode string       `json:"hashed_code"`
+	UsedAt     sql.NullTime `json:"used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type User struct {
-	Username          string    `json:"username"`
-	HashedPassword    string    `json:"hashed_password"`
-	FullName          string    `json:"full_name"`
-	Email             string    `json:"email"`
-	PasswordChangedAt time.Time `json:"password_changed_at"`
-	CreatedAt         time.Time `json:"created_at"`
+	Username              string       `json:"username"`
+	HashedPassword        string       `json:"hashed_password"`
+	FullName              string       `json:"full_name"`
+	Email                 string       `json:"email"`
+	PasswordChangedAt     time.Time    `json:"password_changed_at"`
+	CreatedAt             time.Time    `json:"created_at"`
+	TotpSecret            string       `json:"totp_secret"`
+	TotpEnabled           bool         `json:"totp_enabled"`
+	TotpLastStep          int64        `json:"totp_last_step"`
+	Role                  string       `json:"role"`
+	AvatarID              string       `json:"avatar_id"`
+	DisabledAt            sql.NullTime `json:"disabled_at"`
+	PasswordResetRequired bool         `json:"password_reset_required"`
+}
+
+type UsernameRedirect struct {
+	OldUsername string    `json:"old_username"`
+	Username    string    `json:"username"`
+	CreatedAt   time.Time `json:"created_at"`
 }
@@ -9,13 +9,113 @@ import (
 )
 
 type Querier interface {
+	AddArticleTags(ctx context.Context, arg AddArticleTagsParams) error
+	AddFavorite(ctx context.Context, arg AddFavoriteParams) error
+	AddReaction(ctx context.Context, arg AddReactionParams) error
+	// Nothing is updated when the article isn't in one of from_statuses anymore, e.g. the publisher got to it first
+	ChangeArticleStatus(ctx context.Context, arg ChangeArticleStatusParams) (Article, error)
+	// Returns 0 if the token has been consumed already. Expired tokens are cleaned up on the way,
+	// they are rejected by signature verification anyway
+	ConsumeToken(ctx context.Context, arg ConsumeTokenParams) (int64, error)
+	// Deleted comments aren't counted
+	CountCommentsOfArticles(ctx context.Context, articleIds []int64) ([]CountCommentsOfArticlesRow, error)
+	// Favorited tells if the given user is one of those who favorited the article
+	CountFavoritesOfArticles(ctx context.Context, arg CountFavoritesOfArticlesParams) ([]CountFavoritesOfArticlesRow, error)
+	// Reacted tells if the given user left the reaction
+	CountReactionsOfArticles(ctx context.Context, arg CountReactionsOfArticlesParams) ([]CountReactionsOfArticlesRow, error)
 	CreateArticle(ctx context.Context, arg CreateArticleParams) (Article, error)
+	// Called in the transaction that wrote the article, which keeps the article row locked,
+	// so concurrent updates can't get the same number
+	CreateArticleRevision(ctx context.Context, arg CreateArticleRevisionParams) (ArticleRevision, error)
+	CreateComment(ctx context.Context, arg CreateCommentParams) (Comment, error)
+	// Expired authorizations are cleaned up on the way. They are kept for a day after expiring,
+	// so that clients still polling get expired_token rather than invalid_grant
+	CreateDeviceAuthorization(ctx context.Context, arg CreateDeviceAuthorizationParams) (DeviceAuthorization, error)
+	CreateFollow(ctx context.Context, arg CreateFollowParams) error
+	CreateImpersonationLogEntry(ctx context.Context, arg CreateImpersonationLogEntryParams) error
+	CreateInvitation(ctx context.Context, arg CreateInvitationParams) (Invitation, error)
+	CreateLoginEvent(ctx context.Context, arg CreateLoginEventParams) error
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteArticle(ctx context.Context, id int64) error
+	CreateUsernameRedirect(ctx context.Context, arg CreateUsernameRedirectParams) error
+	DecideDeviceAuthorization(ctx context.Context, arg DecideDeviceAuthorizationParams) (DeviceAuthorization, error)
+	// Nothing is deleted when version is given and the article has another one
+	DeleteArticle(ctx context.Context, arg DeleteArticleParams) (int64, error)
+	DeleteArticleTags(ctx context.Context, articleID int64) error
+	// Only comments without replies are deleted, the others have to be marked with MarkCommentDeleted.
+	// A reply inserted concurrently isn't seen by the check, the delete fails with foreign_key_violation then
+	DeleteComment(ctx context.Context, id int64) (int64, error)
+	DeleteDeviceAuthorization(ctx context.Context, id int64) (int64, error)
+	DeleteFavorite(ctx context.Context, arg DeleteFavoriteParams) error
+	DeleteFollow(ctx context.Context, arg DeleteFollowParams) error
+	DeleteReaction(ctx context.Context, arg DeleteReactionParams) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DeleteUsernameRedirect(ctx context.Context, oldUsername string) error
+	DisableUser(ctx context.Context, username string) (User, error)
+	DisableUserTOTP(ctx context.Context, username string) (User, error)
+	EnableUser(ctx context.Context, username string) (User, error)
+	EnableUserTOTP(ctx context.Context, username string) (User, error)
 	GetArticle(ctx context.Context, id int64) (Article, error)
+	GetArticleRevision(ctx context.Context, arg GetArticleRevisionParams) (ArticleRevision, error)
+	// Only published articles are counted, the others aren't public
+	GetAuthor(ctx context.Context, username string) (GetAuthorRow, error)
+	GetComment(ctx context.Context, id int64) (Comment, error)
+	GetDeviceAuthorization(ctx context.Context, hashedDeviceCode string) (DeviceAuthorization, error)
+	// Device is the combination of IP and user agent. Logins with the correct password count, even if MFA wasn't finished
+	GetLoginDeviceHistory(ctx context.Context, arg GetLoginDeviceHistoryParams) (GetLoginDeviceHistoryRow, error)
 	GetUser(ctx context.Context, username string) (User, error)
-	ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error)
+	GetUserByLogin(ctx context.Context, login string) (User, error)
+	GetUsernameRedirect(ctx context.Context, oldUsername string) (string, error)
+	// Without the content, which can be fetched revision by revision
+	ListArticleRevisions(ctx context.Context, arg ListArticleRevisionsParams) ([]ListArticleRevisionsRow, error)
+	ListAuthors(ctx context.Context, arg ListAuthorsParams) ([]ListAuthorsRow, error)
+	// All replies in the threads of the given comments, however deep they are
+	ListCommentReplies(ctx context.Context, parentIds []int64) ([]Comment, error)
+	ListComments(ctx context.Context, arg ListCommentsParams) ([]Comment, error)
+	// Most recently favorited first. Articles that aren't published anymore are only listed to their author
+	ListFavoriteArticles(ctx context.Context, arg ListFavoriteArticlesParams) ([]Article, error)
+	ListFeedArticles(ctx context.Context, arg ListFeedArticlesParams) ([]Article, error)
+	ListFollowers(ctx context.Context, arg ListFollowersParams) ([]ListFollowersRow, error)
+	ListFollowing(ctx context.Context, arg ListFollowingParams) ([]ListFollowingRow, error)
+	// Filters are skipped when null
+	ListImpersonationLog(ctx context.Context, arg ListImpersonationLogParams) ([]ImpersonationLog, error)
+	ListInvitations(ctx context.Context, arg ListInvitationsParams) ([]Invitation, error)
+	ListLoginEvents(ctx context.Context, arg ListLoginEventsParams) ([]LoginEvent, error)
+	ListPublicArticles(ctx context.Context, arg ListPublicArticlesParams) ([]Article, error)
+	ListRootComments(ctx context.Context, arg ListRootCommentsParams) ([]Comment, error)
+	// Only tags used by some published article are listed, the other articles aren't public
+	ListTags(ctx context.Context, arg ListTagsParams) ([]ListTagsRow, error)
+	// Tags of many articles at once, so that lists don't need a query per article
+	ListTagsOfArticles(ctx context.Context, articleIds []int64) ([]ListTagsOfArticlesRow, error)
+	// Read on every authenticated request, so that disabling an account takes effect immediately
+	ListUserAccess(ctx context.Context, usernames []string) ([]ListUserAccessRow, error)
+	// Filters are skipped when null. Search is matched as a substring of username, full name and email
+	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	MarkCommentDeleted(ctx context.Context, id int64) error
+	PollDeviceAuthorization(ctx context.Context, arg PollDeviceAuthorizationParams) (DeviceAuthorization, error)
+	// Rows locked by another instance running the same query are skipped, so every article is published once
+	PublishScheduledArticles(ctx context.Context, limit int32) ([]Article, error)
+	// Articles indexed with another configuration are rebuilt, so that all of them can be searched with the current one
+	ReindexArticleSearch(ctx context.Context, language string) (int64, error)
+	RequirePasswordReset(ctx context.Context, username string) (User, error)
+	RevokeInvitation(ctx context.Context, id int64) (Invitation, error)
+	// Headline matches weigh more than content ones. Snippets are HTML: the text is escaped first
+	// and only the matched words are wrapped in <b></b>, so that stored markup can't get into them
+	SearchArticles(ctx context.Context, arg SearchArticlesParams) ([]SearchArticlesRow, error)
+	SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (User, error)
+	SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error)
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error)
+	// Nothing is updated when version is given and the article has another one
 	UpdateArticle(ctx context.Context, arg UpdateArticleParams) (Article, error)
+	UpdateComment(ctx context.Context, arg UpdateCommentParams) (Comment, error)
+	UpdateUserHashedPassword(ctx context.Context, arg UpdateUserHashedPasswordParams) error
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUsername(ctx context.Context, arg UpdateUsernameParams) (User, error)
+	// Existing tags are returned too, the no-op update makes RETURNING include them
+	UpsertTags(ctx context.Context, names []string) ([]Tag, error)
+	UseInvitation(ctx context.Context, arg UseInvitationParams) (Invitation, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,142 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: revision.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createArticleRevision = `-- name: CreateArticleRevision :one
+INSERT INTO article_revisions (
+    article_id,
+    revision,
+    headline,
+    content,
+    editor,
+    restored_from
+) VALUES (
+    $1,
+    (SELECT COALESCE(MAX(revision), 0) + 1 FROM article_revisions WHERE article_id = $1),
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING article_id, revision, headline, content, editor, restored_from, created_at
+`
+
+type CreateArticleRevisionParams struct {
+	ArticleID    int64         `json:"article_id"`
+	Headline     string        `json:"headline"`
+	Content      string        `json:"content"`
+	Editor       string        `json:"editor"`
+	RestoredFrom sql.NullInt32 `json:"restored_from"`
+}
+
+// Called in the transaction that wrote the article, which keeps the article row locked,
+// so concurrent updates can't get the same number
+func (q *Queries) CreateArticleRevision(ctx context.Context, arg CreateArticleRevisionParams) (ArticleRevision, error) {
+	row := q.db.QueryRowContext(ctx, createArticleRevision,
+		arg.ArticleID,
+		arg.Headline,
+		arg.Content,
+		arg.Editor,
+		arg.RestoredFrom,
+	)
+	var i ArticleRevision
+	err := row.Scan(
+		&i.ArticleID,
+		&i.Revision,
+		&i.Headline,
+		&i.Content,
+		&i.Editor,
+		&i.RestoredFrom,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getArticleRevision = `-- name: GetArticleRevision :one
+SELECT article_id, revision, headline, content, editor, restored_from, created_at FROM article_revisions
+WHERE article_id = $1 AND revision = $2
+LIMIT 1
+`
+
+type GetArticleRevisionParams struct {
+	ArticleID int64 `json:"article_id"`
+	Revision  int32 `json:"revision"`
+}
+
+func (q *Queries) GetArticleRevision(ctx context.Context, arg GetArticleRevisionParams) (ArticleRevision, error) {
+	row := q.db.QueryRowContext(ctx, getArticleRevision, arg.ArticleID, arg.Revision)
+	var i ArticleRevision
+	err := row.Scan(
+		&i.ArticleID,
+		&i.Revision,
+		&i.Headline,
+		&i.Content,
+		&i.Editor,
+		&i.RestoredFrom,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listArticleRevisions = `-- name: ListArticleRevisions :many
+SELECT article_id, revision, headline, editor, restored_from, created_at FROM article_revisions
+WHERE article_id = $1
+ORDER BY revision DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListArticleRevisionsParams struct {
+	ArticleID int64 `json:"article_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+type ListArticleRevisionsRow struct {
+	ArticleID    int64         `json:"article_id"`
+	Revision     int32         `json:"revision"`
+	Headline     string        `json:"headline"`
+	Editor       string        `json:"editor"`
+	RestoredFrom sql.NullInt32 `json:"restored_from"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// Without the content, which can be fetched revision by revision
+func (q *Queries) ListArticleRevisions(ctx context.Context, arg ListArticleRevisionsParams) ([]ListArticleRevisionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listArticleRevisions, arg.ArticleID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListArticleRevisionsRow{}
+	for rows.Next() {
+		var i ListArticleRevisionsRow
+		if err := rows.Scan(
+			&i.ArticleID,
+			&i.Revision,
+			&i.Headline,
+			&i.Editor,
+			&i.RestoredFrom,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
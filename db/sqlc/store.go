@@ -1,16 +1,26 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 )
 
-// Store provides all functions to execute db queries
+// Store provides all functions to execute db queries and transactions
 type Store interface {
 	Querier
+	ListArticles(ctx context.Context, arg ListArticlesParams) ([]Article, error)
+	CountArticles(ctx context.Context, arg ListArticlesParams) (int64, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (User, error)
+	ChangeUsernameTx(ctx context.Context, arg ChangeUsernameTxParams) (User, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (User, error)
+	DisableTOTPTx(ctx context.Context, username string) (User, error)
+	CreateArticleTx(ctx context.Context, arg CreateArticleTxParams) (ArticleTxResult, error)
+	UpdateArticleTx(ctx context.Context, arg UpdateArticleTxParams) (ArticleTxResult, error)
 	Close() error
 }
 
-// SQLStore provides all functions to execute SQL queries
+// SQLStore provides all functions to execute SQL queries and transactions
 type SQLStore struct {
 	*Queries
 	db *sql.DB
@@ -28,3 +38,22 @@ func NewStore(db *sql.DB) Store {
 func (store *SQLStore) Close() error {
 	return store.db.Close()
 }
+
+// execTx executes a function within a database transaction
+func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	q := New(tx)
+	err = fn(q)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx err: %w, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
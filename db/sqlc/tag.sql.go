@@ -0,0 +1,155 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: tag.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+const addArticleTags = `-- name: AddArticleTags :exec
+INSERT INTO article_tags (
+    article_id,
+    tag_id
+) SELECT $1, unnest($2::bigint[])
+ON CONFLICT DO NOTHING
+`
+
+type AddArticleTagsParams struct {
+	ArticleID int64   `json:"article_id"`
+	TagIds    []int64 `json:"tag_ids"`
+}
+
+func (q *Queries) AddArticleTags(ctx context.Context, arg AddArticleTagsParams) error {
+	_, err := q.db.ExecContext(ctx, addArticleTags, arg.ArticleID, pq.Array(arg.TagIds))
+	return err
+}
+
+const deleteArticleTags = `-- name: DeleteArticleTags :exec
+DELETE FROM article_tags
+WHERE article_id = $1
+`
+
+func (q *Queries) DeleteArticleTags(ctx context.Context, articleID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteArticleTags, articleID)
+	return err
+}
+
+const listTags = `-- name: ListTags :many
+SELECT t.name, count(*) AS article_count
+FROM tags t
+JOIN article_tags at ON at.tag_id = t.id
+JOIN articles a ON a.id = at.article_id
+WHERE a.status = 'published'
+GROUP BY t.id
+ORDER BY article_count DESC, t.name
+LIMIT $1
+OFFSET $2
+`
+
+type ListTagsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+type ListTagsRow struct {
+	Name         string `json:"name"`
+	ArticleCount int64  `json:"article_count"`
+}
+
+// Only tags used by some published article are listed, the other articles aren't public
+func (q *Queries) ListTags(ctx context.Context, arg ListTagsParams) ([]ListTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTags, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTagsRow{}
+	for rows.Next() {
+		var i ListTagsRow
+		if err := rows.Scan(&i.Name, &i.ArticleCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagsOfArticles = `-- name: ListTagsOfArticles :many
+SELECT at.article_id, t.name
+FROM article_tags at
+JOIN tags t ON t.id = at.tag_id
+WHERE at.article_id = ANY($1::bigint[])
+ORDER BY at.article_id, t.name
+`
+
+type ListTagsOfArticlesRow struct {
+	ArticleID int64  `json:"article_id"`
+	Name      string `json:"name"`
+}
+
+// Tags of many articles at once, so that lists don't need a query per article
+func (q *Queries) ListTagsOfArticles(ctx context.Context, articleIds []int64) ([]ListTagsOfArticlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTagsOfArticles, pq.Array(articleIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTagsOfArticlesRow{}
+	for rows.Next() {
+		var i ListTagsOfArticlesRow
+		if err := rows.Scan(&i.ArticleID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertTags = `-- name: UpsertTags :many
+INSERT INTO tags (
+    name
+) SELECT unnest($1::varchar[])
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, name, created_at
+`
+
+// Existing tags are returned too, the no-op update makes RETURNING include them
+func (q *Queries) UpsertTags(ctx context.Context, names []string) ([]Tag, error) {
+	rows, err := q.db.QueryContext(ctx, upsertTags, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Tag{}
+	for rows.Next() {
+		var i Tag
+		if err := rows.Scan(&i.ID, &i.Name, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ArticleTxResult is the result of the article transactions
+type ArticleTxResult struct {
+	Article Article
+	Tags    []string
+	// Number of the revision the transaction wrote
+	Revision int32
+}
+
+// CreateArticleTxParams contains the input parameters of the create article transaction
+type CreateArticleTxParams struct {
+	CreateArticleParams
+	// Normalized tag names
+	Tags []string
+}
+
+// CreateArticleTx creates a new article with its tags and its first revision. Tags that don't exist yet are created
+func (store *SQLStore) CreateArticleTx(ctx context.Context, arg CreateArticleTxParams) (ArticleTxResult, error) {
+	var result ArticleTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.Article, err = q.CreateArticle(ctx, arg.CreateArticleParams)
+		if err != nil {
+			return err
+		}
+
+		result.Revision, err = addArticleRevision(ctx, q, result.Article, arg.Author, sql.NullInt32{})
+		if err != nil {
+			return err
+		}
+
+		result.Tags, err = setArticleTags(ctx, q, result.Article.ID, arg.Tags)
+		return err
+	})
+
+	return result, err
+}
+
+// UpdateArticleTxParams contains the input parameters of the update article transaction
+type UpdateArticleTxParams struct {
+	UpdateArticleParams
+	// Normalized tag names replacing the current ones. Tags are left as they are when it's nil
+	Tags []string
+	// User making the change, recorded in the revision
+	Editor string
+	// Set when the update restores an older revision
+	RestoredFrom sql.NullInt32
+}
+
+// UpdateArticleTx updates an article, records the result as a new revision and replaces its tags
+func (store *SQLStore) UpdateArticleTx(ctx context.Context, arg UpdateArticleTxParams) (ArticleTxResult, error) {
+	var result ArticleTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.Article, err = q.UpdateArticle(ctx, arg.UpdateArticleParams)
+		if err != nil {
+			return err
+		}
+
+		result.Revision, err = addArticleRevision(ctx, q, result.Article, arg.Editor, arg.RestoredFrom)
+		if err != nil {
+			return err
+		}
+
+		if arg.Tags == nil {
+			result.Tags, err = listArticleTags(ctx, q, result.Article.ID)
+			return err
+		}
+
+		err = q.DeleteArticleTags(ctx, result.Article.ID)
+		if err != nil {
+			return err
+		}
+
+		result.Tags, err = setArticleTags(ctx, q, result.Article.ID, arg.Tags)
+		return err
+	})
+
+	return result, err
+}
+
+func addArticleRevision(ctx context.Context, q *Queries, article Article, editor string, restoredFrom sql.NullInt32) (int32, error) {
+	revision, err := q.CreateArticleRevision(ctx, CreateArticleRevisionParams{
+		ArticleID:    article.ID,
+		Headline:     article.Headline,
+		Content:      article.Content,
+		Editor:       editor,
+		RestoredFrom: restoredFrom,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return revision.Revision, nil
+}
+
+func setArticleTags(ctx context.Context, q *Queries, articleID int64, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return []string{}, nil
+	}
+
+	tags, err := q.UpsertTags(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	tagIDs := make([]int64, len(tags))
+	for i, tag := range tags {
+		tagIDs[i] = tag.ID
+	}
+	err = q.AddArticleTags(ctx, AddArticleTagsParams{
+		ArticleID: articleID,
+		TagIds:    tagIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return listArticleTags(ctx, q, articleID)
+}
+
+func listArticleTags(ctx context.Context, q *Queries, articleID int64) ([]string, error) {
+	rows, err := q.ListTagsOfArticles(ctx, []int64{articleID})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, len(rows))
+	for i, row := range rows {
+		tags[i] = row.Name
+	}
+	return tags, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+)
+
+// EnableTOTPTxParams contains the input parameters of the enable TOTP transaction
+type EnableTOTPTxParams struct {
+	Username            string
+	HashedRecoveryCodes []string
+}
+
+// EnableTOTPTx turns on two-factor authentication for the user
+// and replaces all of the user's recovery codes with the new ones
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		user, err = q.EnableUserTOTP(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			_, err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return user, err
+}
+
+// DisableTOTPTx turns off two-factor authentication for the user and removes the user's recovery codes
+func (store *SQLStore) DisableTOTPTx(ctx context.Context, username string) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		user, err = q.DisableUserTOTP(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteRecoveryCodes(ctx, username)
+	})
+
+	return user, err
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrInvalidInvitation is returned when the invitation code doesn't match any usable invitation
+var ErrInvalidInvitation = errors.New("invitation is invalid, expired or already used")
+
+// CreateUserTxParams contains the input parameters of the create user transaction
+type CreateUserTxParams struct {
+	CreateUserParams
+	HashedInvitationCode string
+}
+
+// CreateUserTx creates a new user and consumes the invitation the user registered with.
+// The user isn't created if the invitation can't be used.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		user, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.UseInvitation(ctx, UseInvitationParams{
+			UsedBy:     sql.NullString{String: user.Username, Valid: true},
+			HashedCode: arg.HashedInvitationCode,
+			Email:      user.Email,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidInvitation
+		}
+		return err
+	})
+
+	return user, err
+}
+
+// ChangeUsernameTxParams contains the input parameters of the change username transaction
+type ChangeUsernameTxParams struct {
+	Username    string
+	NewUsername string
+}
+
+// ChangeUsernameTx renames the user. Articles and other references follow thanks to ON UPDATE CASCADE.
+// A redirect from the old username is kept, so that old links still resolve
+func (store *SQLStore) ChangeUsernameTx(ctx context.Context, arg ChangeUsernameTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		user, err = q.UpdateUsername(ctx, UpdateUsernameParams{
+			Username:    arg.Username,
+			NewUsername: arg.NewUsername,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The new username is taken for real now, it can't redirect anywhere anymore
+		err = q.DeleteUsernameRedirect(ctx, arg.NewUsername)
+		if err != nil {
+			return err
+		}
+
+		return q.CreateUsernameRedirect(ctx, CreateUsernameRedirectParams{
+			OldUsername: arg.Username,
+			Username:    arg.NewUsername,
+		})
+	})
+
+	return user, err
+}
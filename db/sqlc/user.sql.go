@@ -7,6 +7,9 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -17,7 +20,7 @@ INSERT INTO users (
     email
 ) VALUES (
     $1, $2, $3, $4
-) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
 `
 
 type CreateUserParams struct {
@@ -42,12 +45,134 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const disableUser = `-- name: DisableUser :one
+UPDATE users
+SET disabled_at = COALESCE(disabled_at, NOW())
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+func (q *Queries) DisableUser(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, disableUser, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const disableUserTOTP = `-- name: DisableUserTOTP :one
+UPDATE users
+SET
+    totp_secret = '',
+    totp_enabled = false,
+    totp_last_step = 0
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, disableUserTOTP, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const enableUser = `-- name: EnableUser :one
+UPDATE users
+SET disabled_at = NULL
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+func (q *Queries) EnableUser(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableUser, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :one
+UPDATE users
+SET totp_enabled = true
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableUserTOTP, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -61,6 +186,375 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const getUserByLogin = `-- name: GetUserByLogin :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required FROM users
+WHERE lower(username) = lower($1::varchar)
+    OR email = lower($1::varchar)
+LIMIT 1
+`
+
+func (q *Queries) GetUserByLogin(ctx context.Context, login string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByLogin, login)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const listUserAccess = `-- name: ListUserAccess :many
+SELECT username, role, disabled_at FROM users
+WHERE username = ANY($1::varchar[])
+`
+
+type ListUserAccessRow struct {
+	Username   string       `json:"username"`
+	Role       string       `json:"role"`
+	DisabledAt sql.NullTime `json:"disabled_at"`
+}
+
+// Read on every authenticated request, so that disabling an account takes effect immediately
+func (q *Queries) ListUserAccess(ctx context.Context, usernames []string) ([]ListUserAccessRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserAccess, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserAccessRow{}
+	for rows.Next() {
+		var i ListUserAccessRow
+		if err := rows.Scan(&i.Username, &i.Role, &i.DisabledAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required FROM users
+WHERE ($1::text IS NULL
+        OR username ILIKE '%' || $1::text || '%'
+        OR full_name ILIKE '%' || $1::text || '%'
+        OR email ILIKE '%' || $1::text || '%')
+    AND ($2::varchar IS NULL OR role = $2::varchar)
+    AND ($3::bool IS NULL OR (disabled_at IS NOT NULL) = $3::bool)
+ORDER BY created_at DESC, username
+LIMIT $5
+OFFSET $4
+`
+
+type ListUsersParams struct {
+	Search   sql.NullString `json:"search"`
+	Role     sql.NullString `json:"role"`
+	Disabled sql.NullBool   `json:"disabled"`
+	Offset   int32          `json:"offset"`
+	Limit    int32          `json:"limit"`
+}
+
+// Filters are skipped when null. Search is matched as a substring of username, full name and email
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.Search,
+		arg.Role,
+		arg.Disabled,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.Username,
+			&i.HashedPassword,
+			&i.FullName,
+			&i.Email,
+			&i.PasswordChangedAt,
+			&i.CreatedAt,
+			&i.TotpSecret,
+			&i.TotpEnabled,
+			&i.TotpLastStep,
+			&i.Role,
+			&i.AvatarID,
+			&i.DisabledAt,
+			&i.PasswordResetRequired,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const requirePasswordReset = `-- name: RequirePasswordReset :one
+UPDATE users
+SET password_reset_required = true
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+func (q *Queries) RequirePasswordReset(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, requirePasswordReset, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const setUserAvatar = `-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_id = $2
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+type SetUserAvatarParams struct {
+	Username string `json:"username"`
+	AvatarID string `json:"avatar_id"`
+}
+
+func (q *Queries) SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserAvatar, arg.Username, arg.AvatarID)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+type SetUserRoleParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.Username, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :one
+UPDATE users
+SET
+    totp_secret = $1,
+    totp_enabled = false,
+    totp_last_step = 0
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+type SetUserTOTPSecretParams struct {
+	TotpSecret string `json:"totp_secret"`
+	Username   string `json:"username"`
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserTOTPSecret, arg.TotpSecret, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
 	)
 	return i, err
 }
+
+const updateUserHashedPassword = `-- name: UpdateUserHashedPassword :exec
+UPDATE users
+SET hashed_password = $1
+WHERE username = $2
+`
+
+type UpdateUserHashedPasswordParams struct {
+	HashedPassword string `json:"hashed_password"`
+	Username       string `json:"username"`
+}
+
+func (q *Queries) UpdateUserHashedPassword(ctx context.Context, arg UpdateUserHashedPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserHashedPassword, arg.HashedPassword, arg.Username)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET
+    hashed_password = $1,
+    password_changed_at = NOW(),
+    password_reset_required = false
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string `json:"hashed_password"`
+	Username       string `json:"username"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.HashedPassword, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const updateUsername = `-- name: UpdateUsername :one
+UPDATE users
+SET username = $1
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, totp_secret, totp_enabled, totp_last_step, role, avatar_id, disabled_at, password_reset_required
+`
+
+type UpdateUsernameParams struct {
+	NewUsername string `json:"new_username"`
+	Username    string `json:"username"`
+}
+
+func (q *Queries) UpdateUsername(ctx context.Context, arg UpdateUsernameParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUsername, arg.NewUsername, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.Role,
+		&i.AvatarID,
+		&i.DisabledAt,
+		&i.PasswordResetRequired,
+	)
+	return i, err
+}
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $1
+WHERE username = $2 AND totp_last_step < $1
+`
+
+type UseUserTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useUserTOTPStep, arg.Step, arg.Username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,53 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: username_redirect.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createUsernameRedirect = `-- name: CreateUsernameRedirect :exec
+INSERT INTO username_redirects (
+    old_username,
+    username
+) VALUES (
+    $1, $2
+) ON CONFLICT (old_username) DO UPDATE SET
+    username = EXCLUDED.username,
+    created_at = NOW()
+`
+
+type CreateUsernameRedirectParams struct {
+	OldUsername string `json:"old_username"`
+	Username    string `json:"username"`
+}
+
+func (q *Queries) CreateUsernameRedirect(ctx context.Context, arg CreateUsernameRedirectParams) error {
+	_, err := q.db.ExecContext(ctx, createUsernameRedirect, arg.OldUsername, arg.Username)
+	return err
+}
+
+const deleteUsernameRedirect = `-- name: DeleteUsernameRedirect :exec
+DELETE FROM username_redirects
+WHERE old_username = $1
+`
+
+func (q *Queries) DeleteUsernameRedirect(ctx context.Context, oldUsername string) error {
+	_, err := q.db.ExecContext(ctx, deleteUsernameRedirect, oldUsername)
+	return err
+}
+
+const getUsernameRedirect = `-- name: GetUsernameRedirect :one
+SELECT username FROM username_redirects
+WHERE old_username = $1 LIMIT 1
+`
+
+func (q *Queries) GetUsernameRedirect(ctx context.Context, oldUsername string) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUsernameRedirect, oldUsername)
+	var username string
+	err := row.Scan(&username)
+	return username, err
+}
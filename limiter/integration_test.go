@@ -0,0 +1,105 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kamilwrzyszcz/go_example/util"
+	"github.com/stretchr/testify/suite"
+)
+
+// Integration tests
+
+type RedisIntegrationTestSuite struct {
+	suite.Suite
+	limiter *RedisLimiter
+}
+
+func TestRedisIntegrationTestSuite(t *testing.T) {
+	suite.Run(t, &RedisIntegrationTestSuite{})
+}
+
+func (its *RedisIntegrationTestSuite) SetupSuite() {
+	config, err := util.LoadConfig("./..")
+	if err != nil {
+		its.FailNowf("cannot load config: ", err.Error())
+	}
+
+	its.limiter, err = NewRedisLimiter(config.RedisAddress, config.RedisPassword)
+	if err != nil {
+		its.FailNowf("cannot create redis limiter: ", err.Error())
+	}
+}
+
+func (its *RedisIntegrationTestSuite) TearDownSuite() {
+	tearDownRedis(its)
+}
+
+// Tests
+
+func (its *RedisIntegrationTestSuite) TestFailAndCheck() {
+	key := "user:" + util.RandomAuthor()
+	policy := Policy{
+		MaxAttempts: 2,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+		Window:      time.Hour,
+	}
+
+	// Free attempts
+	for i := 0; i < 2; i++ {
+		delay, err := its.limiter.Fail(context.Background(), key, policy)
+		its.NoError(err)
+		its.Zero(delay)
+	}
+
+	wait, err := its.limiter.Check(context.Background(), key)
+	its.NoError(err)
+	its.Zero(wait)
+
+	// Locked
+	delay, err := its.limiter.Fail(context.Background(), key, policy)
+	its.NoError(err)
+	its.Equal(time.Minute, delay)
+
+	wait, err = its.limiter.Check(context.Background(), "ip:0.0.0.0", key)
+	its.NoError(err)
+	its.InDelta(time.Minute, wait, float64(time.Second))
+
+	// Backoff grows
+	delay, err = its.limiter.Fail(context.Background(), key, policy)
+	its.NoError(err)
+	its.Equal(2*time.Minute, delay)
+}
+
+func (its *RedisIntegrationTestSuite) TestReset() {
+	key := "user:" + util.RandomAuthor()
+	policy := Policy{
+		MaxAttempts: 0,
+		BaseDelay:   time.Minute,
+		Window:      time.Hour,
+	}
+
+	delay, err := its.limiter.Fail(context.Background(), key, policy)
+	its.NoError(err)
+	its.Equal(time.Minute, delay)
+
+	err = its.limiter.Reset(context.Background(), key)
+	its.NoError(err)
+
+	wait, err := its.limiter.Check(context.Background(), key)
+	its.NoError(err)
+	its.Zero(wait)
+}
+
+// Setup helper functions
+
+func tearDownRedis(its *RedisIntegrationTestSuite) {
+	its.T().Log("tearing down redis")
+
+	err := its.limiter.rdb.Close()
+	if err != nil {
+		its.FailNowf("failed to close redis client: ", err.Error())
+	}
+}
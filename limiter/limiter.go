@@ -0,0 +1,46 @@
+package limiter
+
+import (
+	"context"
+	"time"
+)
+
+// Policy describes how failed attempts are throttled.
+// The first MaxAttempts failures inside Window are free, every following one
+// locks the key for BaseDelay doubled per additional failure, capped at MaxDelay.
+type Policy struct {
+	MaxAttempts int64
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Window      time.Duration
+}
+
+// Delay returns for how long a key should be locked after the given number of failures
+func (policy Policy) Delay(failures int64) time.Duration {
+	if failures <= policy.MaxAttempts || policy.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := policy.BaseDelay
+	for i := policy.MaxAttempts + 1; i < failures; i++ {
+		if policy.MaxDelay > 0 && delay >= policy.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	return delay
+}
+
+// Limiter keeps track of failed attempts per key (username, client IP etc.)
+type Limiter interface {
+	// Check returns how long the caller has to wait before the next attempt for any of the keys
+	Check(ctx context.Context, keys ...string) (time.Duration, error)
+	// Fail registers a failed attempt for the key and returns the resulting lockout
+	Fail(ctx context.Context, key string, policy Policy) (time.Duration, error)
+	// Reset clears failed attempts and lockouts of the keys
+	Reset(ctx context.Context, keys ...string) error
+}
@@ -0,0 +1,36 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	policy := Policy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    10 * time.Second,
+		Window:      time.Hour,
+	}
+
+	testCases := []struct {
+		failures int64
+		delay    time.Duration
+	}{
+		{failures: 0, delay: 0},
+		{failures: 3, delay: 0},
+		{failures: 4, delay: time.Second},
+		{failures: 5, delay: 2 * time.Second},
+		{failures: 7, delay: 8 * time.Second},
+		{failures: 8, delay: 10 * time.Second},
+		{failures: 1000, delay: 10 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.delay, policy.Delay(tc.failures), "failures: %d", tc.failures)
+	}
+
+	require.Zero(t, Policy{}.Delay(10))
+}
@@ -0,0 +1,90 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+const (
+	failuresKeyPrefix = "limiter:failures:"
+	lockKeyPrefix     = "limiter:lock:"
+)
+
+// RedisLimiter Provides Redis-backed failed attempt counters
+type RedisLimiter struct {
+	rdb *redis.Client
+}
+
+// NewRedisLimiter creates and returns a new RedisLimiter
+func NewRedisLimiter(address, password string) (*RedisLimiter, error) {
+	limiter := &RedisLimiter{
+		rdb: redis.NewClient(&redis.Options{
+			Addr:        address,
+			Password:    password,
+			DB:          0, // default db
+			DialTimeout: 100 * time.Millisecond,
+			ReadTimeout: 100 * time.Millisecond,
+		}),
+	}
+
+	if _, err := limiter.rdb.Ping(context.Background()).Result(); err != nil {
+		return nil, err
+	}
+
+	return limiter, nil
+}
+
+// Check Returns the longest remaining lockout of the keys
+func (limiter *RedisLimiter) Check(ctx context.Context, keys ...string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range keys {
+		ttl, err := limiter.rdb.PTTL(ctx, lockKeyPrefix+key).Result()
+		if err != nil {
+			return 0, fmt.Errorf("couldn't get lock ttl from redis: %w", err)
+		}
+		// negative values mean there is no lock or it has no expiration
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+
+	return wait, nil
+}
+
+// Fail Increments failed attempts counter of the key and locks it when the policy says so
+func (limiter *RedisLimiter) Fail(ctx context.Context, key string, policy Policy) (time.Duration, error) {
+	var incr *redis.IntCmd
+	_, err := limiter.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, failuresKeyPrefix+key)
+		pipe.Expire(ctx, failuresKeyPrefix+key, policy.Window)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't increment failed attempts: %w", err)
+	}
+
+	delay := policy.Delay(incr.Val())
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	err = limiter.rdb.Set(ctx, lockKeyPrefix+key, incr.Val(), delay).Err()
+	if err != nil {
+		return 0, fmt.Errorf("couldn't set lock: %w", err)
+	}
+
+	return delay, nil
+}
+
+// Reset Deletes failed attempts counters and locks of the keys
+func (limiter *RedisLimiter) Reset(ctx context.Context, keys ...string) error {
+	redisKeys := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		redisKeys = append(redisKeys, failuresKeyPrefix+key, lockKeyPrefix+key)
+	}
+
+	return limiter.rdb.Del(ctx, redisKeys...).Err()
+}
@@ -0,0 +1,20 @@
+package mail
+
+import (
+	"context"
+	"log"
+)
+
+// LogMailer only logs the emails. Meant for development, where there is no SMTP server to talk to
+type LogMailer struct{}
+
+// NewLogMailer creates a new LogMailer
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the message
+func (mailer *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("email to %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
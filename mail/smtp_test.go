@@ -0,0 +1,23 @@
+package mail
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMessage(t *testing.T) {
+	msg := buildMessage("noreply@example.com", Message{
+		To:      "user@example.com\r\nBcc: victim@example.com",
+		Subject: "Hello",
+		Body:    "line 1\nline 2",
+	})
+
+	text := string(msg)
+	require.True(t, strings.HasPrefix(text, "From: noreply@example.com\r\n"))
+	require.Contains(t, text, "To: user@example.comBcc: victim@example.com\r\n")
+	require.NotContains(t, text, "\r\nBcc:")
+	require.Contains(t, text, "Subject: Hello\r\n")
+	require.True(t, strings.HasSuffix(text, "\r\n\r\nline 1\r\nline 2"))
+}
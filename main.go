@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 
 	"github.com/kamilwrzyszcz/go_example/api"
+	"github.com/kamilwrzyszcz/go_example/blob"
 	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/kamilwrzyszcz/go_example/limiter"
+	"github.com/kamilwrzyszcz/go_example/mail"
+	"github.com/kamilwrzyszcz/go_example/publisher"
 	"github.com/kamilwrzyszcz/go_example/session"
 	"github.com/kamilwrzyszcz/go_example/util"
 )
@@ -27,10 +32,46 @@ func main() {
 		log.Fatal("cannot connect to session client: ", err)
 	}
 
-	server, err := api.NewServer(config, store, sessionClient)
+	loginLimiter, err := limiter.NewRedisLimiter(config.RedisAddress, config.RedisPassword)
+	if err != nil {
+		log.Fatal("cannot connect to limiter client: ", err)
+	}
+
+	var blobStore blob.BlobStore
+	switch config.BlobStore {
+	case "s3":
+		blobStore, err = blob.NewS3Store(blob.S3Config{
+			Endpoint:        config.S3Endpoint,
+			Region:          config.S3Region,
+			Bucket:          config.S3Bucket,
+			AccessKeyID:     config.S3AccessKeyID,
+			SecretAccessKey: config.S3SecretAccessKey,
+			PublicURL:       config.BlobPublicURL,
+		})
+	default:
+		blobStore, err = blob.NewLocalStore(config.BlobLocalDir, config.BlobPublicURL)
+	}
+	if err != nil {
+		log.Fatal("cannot create blob store: ", err)
+	}
+
+	// Without SMTP server emails are only logged, which is enough for development
+	var mailer mail.Mailer = mail.NewLogMailer()
+	if config.SMTPHost != "" {
+		mailer = mail.NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	}
+
+	// Scheduled articles are published in the background, running it in every instance is safe
+	go publisher.NewPublisher(store, config.PublisherInterval).Run(context.Background())
+
+	server, err := api.NewServer(config, store, sessionClient, loginLimiter, blobStore, mailer)
 	if err != nil {
 		log.Fatal("cannot create server: ", err)
 	}
+	err = server.ReindexSearch(context.Background())
+	if err != nil {
+		log.Fatal("cannot prepare search: ", err)
+	}
 
 	err = server.Start(config.ServerAddress)
 	if err != nil {
@@ -0,0 +1,71 @@
+package publisher
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+)
+
+const (
+	defaultInterval = time.Minute
+	batchSize       = 100
+)
+
+// Publisher publishes scheduled articles once their publish_at comes.
+// Every API instance can run one, the query makes sure each article is published only once
+type Publisher struct {
+	store    db.Store
+	interval time.Duration
+}
+
+// NewPublisher creates a new Publisher checking for due articles every interval
+func NewPublisher(store db.Store, interval time.Duration) *Publisher {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Publisher{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// PublishDue publishes all articles that are due and returns how many of them it published
+func (publisher *Publisher) PublishDue(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		articles, err := publisher.store.PublishScheduledArticles(ctx, batchSize)
+		if err != nil {
+			return published, err
+		}
+		published += len(articles)
+
+		// A smaller batch means there is nothing left, or another instance has the rest
+		if len(articles) < batchSize {
+			return published, nil
+		}
+	}
+}
+
+// Run publishes due articles every interval until the context is done
+func (publisher *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(publisher.interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := publisher.PublishDue(ctx)
+		if err != nil {
+			log.Printf("cannot publish scheduled articles: %v", err)
+		}
+		if published > 0 {
+			log.Printf("published %d scheduled articles", published)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package publisher
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/kamilwrzyszcz/go_example/db/mock"
+	db "github.com/kamilwrzyszcz/go_example/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishDue(t *testing.T) {
+	testCases := []struct {
+		name       string
+		buildStubs func(store *mockdb.MockStore)
+		published  int
+		err        bool
+	}{
+		{
+			name: "NothingDue",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					PublishScheduledArticles(gomock.Any(), gomock.Eq(int32(batchSize))).
+					Times(1).
+					Return([]db.Article{}, nil)
+			},
+		},
+		{
+			name: "ManyBatches",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						PublishScheduledArticles(gomock.Any(), gomock.Eq(int32(batchSize))).
+						Times(2).
+						Return(make([]db.Article, batchSize), nil),
+					store.EXPECT().
+						PublishScheduledArticles(gomock.Any(), gomock.Eq(int32(batchSize))).
+						Times(1).
+						Return(make([]db.Article, 3), nil),
+				)
+			},
+			published: 2*batchSize + 3,
+		},
+		{
+			name: "Error",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().
+						PublishScheduledArticles(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(make([]db.Article, batchSize), nil),
+					store.EXPECT().
+						PublishScheduledArticles(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(nil, sql.ErrConnDone),
+				)
+			},
+			published: batchSize,
+			err:       true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			published, err := NewPublisher(store, time.Second).PublishDue(context.Background())
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.published, published)
+		})
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		PublishScheduledArticles(gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		Return([]db.Article{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		NewPublisher(store, 10*time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publisher didn't stop")
+	}
+}
@@ -95,6 +95,96 @@ func (its *RedisIntegrationTestSuite) TestDelete() {
 	its.NoError(err)
 }
 
+func (its *RedisIntegrationTestSuite) TestDelByUsername() {
+	session1 := createRandomSession()
+	err := its.client.Set(context.Background(), session1.ID, session1)
+	its.NoError(err)
+
+	session2 := createRandomSession()
+	session2.Username = session1.Username
+	err = its.client.Set(context.Background(), session2.ID, session2)
+	its.NoError(err)
+
+	session3 := createRandomSession()
+	err = its.client.Set(context.Background(), session3.ID, session3)
+	its.NoError(err)
+
+	err = its.client.DelByUsername(context.Background(), session1.Username)
+	its.NoError(err)
+
+	_, err = its.client.Get(context.Background(), session1.ID)
+	its.Error(err)
+	_, err = its.client.Get(context.Background(), session2.ID)
+	its.Error(err)
+
+	// Sessions of other users are left alone
+	session4, err := its.client.Get(context.Background(), session3.ID)
+	its.NoError(err)
+	its.Equal(session3.ID, session4.ID)
+
+	// User without sessions
+	err = its.client.DelByUsername(context.Background(), "non-existing-user")
+	its.NoError(err)
+}
+
+func (its *RedisIntegrationTestSuite) TestShortSessionKeepsIndex() {
+	long := createRandomSession()
+	long.ExpiresAt = time.Now().Add(24 * time.Hour)
+	err := its.client.Set(context.Background(), long.ID, long)
+	its.NoError(err)
+
+	// A short session set later, like an impersonation one, doesn't shorten the index
+	short := createRandomSession()
+	short.Username = long.Username
+	short.ExpiresAt = time.Now().Add(time.Minute)
+	err = its.client.Set(context.Background(), short.ID, short)
+	its.NoError(err)
+
+	ttl, err := its.client.rdb.TTL(context.Background(), userSessionsKey(long.Username)).Result()
+	its.NoError(err)
+	its.Greater(ttl, 23*time.Hour)
+
+	// A longer one still extends it
+	longer := createRandomSession()
+	longer.Username = long.Username
+	longer.ExpiresAt = time.Now().Add(48 * time.Hour)
+	err = its.client.Set(context.Background(), longer.ID, longer)
+	its.NoError(err)
+
+	ttl, err = its.client.rdb.TTL(context.Background(), userSessionsKey(long.Username)).Result()
+	its.NoError(err)
+	its.Greater(ttl, 47*time.Hour)
+
+	err = its.client.DelByUsername(context.Background(), long.Username)
+	its.NoError(err)
+	_, err = its.client.Get(context.Background(), long.ID)
+	its.Error(err)
+}
+
+func (its *RedisIntegrationTestSuite) TestListByUsername() {
+	session1 := createRandomSession()
+	err := its.client.Set(context.Background(), session1.ID, session1)
+	its.NoError(err)
+
+	session2 := createRandomSession()
+	session2.Username = session1.Username
+	err = its.client.Set(context.Background(), session2.ID, session2)
+	its.NoError(err)
+
+	// Deleted sessions are skipped
+	err = its.client.Del(context.Background(), session2.ID)
+	its.NoError(err)
+
+	sessions, err := its.client.ListByUsername(context.Background(), session1.Username)
+	its.NoError(err)
+	its.Len(sessions, 1)
+	its.Equal(session1.ID, sessions[0].ID)
+
+	sessions, err = its.client.ListByUsername(context.Background(), "non-existing-user")
+	its.NoError(err)
+	its.Empty(sessions)
+}
+
 // Setup helper functions
 
 func createRandomSession() *Session {
@@ -49,6 +49,20 @@ func (mr *MockSessionClientMockRecorder) Del(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Del", reflect.TypeOf((*MockSessionClient)(nil).Del), arg0, arg1)
 }
 
+// DelByUsername mocks base method.
+func (m *MockSessionClient) DelByUsername(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DelByUsername", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DelByUsername indicates an expected call of DelByUsername.
+func (mr *MockSessionClientMockRecorder) DelByUsername(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelByUsername", reflect.TypeOf((*MockSessionClient)(nil).DelByUsername), arg0, arg1)
+}
+
 // Get mocks base method.
 func (m *MockSessionClient) Get(arg0 context.Context, arg1 string) (*session.Session, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +78,21 @@ func (mr *MockSessionClientMockRecorder) Get(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSessionClient)(nil).Get), arg0, arg1)
 }
 
+// ListByUsername mocks base method.
+func (m *MockSessionClient) ListByUsername(arg0 context.Context, arg1 string) ([]*session.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUsername", arg0, arg1)
+	ret0, _ := ret[0].([]*session.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUsername indicates an expected call of ListByUsername.
+func (mr *MockSessionClientMockRecorder) ListByUsername(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUsername", reflect.TypeOf((*MockSessionClient)(nil).ListByUsername), arg0, arg1)
+}
+
 // Set mocks base method.
 func (m *MockSessionClient) Set(arg0 context.Context, arg1 string, arg2 *session.Session) error {
 	m.ctrl.T.Helper()
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
-	
+
 	"github.com/go-redis/redis/v9"
 )
 
@@ -44,7 +44,18 @@ func (client *RedisClient) Set(ctx context.Context, key string, session *Session
 	t2 := session.ExpiresAt
 	til := t2.Sub(t1)
 
-	return client.rdb.Set(ctx, key, body, til).Err()
+	// Sessions are indexed by username, so that all of them can be found at once.
+	// The index lives as long as the longest lived session, a shorter one never cuts it down.
+	// GT treats a key without expiry as living forever, NX gives a new index its first expiry
+	userKey := userSessionsKey(session.Username)
+	_, err = client.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, body, til)
+		pipe.SAdd(ctx, userKey, key)
+		pipe.ExpireNX(ctx, userKey, til)
+		pipe.ExpireGT(ctx, userKey, til)
+		return nil
+	})
+	return err
 }
 
 // Get Gets value from redis by key
@@ -73,3 +84,55 @@ func (client *RedisClient) Get(ctx context.Context, key string) (*Session, error
 func (client *RedisClient) Del(ctx context.Context, key string) error {
 	return client.rdb.Del(ctx, key).Err()
 }
+
+// DelByUsername Deletes all sessions of the user from redis
+func (client *RedisClient) DelByUsername(ctx context.Context, username string) error {
+	userKey := userSessionsKey(username)
+
+	keys, err := client.rdb.SMembers(ctx, userKey).Result()
+	if err != nil {
+		return fmt.Errorf("couldn't get user sessions from redis: %w", err)
+	}
+
+	// Index may still hold sessions that are already deleted or expired, deleting them again is harmless
+	keys = append(keys, userKey)
+	return client.rdb.Del(ctx, keys...).Err()
+}
+
+// ListByUsername Gets all active sessions of the user from redis
+func (client *RedisClient) ListByUsername(ctx context.Context, username string) ([]*Session, error) {
+	keys, err := client.rdb.SMembers(ctx, userSessionsKey(username)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get user sessions from redis: %w", err)
+	}
+	if len(keys) == 0 {
+		return []*Session{}, nil
+	}
+
+	values, err := client.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get values from redis: %w", err)
+	}
+
+	sessions := make([]*Session, 0, len(values))
+	for _, value := range values {
+		// Index may still hold sessions that are already deleted or expired
+		body, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var session Session
+		err = json.Unmarshal([]byte(body), &session)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal: %w", err)
+		}
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, nil
+}
+
+func userSessionsKey(username string) string {
+	return "user_sessions:" + username
+}
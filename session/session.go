@@ -15,10 +15,14 @@ type Session struct {
 	UserAgent    string    `json:"user_agent"`
 	ClientIP     string    `json:"client_ip"`
 	IsBlocked    bool      `json:"is_blocked"`
+	// Username of the admin acting as the user, empty for regular sessions
+	Impersonator string `json:"impersonator,omitempty"`
 }
 
 type SessionClient interface {
 	Set(ctx context.Context, key string, session *Session) error
 	Get(ctx context.Context, key string) (*Session, error)
 	Del(ctx context.Context, key string) error
+	DelByUsername(ctx context.Context, username string) error
+	ListByUsername(ctx context.Context, username string) ([]*Session, error)
 }
@@ -29,6 +29,28 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 func (maker *JWTMaker) CreateToken(sessionID uuid.UUID, username string, duration time.Duration) (string, *Payload, error) {
 	payload := NewPayload(sessionID, username, duration)
 
+	return maker.sign(payload)
+}
+
+// CreateImpersonationToken creates a new token for a specific username, impersonator and duration
+func (maker *JWTMaker) CreateImpersonationToken(sessionID uuid.UUID, username string, impersonator string, duration time.Duration) (string, *Payload, error) {
+	payload := NewPayload(sessionID, username, duration)
+	payload.Impersonator = impersonator
+
+	return maker.sign(payload)
+}
+
+// CreatePurposeToken creates a new token for a specific purpose, username and duration
+func (maker *JWTMaker) CreatePurposeToken(purpose string, username string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPurposePayload(purpose, username, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return maker.sign(payload)
+}
+
+func (maker *JWTMaker) sign(payload *Payload) (string, *Payload, error) {
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
 	token, err := jwtToken.SignedString([]byte(maker.secretKey))
@@ -36,6 +36,48 @@ func TestJWTMaker(t *testing.T) {
 	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
 }
 
+func TestJWTMakerPurposeToken(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	username := util.RandomAuthor()
+
+	token, payload, err := maker.CreatePurposeToken(PurposeMFAChallenge, username, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, PurposeMFAChallenge, payload.Purpose)
+	require.NoError(t, payload.CheckPurpose(PurposeMFAChallenge))
+	require.EqualError(t, payload.CheckPurpose(PurposeSession), ErrInvalidToken.Error())
+}
+
+func TestJWTMakerImpersonationToken(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	username := util.RandomAuthor()
+	impersonator := util.RandomAuthor()
+	sessionID, err := uuid.NewRandom()
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateImpersonationToken(sessionID, username, impersonator, time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, sessionID, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, impersonator, payload.Impersonator)
+	require.NoError(t, payload.CheckPurpose(PurposeSession))
+}
+
 func TestExpiredJWTToken(t *testing.T) {
 	maker, err := NewJWTMaker(util.RandomString(32))
 	require.NoError(t, err)
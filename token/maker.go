@@ -11,6 +11,12 @@ type Maker interface {
 	// CreateToken creates a new token for a specific username and duration
 	CreateToken(sessionID uuid.UUID, username string, duration time.Duration) (string, *Payload, error)
 
+	// CreateImpersonationToken creates a new session token for a specific username, recording the admin acting as them
+	CreateImpersonationToken(sessionID uuid.UUID, username string, impersonator string, duration time.Duration) (string, *Payload, error)
+
+	// CreatePurposeToken creates a new token, not bound to any session, for a specific purpose, username and duration
+	CreatePurposeToken(purpose string, username string, duration time.Duration) (string, *Payload, error)
+
 	// VerifyToken checks if the token is valid or not
 	VerifyToken(token string) (*Payload, error)
 }
@@ -13,12 +13,24 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// Purposes of the tokens. Only session tokens are bound to a session,
+// the rest are short-lived tokens used for a single step of some flow.
+const (
+	PurposeSession       = ""
+	PurposeMFAChallenge  = "mfa_challenge"
+	PurposeMagicLink     = "magic_link"
+	PurposePasswordReset = "password_reset"
+)
+
 // Payload contains the payload data of the token
 type Payload struct {
-	ID        uuid.UUID `json:"id"`
-	Username  string    `json:"username"`
-	IssuedAt  time.Time `json:"issued_at"`
-	ExpiredAt time.Time `json:"expired_at"`
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Purpose  string    `json:"purpose,omitempty"`
+	// Username of the admin acting as the user, empty for regular sessions
+	Impersonator string    `json:"impersonator,omitempty"`
+	IssuedAt     time.Time `json:"issued_at"`
+	ExpiredAt    time.Time `json:"expired_at"`
 }
 
 // NewPayload creates a new token payload with a specific username and duration
@@ -33,6 +45,19 @@ func NewPayload(sessionID uuid.UUID, username string, duration time.Duration) *P
 	return payload
 }
 
+// NewPurposePayload creates a new token payload for a specific purpose, username and duration
+func NewPurposePayload(purpose string, username string, duration time.Duration) (*Payload, error) {
+	tokenID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := NewPayload(tokenID, username, duration)
+	payload.Purpose = purpose
+
+	return payload, nil
+}
+
 // Valid checks if the token payload is valid or not
 func (payload *Payload) Valid() error {
 	if time.Now().After(payload.ExpiredAt) {
@@ -40,3 +65,11 @@ func (payload *Payload) Valid() error {
 	}
 	return nil
 }
+
+// CheckPurpose checks if the token was issued for the purpose
+func (payload *Payload) CheckPurpose(purpose string) error {
+	if payload.Purpose != purpose {
+		return ErrInvalidToken
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package util
+
+// Statuses of articles. Only published articles can be read by others than their author
+const (
+	ArticleDraft     = "draft"
+	ArticlePublished = "published"
+	// Published automatically once publish_at comes
+	ArticleScheduled = "scheduled"
+	// Taken down, but kept for the author
+	ArticleArchived = "archived"
+)
+
+// articleTransitions lists the statuses an article can go to from each status.
+// Scheduled articles can be rescheduled
+var articleTransitions = map[string][]string{
+	ArticleDraft:     {ArticlePublished, ArticleScheduled},
+	ArticleScheduled: {ArticlePublished, ArticleScheduled, ArticleDraft},
+	ArticlePublished: {ArticleDraft, ArticleArchived},
+	ArticleArchived:  {ArticlePublished, ArticleDraft},
+}
+
+// CanChangeArticleStatus returns true if an article can go from one status to the other
+func CanChangeArticleStatus(from, to string) bool {
+	for _, status := range articleTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanChangeArticleStatus(t *testing.T) {
+	testCases := []struct {
+		from string
+		to   string
+		ok   bool
+	}{
+		{from: ArticleDraft, to: ArticlePublished, ok: true},
+		{from: ArticleDraft, to: ArticleScheduled, ok: true},
+		{from: ArticleDraft, to: ArticleArchived, ok: false},
+		{from: ArticleScheduled, to: ArticleScheduled, ok: true},
+		{from: ArticleScheduled, to: ArticleDraft, ok: true},
+		{from: ArticlePublished, to: ArticleArchived, ok: true},
+		{from: ArticlePublished, to: ArticleScheduled, ok: false},
+		{from: ArticlePublished, to: ArticlePublished, ok: false},
+		{from: ArticleArchived, to: ArticlePublished, ok: true},
+		{from: ArticleArchived, to: ArticleScheduled, ok: false},
+		{from: "unknown", to: ArticlePublished, ok: false},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.ok, CanChangeArticleStatus(tc.from, tc.to), "%s -> %s", tc.from, tc.to)
+	}
+}
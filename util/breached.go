@@ -0,0 +1,82 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Length of the SHA-1 prefix used to split the corpus into range files
+const breachedPrefixLength = 5
+
+// BreachedPasswordChecker checks passwords against a corpus of passwords exposed in data breaches
+type BreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// LocalBreachedPasswords checks passwords against a local copy of the Have I Been Pwned corpus.
+// The directory holds one file per SHA-1 prefix (e.g. 21BD1 or 21BD1.txt), each file in the range API format:
+// the remaining 35 characters of the hash, a colon and the number of times the password was seen.
+// Only the prefix of the hash is needed to find the file, so the corpus can be split and partially shipped.
+type LocalBreachedPasswords struct {
+	dir string
+}
+
+// NewLocalBreachedPasswords creates a new LocalBreachedPasswords reading range files from the directory
+func NewLocalBreachedPasswords(dir string) (*LocalBreachedPasswords, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached passwords directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached passwords path is not a directory: %s", dir)
+	}
+
+	return &LocalBreachedPasswords{dir: dir}, nil
+}
+
+// IsBreached reports if the password is present in the corpus.
+// Missing range file means none of the passwords with that prefix are known.
+func (checker *LocalBreachedPasswords) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	file, err := checker.openRange(prefix)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lineSuffix, count, _ := strings.Cut(line, ":")
+		if strings.EqualFold(lineSuffix, suffix) {
+			// Padding entries of the range API have the count of 0
+			return count != "0", nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("cannot read breached passwords range: %w", err)
+	}
+
+	return false, nil
+}
+
+func (checker *LocalBreachedPasswords) openRange(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(checker.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.Open(filepath.Join(checker.dir, prefix))
+	}
+	return file, err
+}
@@ -18,6 +18,54 @@ type Config struct {
 	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	LoginMaxAttempts     int64         `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginIPMaxAttempts   int64         `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginLockoutDelay    time.Duration `mapstructure:"LOGIN_LOCKOUT_DELAY"`
+	LoginLockoutMaxDelay time.Duration `mapstructure:"LOGIN_LOCKOUT_MAX_DELAY"`
+	LoginAttemptWindow   time.Duration `mapstructure:"LOGIN_ATTEMPT_WINDOW"`
+	MFATokenDuration     time.Duration `mapstructure:"MFA_TOKEN_DURATION"`
+	TOTPIssuer           string        `mapstructure:"TOTP_ISSUER"`
+	PasswordHashAlgo     string        `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	Argon2Memory         uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations     uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism    uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost           int           `mapstructure:"BCRYPT_COST"`
+	PasswordMinLength    int           `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength    int           `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordNeedsUpper   bool          `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordNeedsLower   bool          `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordNeedsDigit   bool          `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordNeedsSymbol  bool          `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	BreachedPasswordsDir string        `mapstructure:"BREACHED_PASSWORDS_DIR"`
+	RegistrationMode     string        `mapstructure:"REGISTRATION_MODE"`
+	InvitationDuration   time.Duration `mapstructure:"INVITATION_DURATION"`
+	BlobStore            string        `mapstructure:"BLOB_STORE"`
+	BlobLocalDir         string        `mapstructure:"BLOB_LOCAL_DIR"`
+	BlobPublicURL        string        `mapstructure:"BLOB_PUBLIC_URL"`
+	S3Endpoint           string        `mapstructure:"S3_ENDPOINT"`
+	S3Region             string        `mapstructure:"S3_REGION"`
+	S3Bucket             string        `mapstructure:"S3_BUCKET"`
+	S3AccessKeyID        string        `mapstructure:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey    string        `mapstructure:"S3_SECRET_ACCESS_KEY"`
+	AvatarMaxSize        int64         `mapstructure:"AVATAR_MAX_SIZE"`
+	SMTPHost             string        `mapstructure:"SMTP_HOST"`
+	SMTPPort             int           `mapstructure:"SMTP_PORT"`
+	SMTPUsername         string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword         string        `mapstructure:"SMTP_PASSWORD"`
+	MailFrom             string        `mapstructure:"MAIL_FROM"`
+	MagicLinkDuration    time.Duration `mapstructure:"MAGIC_LINK_DURATION"`
+	MagicLinkURL         string        `mapstructure:"MAGIC_LINK_URL"`
+	DeviceCodeDuration   time.Duration `mapstructure:"DEVICE_CODE_DURATION"`
+	DevicePollInterval   time.Duration `mapstructure:"DEVICE_POLL_INTERVAL"`
+	DeviceVerifyURL      string        `mapstructure:"DEVICE_VERIFICATION_URL"`
+	ResetTokenDuration   time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	PasswordResetURL     string        `mapstructure:"PASSWORD_RESET_URL"`
+	ImpersonateDuration  time.Duration `mapstructure:"IMPERSONATION_DURATION"`
+	ArticleMaxPageSize   int32         `mapstructure:"ARTICLE_MAX_PAGE_SIZE"`
+	SearchLanguage       string        `mapstructure:"SEARCH_LANGUAGE"`
+	CommentEditWindow    time.Duration `mapstructure:"COMMENT_EDIT_WINDOW"`
+	PublisherInterval    time.Duration `mapstructure:"PUBLISHER_INTERVAL"`
+	ArticleNeedsIfMatch  bool          `mapstructure:"ARTICLE_REQUIRE_IF_MATCH"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Device authorization statuses
+const (
+	DeviceAuthorizationPending  = "pending"
+	DeviceAuthorizationApproved = "approved"
+	DeviceAuthorizationDenied   = "denied"
+)
+
+// Consonants only, so that user codes are easy to type and don't spell words
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+const userCodeLength = 8
+
+// GenerateDeviceCode returns a random, URL safe device code
+func GenerateDeviceCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate device code: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashDeviceCode returns the hash of the device code to be stored instead of the code itself
+func HashDeviceCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateUserCode returns a random user code in the XXXX-XXXX format
+func GenerateUserCode() (string, error) {
+	code := make([]byte, 0, userCodeLength)
+	b := make([]byte, userCodeLength*2)
+	for len(code) < userCodeLength {
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		for _, c := range b {
+			// Rejecting the tail of the byte range keeps the distribution uniform
+			if int(c) >= 256-256%len(userCodeAlphabet) {
+				continue
+			}
+			code = append(code, userCodeAlphabet[int(c)%len(userCodeAlphabet)])
+			if len(code) == userCodeLength {
+				break
+			}
+		}
+	}
+	return formatUserCode(string(code)), nil
+}
+
+// NormalizeUserCode returns the user code as typed by the user in the XXXX-XXXX format,
+// ignoring case, spaces and dashes
+func NormalizeUserCode(code string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		if r == '-' || r == ' ' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return formatUserCode(b.String())
+}
+
+func formatUserCode(code string) string {
+	if len(code) != userCodeLength {
+		return code
+	}
+	return code[:userCodeLength/2] + "-" + code[userCodeLength/2:]
+}
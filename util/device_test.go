@@ -0,0 +1,46 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateUserCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := GenerateUserCode()
+		require.NoError(t, err)
+		require.Len(t, code, userCodeLength+1)
+		require.Equal(t, byte('-'), code[userCodeLength/2])
+
+		for _, r := range strings.ReplaceAll(code, "-", "") {
+			require.Contains(t, userCodeAlphabet, string(r))
+		}
+		require.Equal(t, code, NormalizeUserCode(code))
+	}
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{input: "BCDF-GHJK", want: "BCDF-GHJK"},
+		{input: "bcdfghjk", want: "BCDF-GHJK"},
+		{input: " bcd f-gh jk ", want: "BCDF-GHJK"},
+		{input: "bcd", want: "BCD"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, NormalizeUserCode(tc.input))
+	}
+}
+
+func TestHashDeviceCode(t *testing.T) {
+	code, err := GenerateDeviceCode()
+	require.NoError(t, err)
+
+	require.Equal(t, HashDeviceCode(code), HashDeviceCode(" "+code+" "))
+	require.NotEqual(t, code, HashDeviceCode(code))
+}
@@ -0,0 +1,181 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kinds of diff chunks
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// Diff modes
+const (
+	DiffModeLine = "line"
+	DiffModeWord = "word"
+)
+
+// maxDiffEdits bounds the edit script diffTokens looks for. Its trace takes O(D²) memory,
+// about 8MB at the limit; texts that differ more are compared as a single replacement
+const maxDiffEdits = 1000
+
+// DiffChunk is a piece of text that is the same in both texts, or only in one of them.
+// Joining equal and delete chunks gives the old text, equal and insert ones the new text
+type DiffChunk struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines compares two texts line by line
+func DiffLines(a, b string) []DiffChunk {
+	return diffTokens(splitLines(a), splitLines(b))
+}
+
+// DiffWords compares two texts word by word. Whitespace is compared too, so that the chunks keep it
+func DiffWords(a, b string) []DiffChunk {
+	return diffTokens(splitWords(a), splitWords(b))
+}
+
+// splitLines splits a text into lines, keeping the line breaks
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitWords splits a text into words and the whitespace between them
+func splitWords(text string) []string {
+	var tokens []string
+	start := 0
+	space := false
+	for i, r := range text {
+		isSpace := unicode.IsSpace(r)
+		if i > 0 && isSpace != space {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		space = isSpace
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// diffTokens finds the shortest edit script with the Myers algorithm. It takes O((N+M)D) time
+// and O(D²) memory for the D changed tokens, so small edits of long articles stay cheap.
+// Above maxDiffEdits it gives up and replaces everything between the common prefix and suffix
+func diffTokens(a, b []string) []DiffChunk {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] keeps the furthest x on the diagonals -d..d after d edits
+	var trace [][]int
+
+	var d int
+search:
+	for d = 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceTokens(a, b)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+
+	// Walk back from the end, collecting the script in reverse
+	var reversed []DiffChunk
+	x, y := n, m
+	for ; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, DiffChunk{Op: DiffEqual, Text: a[x]})
+		}
+		if x == prevX {
+			y--
+			reversed = append(reversed, DiffChunk{Op: DiffInsert, Text: b[y]})
+		} else {
+			x--
+			reversed = append(reversed, DiffChunk{Op: DiffDelete, Text: a[x]})
+		}
+	}
+	for x > 0 {
+		x--
+		reversed = append(reversed, DiffChunk{Op: DiffEqual, Text: a[x]})
+	}
+
+	// Merge neighbouring tokens of the same kind
+	chunks := []DiffChunk{}
+	for i := len(reversed) - 1; i >= 0; i-- {
+		token := reversed[i]
+		last := len(chunks) - 1
+		if last >= 0 && chunks[last].Op == token.Op {
+			chunks[last].Text += token.Text
+			continue
+		}
+		chunks = append(chunks, token)
+	}
+	return chunks
+}
+
+// replaceTokens keeps the common prefix and suffix, and marks everything between them as deleted and inserted
+func replaceTokens(a, b []string) []DiffChunk {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	chunks := []DiffChunk{}
+	for _, chunk := range []DiffChunk{
+		{Op: DiffEqual, Text: strings.Join(a[:prefix], "")},
+		{Op: DiffDelete, Text: strings.Join(a[prefix:len(a)-suffix], "")},
+		{Op: DiffInsert, Text: strings.Join(b[prefix:len(b)-suffix], "")},
+		{Op: DiffEqual, Text: strings.Join(a[len(a)-suffix:], "")},
+	} {
+		if chunk.Text != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
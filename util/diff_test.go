@@ -0,0 +1,100 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	a := "first\nsecond\nthird\n"
+	b := "first\nchanged\nthird\nfourth"
+
+	chunks := DiffLines(a, b)
+	require.Equal(t, []DiffChunk{
+		{Op: DiffEqual, Text: "first\n"},
+		{Op: DiffDelete, Text: "second\n"},
+		{Op: DiffInsert, Text: "changed\n"},
+		{Op: DiffEqual, Text: "third\n"},
+		{Op: DiffInsert, Text: "fourth"},
+	}, chunks)
+}
+
+func TestDiffWords(t *testing.T) {
+	chunks := DiffWords("the quick brown fox", "the slow brown  fox jumps")
+	require.Equal(t, []DiffChunk{
+		{Op: DiffEqual, Text: "the "},
+		{Op: DiffDelete, Text: "quick"},
+		{Op: DiffInsert, Text: "slow"},
+		{Op: DiffEqual, Text: " brown"},
+		{Op: DiffDelete, Text: " "},
+		{Op: DiffInsert, Text: "  "},
+		{Op: DiffEqual, Text: "fox"},
+		{Op: DiffInsert, Text: " jumps"},
+	}, chunks)
+}
+
+func TestDiffEdgeCases(t *testing.T) {
+	require.Empty(t, DiffLines("", ""))
+	require.Equal(t, []DiffChunk{{Op: DiffEqual, Text: "same"}}, DiffWords("same", "same"))
+	require.Equal(t, []DiffChunk{{Op: DiffInsert, Text: "new\n"}}, DiffLines("", "new\n"))
+	require.Equal(t, []DiffChunk{{Op: DiffDelete, Text: "old"}}, DiffWords("old", ""))
+}
+
+func TestDiffRebuildsTexts(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		a := randomText()
+		b := randomText()
+
+		for _, chunks := range [][]DiffChunk{DiffLines(a, b), DiffWords(a, b)} {
+			var oldText, newText strings.Builder
+			for _, chunk := range chunks {
+				require.NotEmpty(t, chunk.Text)
+				if chunk.Op != DiffInsert {
+					oldText.WriteString(chunk.Text)
+				}
+				if chunk.Op != DiffDelete {
+					newText.WriteString(chunk.Text)
+				}
+			}
+			require.Equal(t, a, oldText.String())
+			require.Equal(t, b, newText.String())
+		}
+	}
+}
+
+func TestDiffTooManyEdits(t *testing.T) {
+	var a, b strings.Builder
+	a.WriteString("title\n")
+	b.WriteString("title\n")
+	// Every line differs, so the edit script would be longer than maxDiffEdits
+	for i := 0; i < maxDiffEdits; i++ {
+		fmt.Fprintf(&a, "old %d\n", i)
+		fmt.Fprintf(&b, "new %d\n", i)
+	}
+	a.WriteString("end\n")
+	b.WriteString("end\n")
+
+	chunks := DiffLines(a.String(), b.String())
+	require.Equal(t, []DiffChunk{
+		{Op: DiffEqual, Text: "title\n"},
+		{Op: DiffDelete, Text: strings.TrimPrefix(strings.TrimSuffix(a.String(), "end\n"), "title\n")},
+		{Op: DiffInsert, Text: strings.TrimPrefix(strings.TrimSuffix(b.String(), "end\n"), "title\n")},
+		{Op: DiffEqual, Text: "end\n"},
+	}, chunks)
+}
+
+// randomText makes texts out of few words, so that compared texts share some of them
+func randomText() string {
+	words := []string{"a", "b", "c", "d"}
+	separators := []string{" ", "\n", "  "}
+
+	var text strings.Builder
+	for i := RandomInt(0, 20); i > 0; i-- {
+		text.WriteString(words[RandomInt(0, int64(len(words)-1))])
+		text.WriteString(separators[RandomInt(0, int64(len(separators)-1))])
+	}
+	return text.String()
+}
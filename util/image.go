@@ -0,0 +1,79 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // register PNG decoder
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register WebP decoder
+)
+
+// MaxImageDimension limits the width and height of decoded images,
+// so that a small file can't blow up into a huge bitmap
+const MaxImageDimension = 4096
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type, only JPEG, PNG and WebP are allowed")
+	ErrImageTooLarge    = fmt.Errorf("image dimensions exceed %dx%d", MaxImageDimension, MaxImageDimension)
+)
+
+var supportedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// DecodeImage decodes JPEG, PNG or WebP image. The type is sniffed from the content, not trusted from the client
+func DecodeImage(data []byte) (image.Image, error) {
+	if !supportedImageTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode image: %w", err)
+	}
+	if config.Width > MaxImageDimension || config.Height > MaxImageDimension {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode image: %w", err)
+	}
+	return img, nil
+}
+
+// ResizeSquare crops the center square of the image and scales it to size x size.
+// Transparent areas become white, as the result is meant to be encoded without alpha
+func ResizeSquare(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	crop := image.Rect(0, 0, side, side).Add(image.Point{
+		X: bounds.Min.X + (bounds.Dx()-side)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-side)/2,
+	})
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Over, nil)
+	return dst
+}
+
+// EncodeJPEG encodes the image as JPEG
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, fmt.Errorf("cannot encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
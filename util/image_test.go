@@ -0,0 +1,56 @@
+package util
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodeTestPNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestDecodeAndResizeImage(t *testing.T) {
+	img, err := DecodeImage(encodeTestPNG(t, 300, 200))
+	require.NoError(t, err)
+	require.Equal(t, 300, img.Bounds().Dx())
+
+	resized := ResizeSquare(img, 64)
+	require.Equal(t, image.Rect(0, 0, 64, 64), resized.Bounds())
+
+	data, err := EncodeJPEG(resized)
+	require.NoError(t, err)
+
+	decoded, err := DecodeImage(data)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 64, 64), decoded.Bounds())
+}
+
+func TestDecodeImageRejects(t *testing.T) {
+	_, err := DecodeImage([]byte("GIF89a not really a gif"))
+	require.ErrorIs(t, err, ErrUnsupportedImage)
+
+	_, err = DecodeImage([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	require.ErrorIs(t, err, ErrUnsupportedImage)
+
+	_, err = DecodeImage(encodeTestPNG(t, MaxImageDimension+1, 1))
+	require.ErrorIs(t, err, ErrImageTooLarge)
+
+	// PNG signature followed by garbage
+	_, err = DecodeImage(append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), make([]byte, 32)...))
+	require.Error(t, err)
+}
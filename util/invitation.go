@@ -0,0 +1,41 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Registration modes
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite_only"
+	RegistrationClosed     = "closed"
+)
+
+// IsSupportedRegistrationMode returns true if the registration mode is supported
+func IsSupportedRegistrationMode(mode string) bool {
+	switch mode {
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationClosed:
+		return true
+	}
+	return false
+}
+
+// GenerateInvitationCode returns a random, URL safe invitation code
+func GenerateInvitationCode() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate invitation code: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashInvitationCode returns the hash of the invitation code to be stored instead of the code itself
+func HashInvitationCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(code)))
+	return hex.EncodeToString(sum[:])
+}
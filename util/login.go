@@ -0,0 +1,11 @@
+package util
+
+// Outcomes of login attempts
+const (
+	LoginSucceeded = "success"
+	// Password was correct, the second factor is still needed
+	LoginMFARequired = "mfa_required"
+	LoginFailed      = "failed"
+	// Password was correct, but the account is disabled or waiting for a password reset
+	LoginBlocked = "blocked"
+)
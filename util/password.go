@@ -1,21 +1,234 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
+// Supported password hashing algorithms
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// BcryptMaxPasswordLength is the longest password bcrypt hashes, it ignores everything after the 72nd byte
+const BcryptMaxPasswordLength = 72
+
+// Different types of errors returned by the PasswordHasher
+var (
+	ErrMismatchedPassword = errors.New("password doesn't match")
+	ErrPasswordTooLong    = fmt.Errorf("password can't be longer than %d bytes", BcryptMaxPasswordLength)
+	ErrUnknownHashFormat  = errors.New("unknown password hash format")
+)
+
+// Argon2idParams are the cost parameters of argon2id, memory is in KiB
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the recommendations of RFC 9106 for memory constrained environments
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// PasswordHasher hashes passwords and checks them against hashes made with any of the supported algorithms
+type PasswordHasher interface {
+	// Hash returns the hash of the password made with the current algorithm and parameters
+	Hash(password string) (string, error)
+	// Check checks if the password matches the hash
+	Check(password string, hashedPassword string) error
+	// NeedsRehash reports if the hash was made with a different algorithm or parameters than the current ones
+	NeedsRehash(hashedPassword string) bool
+}
+
+type passwordHasher struct {
+	algorithm  string
+	argon2id   Argon2idParams
+	bcryptCost int
+}
+
+// NewPasswordHasher creates a new PasswordHasher hashing with the algorithm.
+// Zero parameters are replaced with the defaults.
+func NewPasswordHasher(algorithm string, argon2idParams Argon2idParams, bcryptCost int) (PasswordHasher, error) {
+	if algorithm == "" {
+		algorithm = PasswordAlgorithmArgon2id
+	}
+	if algorithm != PasswordAlgorithmArgon2id && algorithm != PasswordAlgorithmBcrypt {
+		return nil, fmt.Errorf("unsupported password hashing algorithm: %s", algorithm)
+	}
+
+	if argon2idParams.Memory == 0 {
+		argon2idParams.Memory = DefaultArgon2idParams.Memory
+	}
+	if argon2idParams.Iterations == 0 {
+		argon2idParams.Iterations = DefaultArgon2idParams.Iterations
+	}
+	if argon2idParams.Parallelism == 0 {
+		argon2idParams.Parallelism = DefaultArgon2idParams.Parallelism
+	}
+	if argon2idParams.SaltLength == 0 {
+		argon2idParams.SaltLength = DefaultArgon2idParams.SaltLength
+	}
+	if argon2idParams.KeyLength == 0 {
+		argon2idParams.KeyLength = DefaultArgon2idParams.KeyLength
+	}
+
+	if bcryptCost == 0 {
+		bcryptCost = bcrypt.DefaultCost
+	}
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost: %d", bcryptCost)
+	}
+
+	hasher := &passwordHasher{
+		algorithm:  algorithm,
+		argon2id:   argon2idParams,
+		bcryptCost: bcryptCost,
 	}
-	return string(hashedPassword), nil
+	return hasher, nil
+}
+
+var defaultPasswordHasher, _ = NewPasswordHasher(PasswordAlgorithmArgon2id, DefaultArgon2idParams, bcrypt.DefaultCost)
+
+// HashPassword returns the hash of the password made with the default hasher
+func HashPassword(password string) (string, error) {
+	return defaultPasswordHasher.Hash(password)
 }
 
 // CheckPassword checks if the provided password is correct or not
 func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return defaultPasswordHasher.Check(password, hashedPassword)
+}
+
+// Hash returns the hash of the password made with the current algorithm and parameters
+func (hasher *passwordHasher) Hash(password string) (string, error) {
+	if hasher.algorithm == PasswordAlgorithmBcrypt {
+		if len(password) > BcryptMaxPasswordLength {
+			return "", ErrPasswordTooLong
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), hasher.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hashedPassword), nil
+	}
+
+	salt := make([]byte, hasher.argon2id.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return encodeArgon2id(hasher.argon2id, salt, hashArgon2id(hasher.argon2id, password, salt)), nil
+}
+
+// Check checks if the password matches the hash, no matter which of the supported algorithms made it
+func (hasher *passwordHasher) Check(password string, hashedPassword string) error {
+	if isBcryptHash(hashedPassword) {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return ErrMismatchedPassword
+		}
+		return err
+	}
+
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(key, hashArgon2id(params, password, salt)) != 1 {
+		return ErrMismatchedPassword
+	}
+	return nil
+}
+
+// NeedsRehash reports if the hash was made with a different algorithm or parameters than the current ones
+func (hasher *passwordHasher) NeedsRehash(hashedPassword string) bool {
+	if isBcryptHash(hashedPassword) {
+		if hasher.algorithm != PasswordAlgorithmBcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hashedPassword))
+		return err != nil || cost != hasher.bcryptCost
+	}
+
+	if hasher.algorithm != PasswordAlgorithmArgon2id {
+		return true
+	}
+	params, _, _, err := decodeArgon2id(hashedPassword)
+	return err != nil || params != hasher.argon2id
+}
+
+func isBcryptHash(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
+
+func hashArgon2id(params Argon2idParams, password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+}
+
+// encodeArgon2id returns the hash in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func encodeArgon2id(params Argon2idParams, salt, key []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2id(hashedPassword string) (params Argon2idParams, salt, key []byte, err error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != PasswordAlgorithmArgon2id {
+		err = ErrUnknownHashFormat
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		err = ErrUnknownHashFormat
+		return
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		err = ErrUnknownHashFormat
+		return
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		err = ErrUnknownHashFormat
+		return
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		err = ErrUnknownHashFormat
+		return
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return
 }
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Names of the password policy rules
+const (
+	PasswordRuleMinLength        = "min_length"
+	PasswordRuleMaxLength        = "max_length"
+	PasswordRuleUppercase        = "uppercase"
+	PasswordRuleLowercase        = "lowercase"
+	PasswordRuleDigit            = "digit"
+	PasswordRuleSymbol           = "symbol"
+	PasswordRuleContainsUsername = "contains_username"
+	PasswordRuleContainsEmail    = "contains_email"
+	PasswordRuleBreached         = "breached"
+)
+
+// Parts of the email shorter than that are too common to be forbidden in the password
+const minForbiddenEmailPartLength = 3
+
+// PasswordPolicy describes the requirements new passwords have to meet
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// PasswordViolation describes a single rule the password doesn't satisfy
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError is returned when the password doesn't satisfy the policy
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "password does not satisfy the policy: " + strings.Join(messages, "; ")
+}
+
+// Validate checks the password of the user against every rule of the policy.
+// Returns *PasswordPolicyError listing all of the failed rules.
+func (policy PasswordPolicy) Validate(password, username, email string) error {
+	var violations []PasswordViolation
+	violate := func(rule, format string, args ...interface{}) {
+		violations = append(violations, PasswordViolation{
+			Rule:    rule,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		violate(PasswordRuleMinLength, "must be at least %d characters long", policy.MinLength)
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		violate(PasswordRuleMaxLength, "must be at most %d characters long", policy.MaxLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		violate(PasswordRuleUppercase, "must contain an uppercase letter")
+	}
+	if policy.RequireLower && !hasLower {
+		violate(PasswordRuleLowercase, "must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		violate(PasswordRuleDigit, "must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		violate(PasswordRuleSymbol, "must contain a symbol")
+	}
+
+	lowerPassword := strings.ToLower(password)
+	if username != "" && strings.Contains(lowerPassword, strings.ToLower(username)) {
+		violate(PasswordRuleContainsUsername, "must not contain the username")
+	}
+	if email != "" {
+		lowerEmail := strings.ToLower(email)
+		localPart, _, _ := strings.Cut(lowerEmail, "@")
+		if strings.Contains(lowerPassword, lowerEmail) ||
+			(len(localPart) >= minForbiddenEmailPartLength && strings.Contains(lowerPassword, localPart)) {
+			violate(PasswordRuleContainsEmail, "must not contain the email address")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:     8,
+		MaxLength:     16,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	testCases := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{
+			name:     "OK",
+			password: "Correct-H0rse",
+		},
+		{
+			name:     "TooShort",
+			password: "Ab1!",
+			rules:    []string{PasswordRuleMinLength},
+		},
+		{
+			name:     "TooLong",
+			password: "Correct-H0rse-Battery",
+			rules:    []string{PasswordRuleMaxLength},
+		},
+		{
+			name:     "MissingClasses",
+			password: "correcthorse",
+			rules:    []string{PasswordRuleUppercase, PasswordRuleDigit, PasswordRuleSymbol},
+		},
+		{
+			name:     "ContainsUsername",
+			password: "My-Alice-Pa55",
+			rules:    []string{PasswordRuleContainsUsername},
+		},
+		{
+			name:     "ContainsEmail",
+			password: "Wonder-Land-1",
+			rules:    []string{PasswordRuleContainsEmail},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Validate(tc.password, "alice", "wonder-land@email.com")
+			if len(tc.rules) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			policyErr, ok := err.(*PasswordPolicyError)
+			require.True(t, ok)
+
+			rules := make([]string, len(policyErr.Violations))
+			for i, violation := range policyErr.Violations {
+				rules[i] = violation.Rule
+				require.NotEmpty(t, violation.Message)
+			}
+			require.Equal(t, tc.rules, rules)
+		})
+	}
+}
+
+func TestLocalBreachedPasswords(t *testing.T) {
+	dir := t.TempDir()
+
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(
+		"003D68EB55068C33ACE09247EE4C639306B:3\r\n"+
+			"1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n",
+	), 0o644)
+	require.NoError(t, err)
+
+	checker, err := NewLocalBreachedPasswords(dir)
+	require.NoError(t, err)
+
+	breached, err := checker.IsBreached("password")
+	require.NoError(t, err)
+	require.True(t, breached)
+
+	// Range file doesn't exist
+	breached, err = checker.IsBreached(RandomString(20))
+	require.NoError(t, err)
+	require.False(t, breached)
+
+	_, err = NewLocalBreachedPasswords(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2idParams = Argon2idParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordAlgorithmArgon2id, testArgon2idParams, 0)
+	require.NoError(t, err)
+
+	password := RandomString(100)
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	require.NoError(t, hasher.Check(password, hashedPassword))
+	require.ErrorIs(t, hasher.Check(RandomString(100), hashedPassword), ErrMismatchedPassword)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	// Hashes are salted
+	hashedPassword2, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.NotEqual(t, hashedPassword, hashedPassword2)
+
+	// Changing parameters requires rehash
+	stronger, err := NewPasswordHasher(PasswordAlgorithmArgon2id, Argon2idParams{Memory: 2048, Iterations: 1, Parallelism: 1}, 0)
+	require.NoError(t, err)
+	require.NoError(t, stronger.Check(password, hashedPassword))
+	require.True(t, stronger.NeedsRehash(hashedPassword))
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(PasswordAlgorithmBcrypt, Argon2idParams{}, bcrypt.MinCost)
+	require.NoError(t, err)
+
+	password := RandomString(10)
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	require.NoError(t, hasher.Check(password, hashedPassword))
+	require.ErrorIs(t, hasher.Check(RandomString(10), hashedPassword), ErrMismatchedPassword)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	_, err = hasher.Hash(RandomString(73))
+	require.ErrorIs(t, err, ErrPasswordTooLong)
+}
+
+func TestPasswordHasherMigration(t *testing.T) {
+	bcryptHasher, err := NewPasswordHasher(PasswordAlgorithmBcrypt, Argon2idParams{}, bcrypt.MinCost)
+	require.NoError(t, err)
+	argon2idHasher, err := NewPasswordHasher(PasswordAlgorithmArgon2id, testArgon2idParams, 0)
+	require.NoError(t, err)
+
+	password := RandomString(10)
+	bcryptHash, err := bcryptHasher.Hash(password)
+	require.NoError(t, err)
+
+	// Old hashes are still accepted, but have to be upgraded
+	require.NoError(t, argon2idHasher.Check(password, bcryptHash))
+	require.True(t, argon2idHasher.NeedsRehash(bcryptHash))
+
+	argon2idHash, err := argon2idHasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, bcryptHasher.NeedsRehash(argon2idHash))
+}
+
+func TestInvalidPasswordHasher(t *testing.T) {
+	_, err := NewPasswordHasher("md5", Argon2idParams{}, 0)
+	require.Error(t, err)
+
+	_, err = NewPasswordHasher(PasswordAlgorithmBcrypt, Argon2idParams{}, 100)
+	require.Error(t, err)
+
+	hasher, err := NewPasswordHasher("", Argon2idParams{}, 0)
+	require.NoError(t, err)
+	require.ErrorIs(t, hasher.Check("password", "$argon2id$broken"), ErrUnknownHashFormat)
+	require.ErrorIs(t, hasher.Check("password", "plain"), ErrUnknownHashFormat)
+	require.True(t, hasher.NeedsRehash("plain"))
+}
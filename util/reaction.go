@@ -0,0 +1,27 @@
+package util
+
+// Reactions readers can leave on articles. The names are used in URLs, the emoji are only for display
+const (
+	ReactionThumbsUp = "thumbs_up"
+	ReactionHeart    = "heart"
+	ReactionLaugh    = "laugh"
+	ReactionHooray   = "hooray"
+	ReactionConfused = "confused"
+	ReactionEyes     = "eyes"
+)
+
+// ReactionEmoji maps the supported reactions to their emoji
+var ReactionEmoji = map[string]string{
+	ReactionThumbsUp: "👍",
+	ReactionHeart:    "❤️",
+	ReactionLaugh:    "😄",
+	ReactionHooray:   "🎉",
+	ReactionConfused: "😕",
+	ReactionEyes:     "👀",
+}
+
+// IsSupportedReaction returns true if the reaction is one of the supported ones
+func IsSupportedReaction(reaction string) bool {
+	_, ok := ReactionEmoji[reaction]
+	return ok
+}
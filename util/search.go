@@ -0,0 +1,33 @@
+package util
+
+// searchLanguages are the text search configurations Postgres 14 ships with
+var searchLanguages = map[string]bool{
+	"simple":     true,
+	"arabic":     true,
+	"danish":     true,
+	"dutch":      true,
+	"english":    true,
+	"finnish":    true,
+	"french":     true,
+	"german":     true,
+	"greek":      true,
+	"hungarian":  true,
+	"indonesian": true,
+	"irish":      true,
+	"italian":    true,
+	"lithuanian": true,
+	"nepali":     true,
+	"norwegian":  true,
+	"portuguese": true,
+	"romanian":   true,
+	"russian":    true,
+	"spanish":    true,
+	"swedish":    true,
+	"tamil":      true,
+	"turkish":    true,
+}
+
+// IsSupportedSearchLanguage returns true if the language is a built-in text search configuration
+func IsSupportedSearchLanguage(language string) bool {
+	return searchLanguages[language]
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Limits of tags
+const (
+	TagMaxLength      = 50
+	MaxTagsPerArticle = 10
+)
+
+// ErrInvalidTag is returned for tags without any letters or digits
+var ErrInvalidTag = errors.New("tag has to contain letters or digits")
+
+// NormalizeTag turns a tag into a lowercase slug, so that "Go Modules", " go_modules" and "go-modules" are the same tag
+func NormalizeTag(tag string) (string, error) {
+	var b strings.Builder
+	separator := false
+	for _, r := range strings.ToLower(tag) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if separator && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			separator = false
+			b.WriteRune(r)
+			continue
+		}
+		separator = true
+	}
+
+	slug := b.String()
+	if slug == "" {
+		return "", ErrInvalidTag
+	}
+	if len([]rune(slug)) > TagMaxLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters", slug, TagMaxLength)
+	}
+	return slug, nil
+}
+
+// NormalizeTags normalizes all tags and removes duplicates, keeping the order
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		slug, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			normalized = append(normalized, slug)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	testCases := []struct {
+		tag  string
+		slug string
+		err  bool
+	}{
+		{tag: "go", slug: "go"},
+		{tag: "Go Modules", slug: "go-modules"},
+		{tag: "  go_modules  ", slug: "go-modules"},
+		{tag: "go--modules!", slug: "go-modules"},
+		{tag: "C++", slug: "c"},
+		{tag: "Zażółć Gęślą", slug: "zażółć-gęślą"},
+		{tag: "web 3.0", slug: "web-3-0"},
+		{tag: " -- ", err: true},
+		{tag: strings.Repeat("a", TagMaxLength+1), err: true},
+	}
+
+	for _, tc := range testCases {
+		slug, err := NormalizeTag(tc.tag)
+		if tc.err {
+			require.Error(t, err, tc.tag)
+			continue
+		}
+		require.NoError(t, err, tc.tag)
+		require.Equal(t, tc.slug, slug)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{"Redis", "go", "redis ", "Go"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"redis", "go"}, tags)
+
+	_, err = NormalizeTags([]string{"go", "!!"})
+	require.ErrorIs(t, err, ErrInvalidTag)
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test vector from RFC 6238 appendix B, truncated to 6 digits
+func TestTOTPCode(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	code, err := TOTPCode(secret, TOTPStep(time.Unix(59, 0)))
+	require.NoError(t, err)
+	require.Equal(t, "287082", code)
+
+	code, err = TOTPCode(secret, TOTPStep(time.Unix(1111111109, 0)))
+	require.NoError(t, err)
+	require.Equal(t, "081804", code)
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := TOTPCode(secret, TOTPStep(now))
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTP(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, TOTPStep(now), step)
+
+	// Clock drift of one step is accepted
+	_, ok = ValidateTOTP(secret, code, now.Add(totpPeriod))
+	require.True(t, ok)
+
+	_, ok = ValidateTOTP(secret, code, now.Add(3*totpPeriod))
+	require.False(t, ok)
+
+	_, ok = ValidateTOTP(secret, "12345", now)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(TOTPURI("Go Example", "user", "SECRET"))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/Go Example:user", uri.Path)
+	require.Equal(t, "SECRET", uri.Query().Get("secret"))
+	require.Equal(t, "Go Example", uri.Query().Get("issuer"))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	for _, code := range codes {
+		require.Len(t, code, 11)
+		require.Equal(t, HashRecoveryCode(code), HashRecoveryCode(" "+code[:5]+code[6:]+" "))
+	}
+	require.NotEqual(t, HashRecoveryCode(codes[0]), HashRecoveryCode(codes[1]))
+}